@@ -0,0 +1,158 @@
+package amqpjobs
+
+import (
+	"strings"
+
+	json "github.com/json-iterator/go"
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+)
+
+const (
+	queueTypeArg string = "x-queue-type"
+
+	classicQueue string = "classic"
+	quorumQueue  string = "quorum"
+	streamQueue  string = "stream"
+
+	headersExchange string = "headers"
+)
+
+// queueArgs returns the arguments of the queue declaration, queue_type overrides the x-queue-type argument
+func queueArgs(queueType string, args map[string]interface{}) (amqp.Table, error) {
+	table := toTable(args)
+
+	switch queueType {
+	case "":
+	case classicQueue, quorumQueue, streamQueue:
+		table[queueTypeArg] = queueType
+	default:
+		return nil, errors.Errorf("unknown queue type: %s, should be one of: classic, quorum, stream", queueType)
+	}
+
+	if len(table) == 0 {
+		return nil, nil
+	}
+
+	return table, nil
+}
+
+// replicated reports whether the queue is a quorum or a stream queue, such queues are always durable and can't be exclusive
+func replicated(args amqp.Table) bool {
+	qt, _ := args[queueTypeArg].(string)
+	return qt == quorumQueue || qt == streamQueue
+}
+
+// routingKeys returns the unique routing keys bound to the queue, the pipeline's routing key is the first one
+func routingKeys(key string, keys []string) []string {
+	result := []string{key}
+	for i := 0; i < len(keys); i++ {
+		dup := false
+		for j := 0; j < len(result); j++ {
+			if result[j] == keys[i] {
+				dup = true
+				break
+			}
+		}
+
+		if !dup {
+			result = append(result, keys[i])
+		}
+	}
+
+	return result
+}
+
+// toTable converts the configuration map to the amqp table, the whole JSON numbers are converted to the integers
+func toTable(m map[string]interface{}) amqp.Table {
+	table := make(amqp.Table, len(m))
+	for k, v := range m {
+		table[k] = toField(v)
+	}
+
+	return table
+}
+
+func toField(v interface{}) interface{} {
+	switch f := v.(type) {
+	case float64:
+		if f == float64(int64(f)) {
+			return int64(f)
+		}
+		return f
+	case map[string]interface{}:
+		return toTable(f)
+	case map[interface{}]interface{}:
+		table := make(amqp.Table, len(f))
+		for k, val := range f {
+			if key, ok := k.(string); ok {
+				table[key] = toField(val)
+			}
+		}
+		return table
+	case []interface{}:
+		arr := make([]interface{}, len(f))
+		for i := 0; i < len(f); i++ {
+			arr[i] = toField(f[i])
+		}
+		return arr
+	default:
+		return v
+	}
+}
+
+// pipeTable parses the JSON object option of the pipeline declared via RPC
+func pipeTable(pipe *pipeline.Pipeline, name string) (map[string]interface{}, error) {
+	val := pipe.String(name, "")
+	if val == "" {
+		return nil, nil
+	}
+
+	out := make(map[string]interface{})
+	err := json.Unmarshal([]byte(val), &out)
+	if err != nil {
+		return nil, errors.Errorf("%s should be a JSON object, error: %v", name, err)
+	}
+
+	return out, nil
+}
+
+// pipeList parses the comma separated list option of the pipeline declared via RPC
+func pipeList(pipe *pipeline.Pipeline, name string) []string {
+	val := pipe.String(name, "")
+	if val == "" {
+		return nil
+	}
+
+	parts := strings.Split(val, ",")
+	result := make([]string, 0, len(parts))
+	for i := 0; i < len(parts); i++ {
+		if p := strings.TrimSpace(parts[i]); p != "" {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// withJobHeaders adds the job's headers to the message headers, so the headers exchange can route the job by them.
+// Reserved RR headers are not overwritten.
+func withJobHeaders(table amqp.Table, headers map[string][]string) {
+	for k, v := range headers {
+		if _, ok := table[k]; ok || len(v) == 0 {
+			continue
+		}
+
+		if len(v) == 1 {
+			table[k] = v[0]
+			continue
+		}
+
+		arr := make([]interface{}, len(v))
+		for i := 0; i < len(v); i++ {
+			arr[i] = v[i]
+		}
+		table[k] = arr
+	}
+}
@@ -0,0 +1,67 @@
+package amqpjobs
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueArgs(t *testing.T) {
+	args, err := queueArgs("", nil)
+	require.NoError(t, err)
+	assert.Nil(t, args)
+
+	args, err = queueArgs(quorumQueue, map[string]interface{}{"x-queue-type": "classic", "x-delivery-limit": float64(5)})
+	require.NoError(t, err)
+	assert.Equal(t, amqp.Table{"x-queue-type": "quorum", "x-delivery-limit": int64(5)}, args)
+	assert.True(t, replicated(args))
+
+	args, err = queueArgs("", map[string]interface{}{"x-queue-type": "stream", "x-max-age": "7D"})
+	require.NoError(t, err)
+	assert.True(t, replicated(args))
+
+	_, err = queueArgs("mirrored", nil)
+	require.Error(t, err)
+}
+
+func TestQueueOptions(t *testing.T) {
+	c := &consumer{routingKey: "a", exclusive: true}
+	require.Error(t, c.withQueueOptions(quorumQueue, nil, nil, nil))
+
+	c = &consumer{routingKey: "a"}
+	require.NoError(t, c.withQueueOptions(streamQueue, nil, []string{"b", "a", "c", "b"}, map[string]interface{}{"x-match": "all", "tenant": "acme"}))
+	assert.True(t, c.durable)
+	assert.Equal(t, []string{"a", "b", "c"}, c.routingKeys)
+	assert.Equal(t, amqp.Table{"x-match": "all", "tenant": "acme"}, c.bindingArgs)
+	require.NoError(t, c.bindingArgs.Validate())
+}
+
+func TestPipelineQueueOptions(t *testing.T) {
+	pipe := &pipeline.Pipeline{
+		"queue_args":   `{"x-max-length": 1000, "x-overflow": "reject-publish"}`,
+		"routing_keys": "b, c,,",
+		"binding_args": `{"x-match": "any"}`,
+	}
+
+	qArgs, err := pipeTable(pipe, queueArgsKey)
+	require.NoError(t, err)
+
+	args, err := queueArgs("", qArgs)
+	require.NoError(t, err)
+	assert.Equal(t, amqp.Table{"x-max-length": int64(1000), "x-overflow": "reject-publish"}, args)
+	assert.Equal(t, []string{"b", "c"}, pipeList(pipe, routingKeysKey))
+
+	_, err = pipeTable(&pipeline.Pipeline{"binding_args": "any"}, bindingArgs)
+	require.Error(t, err)
+}
+
+func TestJobHeaders(t *testing.T) {
+	table := amqp.Table{"rr_id": "1"}
+	withJobHeaders(table, map[string][]string{"tenant": {"acme"}, "tags": {"a", "b"}, "rr_id": {"2"}, "empty": {}})
+
+	assert.Equal(t, amqp.Table{"rr_id": "1", "tenant": "acme", "tags": []interface{}{"a", "b"}}, table)
+	require.NoError(t, table.Validate())
+}
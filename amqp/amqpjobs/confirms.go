@@ -0,0 +1,132 @@
+package amqpjobs
+
+import (
+	"context"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spiral/errors"
+)
+
+// confirmsBuffer is the size of the confirmations and returns buffers. The connection's reader blocks on the full buffer,
+// late confirmations of the timed out publishings are drained by the next publishing.
+const confirmsBuffer int = 100
+
+// publishChannel opens the publishing channel, in the confirm mode if the publisher confirms are enabled.
+// Should be called when the publishing channel is not used (not in the publishChan).
+func (c *consumer) publishChannel() (*amqp.Channel, error) {
+	pch, err := c.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	if !c.publisherConfirms {
+		return pch, nil
+	}
+
+	err = pch.Confirm(false)
+	if err != nil {
+		_ = pch.Close()
+		return nil, err
+	}
+
+	// delivery tags start from 1 on the new channel
+	c.deliveryTag = 0
+	c.confirms = pch.NotifyPublish(make(chan amqp.Confirmation, confirmsBuffer))
+	c.returns = pch.NotifyReturn(make(chan amqp.Return, confirmsBuffer))
+
+	return pch, nil
+}
+
+// publish publishes the message and waits for the broker confirmation if the publisher confirms are enabled.
+// The message is published with the mandatory flag, the unroutable message is returned by the broker and reported as an error.
+func (c *consumer) publish(ctx context.Context, pch *amqp.Channel, exchange, key string, msg amqp.Publishing) error {
+	return c.publishWith(ctx, pch, exchange, key, true, msg)
+}
+
+// publishWith publishes the message with the mandatory flag if the publisher confirms are enabled. The exchanges which
+// route the message later (x-delayed-message) return every mandatory message, such messages are published w/o the flag.
+func (c *consumer) publishWith(ctx context.Context, pch *amqp.Channel, exchange, key string, mandatory bool, msg amqp.Publishing) error {
+	if !c.publisherConfirms {
+		return pch.Publish(exchange, key, false, false, msg)
+	}
+
+	err := pch.Publish(exchange, key, mandatory, false, msg)
+	if err != nil {
+		return err
+	}
+
+	c.deliveryTag++
+
+	return c.waitConfirm(ctx, msg.MessageId, exchange, key)
+}
+
+// waitConfirm waits for the confirmation of the last published message (deliveryTag). The broker sends the return
+// of the unroutable message before the confirmation, but both might be already buffered, so the returns are drained
+// after the ack.
+func (c *consumer) waitConfirm(ctx context.Context, id, exchange, key string) error {
+	timer := time.NewTimer(c.publisherConfirmsTimeout)
+	defer timer.Stop()
+
+	var returned *amqp.Return
+	for {
+		select {
+		case r, ok := <-c.returns:
+			if !ok {
+				return errors.Str("publishing channel was closed before the confirmation")
+			}
+
+			// returned message of the previous (timed out) publishing
+			if r.MessageId != id {
+				continue
+			}
+
+			returned = &r
+		case conf, ok := <-c.confirms:
+			if !ok {
+				return errors.Str("publishing channel was closed before the confirmation")
+			}
+
+			// confirmation of the previous (timed out) publishing
+			if conf.DeliveryTag < c.deliveryTag {
+				continue
+			}
+
+			if !conf.Ack {
+				return errors.Errorf("message was rejected (nack) by the broker, exchange: %s, routing key: %s", exchange, key)
+			}
+
+			if returned == nil {
+				returned = c.drainReturns(id)
+			}
+
+			if returned != nil {
+				return errors.Errorf("message is unroutable, exchange: %s, routing key: %s, reply: %d %s", returned.Exchange, returned.RoutingKey, returned.ReplyCode, returned.ReplyText)
+			}
+
+			return nil
+		case <-timer.C:
+			return errors.E(errors.TimeOut, errors.Errorf("no broker confirmation within %s", c.publisherConfirmsTimeout))
+		case <-ctx.Done():
+			return errors.E(errors.TimeOut, ctx.Err())
+		}
+	}
+}
+
+// drainReturns reads the buffered returns without blocking, nil - the message with the id was not returned
+func (c *consumer) drainReturns(id string) *amqp.Return {
+	for {
+		select {
+		case r, ok := <-c.returns:
+			if !ok {
+				return nil
+			}
+
+			if r.MessageId == id {
+				return &r
+			}
+		default:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package amqpjobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spiral/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfirmsConsumer(tag uint64) *consumer {
+	return &consumer{
+		publisherConfirms:        true,
+		publisherConfirmsTimeout: time.Millisecond * 100,
+		confirms:                 make(chan amqp.Confirmation, confirmsBuffer),
+		returns:                  make(chan amqp.Return, confirmsBuffer),
+		deliveryTag:              tag,
+	}
+}
+
+func TestWaitConfirmReturned(t *testing.T) {
+	// the ack and the return are both buffered, the select picks any of them
+	for i := 0; i < 100; i++ {
+		c := testConfirmsConsumer(1)
+		c.returns <- amqp.Return{MessageId: "1", Exchange: "default", RoutingKey: "unknown", ReplyCode: 312, ReplyText: "NO_ROUTE"}
+		c.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+
+		err := c.waitConfirm(context.Background(), "1", "default", "unknown")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unroutable")
+	}
+}
+
+func TestWaitConfirm(t *testing.T) {
+	// late return and confirmation of the previous publishing
+	c := testConfirmsConsumer(2)
+	c.returns <- amqp.Return{MessageId: "1"}
+	c.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: true}
+	c.confirms <- amqp.Confirmation{DeliveryTag: 2, Ack: true}
+	require.NoError(t, c.waitConfirm(context.Background(), "2", "default", "test"))
+
+	c = testConfirmsConsumer(1)
+	c.confirms <- amqp.Confirmation{DeliveryTag: 1, Ack: false}
+	err := c.waitConfirm(context.Background(), "1", "default", "test")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "nack")
+
+	// no confirmation
+	c = testConfirmsConsumer(1)
+	err = c.waitConfirm(context.Background(), "1", "default", "test")
+	assert.True(t, errors.Is(errors.TimeOut, err))
+
+	c = testConfirmsConsumer(1)
+	close(c.confirms)
+	assert.Error(t, c.waitConfirm(context.Background(), "1", "default", "test"))
+}
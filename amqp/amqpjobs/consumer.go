@@ -2,7 +2,6 @@ package amqpjobs
 
 import (
 	"context"
-	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -54,10 +53,34 @@ type consumer struct {
 	requeueOnFail     bool
 	durable           bool
 	deleteQueueOnStop bool
+	// arguments of the queue declaration, including the x-queue-type
+	queueArgs amqp.Table
+	// routing keys bound to the queue, the routingKey is the first one
+	routingKeys []string
+	bindingArgs amqp.Table
+
+	// delayed jobs
+	delayMode string
+	// the x-delayed-message exchange is used, the broker supports it
+	nativeDelay bool
+	// delay queues declared by the consumer, used only with the publishing channel
+	delayQueues map[string]struct{}
+	// channel of the delay hop queue consumer, see startHopper
+	hopChan *amqp.Channel
+
+	// publisher confirms, confirmations and returns of the current publishing channel
+	publisherConfirms        bool
+	publisherConfirmsTimeout time.Duration
+	confirms                 chan amqp.Confirmation
+	returns                  chan amqp.Return
+	// delivery tag of the last publishing on the current publishing channel
+	deliveryTag uint64
 
 	listeners uint32
 	delayed   *int64
 	stopCh    chan struct{}
+	// closed when the redialer exits, after the cleanup on stop
+	stoppedCh chan struct{}
 }
 
 // NewAMQPConsumer initializes rabbitmq pipeline
@@ -96,6 +119,7 @@ func NewAMQPConsumer(configKey string, log *zap.Logger, cfg cfgPlugin.Configurer
 		pq:        pq,
 		consumeID: uuid.NewString(),
 		stopCh:    make(chan struct{}),
+		stoppedCh: make(chan struct{}),
 		// TODO to config
 		retryTimeout: time.Minute * 5,
 		priority:     conf.Priority,
@@ -112,6 +136,17 @@ func NewAMQPConsumer(configKey string, log *zap.Logger, cfg cfgPlugin.Configurer
 		exclusive:         conf.Exclusive,
 		multipleAck:       conf.MultipleAck,
 		requeueOnFail:     conf.RequeueOnFail,
+
+		delayMode:   conf.DelayMode,
+		delayQueues: make(map[string]struct{}),
+
+		publisherConfirms:        conf.PublisherConfirms,
+		publisherConfirmsTimeout: conf.PublisherConfirmsTimeout,
+	}
+
+	err = jb.withQueueOptions(conf.QueueType, conf.QueueArgs, conf.RoutingKeys, conf.BindingArgs)
+	if err != nil {
+		return nil, errors.E(op, err)
 	}
 
 	jb.conn, err = amqp.Dial(conf.Addr)
@@ -127,7 +162,7 @@ func NewAMQPConsumer(configKey string, log *zap.Logger, cfg cfgPlugin.Configurer
 		return nil, errors.E(op, err)
 	}
 
-	pch, err := jb.conn.Channel()
+	pch, err := jb.publishChannel()
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
@@ -165,6 +200,7 @@ func FromPipeline(pipeline *pipeline.Pipeline, log *zap.Logger, cfg cfgPlugin.Co
 		pq:           pq,
 		consumeID:    uuid.NewString(),
 		stopCh:       make(chan struct{}),
+		stoppedCh:    make(chan struct{}),
 		retryTimeout: time.Minute * 5,
 		delayed:      utils.Int64(0),
 
@@ -180,6 +216,27 @@ func FromPipeline(pipeline *pipeline.Pipeline, log *zap.Logger, cfg cfgPlugin.Co
 		exclusive:         pipeline.Bool(exclusive, false),
 		multipleAck:       pipeline.Bool(multipleAsk, false),
 		requeueOnFail:     pipeline.Bool(requeueOnFail, false),
+
+		delayMode:   pipeline.String(delayModeKey, queueDelay),
+		delayQueues: make(map[string]struct{}),
+
+		publisherConfirms:        pipeline.Bool(publisherConfirms, false),
+		publisherConfirmsTimeout: time.Second * time.Duration(pipeline.Int(publisherConfirmsTimeout, 10)),
+	}
+
+	qArgs, err := pipeTable(pipeline, queueArgsKey)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	bArgs, err := pipeTable(pipeline, bindingArgs)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	err = jb.withQueueOptions(pipeline.String(queueType, ""), qArgs, pipeList(pipeline, routingKeysKey), bArgs)
+	if err != nil {
+		return nil, errors.E(op, err)
 	}
 
 	jb.conn, err = amqp.Dial(conf.Addr)
@@ -195,7 +252,7 @@ func FromPipeline(pipeline *pipeline.Pipeline, log *zap.Logger, cfg cfgPlugin.Co
 		return nil, errors.E(op, err)
 	}
 
-	pch, err := jb.conn.Channel()
+	pch, err := jb.publishChannel()
 	if err != nil {
 		return nil, errors.E(op, err)
 	}
@@ -212,6 +269,41 @@ func FromPipeline(pipeline *pipeline.Pipeline, log *zap.Logger, cfg cfgPlugin.Co
 	return jb, nil
 }
 
+// withQueueOptions sets the queue type, the queue and the binding arguments and the routing keys of the queue
+func (c *consumer) withQueueOptions(qType string, qArgs map[string]interface{}, keys []string, bArgs map[string]interface{}) error {
+	var err error
+	c.queueArgs, err = queueArgs(qType, qArgs)
+	if err != nil {
+		return err
+	}
+
+	// quorum and stream queues are replicated, so they are always durable
+	if replicated(c.queueArgs) {
+		if c.exclusive {
+			return errors.Errorf("%s queue can't be exclusive", c.queueArgs[queueTypeArg])
+		}
+
+		c.durable = true
+	}
+
+	c.routingKeys = routingKeys(c.routingKey, keys)
+
+	if c.delayMode == "" {
+		c.delayMode = queueDelay
+	}
+
+	err = validDelayMode(c.delayMode)
+	if err != nil {
+		return err
+	}
+
+	if len(bArgs) > 0 {
+		c.bindingArgs = toTable(bArgs)
+	}
+
+	return nil
+}
+
 func (c *consumer) Push(ctx context.Context, job *jobs.Job) error {
 	const op = errors.Op("rabbitmq_push")
 	// check if the pipeline registered
@@ -276,6 +368,11 @@ func (c *consumer) Run(_ context.Context, p *pipeline.Pipeline) error {
 	// run listener
 	c.listener(deliv)
 
+	err = c.startHopper()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
 	atomic.StoreUint32(&c.listeners, 1)
 	c.log.Debug("pipeline was started", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
 	return nil
@@ -398,9 +495,24 @@ func (c *consumer) Resume(_ context.Context, p string) {
 	c.log.Debug("pipeline was resumed", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
 }
 
-func (c *consumer) Stop(context.Context) error {
+// Stop closes the connection and waits for the delay queues cleanup
+func (c *consumer) Stop(ctx context.Context) error {
 	start := time.Now()
-	c.stopCh <- struct{}{}
+	const op = errors.Op("amqp_stop")
+
+	select {
+	case c.stopCh <- struct{}{}:
+	// the redialer already exited
+	case <-c.stoppedCh:
+	case <-ctx.Done():
+		return errors.E(op, errors.TimeOut, ctx.Err())
+	}
+
+	select {
+	case <-c.stoppedCh:
+	case <-ctx.Done():
+		return errors.E(op, errors.TimeOut, ctx.Err())
+	}
 
 	pipe := c.pipeline.Load().(*pipeline.Pipeline)
 	c.log.Debug("pipeline was stopped", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
@@ -423,39 +535,14 @@ func (c *consumer) handleItem(ctx context.Context, msg *Item) error {
 			return errors.E(op, err)
 		}
 
+		if c.exchangeType == headersExchange {
+			withJobHeaders(table, msg.Headers)
+		}
+
 		// handle timeouts
 		if msg.Options.DelayDuration() > 0 {
 			atomic.AddInt64(c.delayed, 1)
-			// TODO declare separate method for this if condition
-			// TODO dlx cache channel??
-			delayMs := int64(msg.Options.DelayDuration().Seconds() * 1000)
-			tmpQ := fmt.Sprintf("delayed-%d.%s.%s", delayMs, c.exchangeName, c.queue)
-			_, err = pch.QueueDeclare(tmpQ, true, false, false, false, amqp.Table{
-				dlx:           c.exchangeName,
-				dlxRoutingKey: c.routingKey,
-				dlxTTL:        delayMs,
-				dlxExpires:    delayMs * 2,
-			})
-			if err != nil {
-				atomic.AddInt64(c.delayed, ^int64(0))
-				return errors.E(op, err)
-			}
-
-			err = pch.QueueBind(tmpQ, tmpQ, c.exchangeName, false, nil)
-			if err != nil {
-				atomic.AddInt64(c.delayed, ^int64(0))
-				return errors.E(op, err)
-			}
-
-			// insert to the local, limited pipeline
-			err = pch.Publish(c.exchangeName, tmpQ, false, false, amqp.Publishing{
-				Headers:      table,
-				ContentType:  contentType,
-				Timestamp:    time.Now(),
-				DeliveryMode: amqp.Persistent,
-				Body:         msg.Body(),
-			})
-
+			err = c.publishDelayed(ctx, pch, msg, table)
 			if err != nil {
 				atomic.AddInt64(c.delayed, ^int64(0))
 				return errors.E(op, err)
@@ -464,11 +551,12 @@ func (c *consumer) handleItem(ctx context.Context, msg *Item) error {
 			return nil
 		}
 
-		err = pch.Publish(c.exchangeName, c.routingKey, false, false, amqp.Publishing{
+		err = c.publish(ctx, pch, c.exchangeName, c.routingKey, amqp.Publishing{
 			Headers:      table,
 			ContentType:  contentType,
 			Timestamp:    time.Now(),
 			DeliveryMode: amqp.Persistent,
+			MessageId:    msg.ID(),
 			Body:         msg.Body(),
 		})
 
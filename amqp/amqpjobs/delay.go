@@ -0,0 +1,345 @@
+package amqpjobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// delay modes
+	// queueDelay declares a temporary queue with the message TTL for every delay value
+	queueDelay string = "queue"
+	// nativeDelay uses the exchange of the rabbitmq_delayed_message_exchange plugin, falls back to the tiers
+	nativeDelay string = "native"
+	// tiersDelay uses the bounded set of the delay queues, the job hops between the tiers until its delay expires.
+	// The tiers dead-letter the jobs to the hop queue, the hop consumer moves the job to the next tier or to the
+	// pipeline's exchange
+	tiersDelay string = "tiers"
+
+	delayedExchangeType string = "x-delayed-message"
+	delayedTypeArg      string = "x-delayed-type"
+	delayHeader         string = "x-delay"
+	// delayUntilHeader is the time (unix ms) the tiered delayed job is ready
+	delayUntilHeader string = "rr_delay_until"
+
+	// the tiers are the powers of two seconds: 1s, 2s, 4s ... maxDelayTier
+	maxDelayTier = time.Second << 16
+	// the job is ready if the rest of its delay is less than the smallest tier
+	minDelayTier = time.Second
+	// max time the hop consumer waits for the publishing channel and the confirmation to move the job
+	hopTimeout = time.Second * 30
+)
+
+// delayedExchange is the name of the x-delayed-message exchange of the pipeline
+func (c *consumer) delayedExchange() string {
+	return c.exchangeName + ".delayed"
+}
+
+// initDelayedExchange declares the x-delayed-message exchange and binds the queue to it.
+// Returns false if the exchange type is not supported by the broker (plugin is not enabled).
+func (c *consumer) initDelayedExchange(queueName string) (bool, error) {
+	// the failed declaration closes the channel
+	channel, err := c.conn.Channel()
+	if err != nil {
+		return false, err
+	}
+
+	err = channel.ExchangeDeclare(c.delayedExchange(), delayedExchangeType, true, false, false, false, amqp.Table{
+		delayedTypeArg: c.exchangeType,
+	})
+	if err != nil {
+		c.log.Warn("x-delayed-message exchange is not available, the delay tiers are used", zap.String("exchange", c.delayedExchange()), zap.Error(err))
+		return false, nil
+	}
+
+	for i := 0; i < len(c.routingKeys); i++ {
+		err = channel.QueueBind(queueName, c.routingKeys[i], c.delayedExchange(), false, c.bindingArgs)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, channel.Close()
+}
+
+// publishDelayed publishes the delayed job according to the delay mode
+func (c *consumer) publishDelayed(ctx context.Context, pch *amqp.Channel, msg *Item, table amqp.Table) error {
+	pub := amqp.Publishing{
+		Headers:      table,
+		ContentType:  contentType,
+		Timestamp:    time.Now(),
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.ID(),
+		Body:         msg.Body(),
+	}
+
+	switch {
+	case c.delayMode == queueDelay:
+		return c.publishDelayQueue(ctx, pch, msg.Options.DelayDuration(), pub)
+	case c.nativeDelay:
+		table[delayHeader] = msg.Options.DelayDuration().Milliseconds()
+		// the exchange routes the message after the delay, the mandatory message is always returned
+		return c.publishWith(ctx, pch, c.delayedExchange(), c.routingKey, false, pub)
+	case msg.Options.DelayDuration() < minDelayTier:
+		// sub-second delay is not rounded up to the smallest tier
+		return c.publish(ctx, pch, c.exchangeName, c.routingKey, pub)
+	default:
+		table[delayUntilHeader] = time.Now().Add(msg.Options.DelayDuration()).UnixMilli()
+		return c.publishTier(ctx, pch, msg.Options.DelayDuration(), pub)
+	}
+}
+
+// publishDelayQueue publishes the job to the temporary queue of the job's delay, the queue dead-letters the job to the exchange
+func (c *consumer) publishDelayQueue(ctx context.Context, pch *amqp.Channel, delay time.Duration, pub amqp.Publishing) error {
+	delayMs := delay.Milliseconds()
+	tmpQ := fmt.Sprintf("delayed-%d.%s.%s", delayMs, c.exchangeName, c.queue)
+	_, err := pch.QueueDeclare(tmpQ, true, false, false, false, amqp.Table{
+		dlx:           c.exchangeName,
+		dlxRoutingKey: c.routingKey,
+		dlxTTL:        delayMs,
+		dlxExpires:    delayMs * 2,
+	})
+	if err != nil {
+		return err
+	}
+
+	c.delayQueues[tmpQ] = struct{}{}
+
+	// the binding w/o arguments matches all messages of the headers exchange,
+	// so the delayed message is published to the delayed queue via the default exchange
+	delayExchange := ""
+	if c.exchangeType != headersExchange {
+		delayExchange = c.exchangeName
+		err = pch.QueueBind(tmpQ, tmpQ, c.exchangeName, false, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// insert to the local, limited pipeline
+	return c.publish(ctx, pch, delayExchange, tmpQ, pub)
+}
+
+// publishTier publishes the job to the largest delay tier not exceeding the rest of the job's delay
+func (c *consumer) publishTier(ctx context.Context, pch *amqp.Channel, rest time.Duration, pub amqp.Publishing) error {
+	tier := delayTier(rest)
+	name := c.tierQueue(tier)
+
+	// declared every time, the empty tier might be deleted by another consumer on stop
+	_, err := pch.QueueDeclare(name, true, false, false, false, c.tierArgs(tier))
+	if err != nil {
+		return err
+	}
+
+	c.delayQueues[name] = struct{}{}
+
+	// the default exchange routes the message to the queue by its name
+	return c.publish(ctx, pch, "", name, pub)
+}
+
+// tiered returns true if the delayed jobs hop between the delay tiers
+func (c *consumer) tiered() bool {
+	return c.delayMode == tiersDelay || (c.delayMode == nativeDelay && !c.nativeDelay)
+}
+
+// hopQueue is the queue the delay tiers dead-letter the jobs to
+func (c *consumer) hopQueue() string {
+	return fmt.Sprintf("delay-hop.%s.%s", c.exchangeName, c.queue)
+}
+
+// hopArgs returns the arguments of the hop queue, the queue is quorum if the pipeline's queue is replicated
+func (c *consumer) hopArgs() amqp.Table {
+	if replicated(c.queueArgs) {
+		return amqp.Table{queueTypeArg: quorumQueue}
+	}
+
+	return nil
+}
+
+// startHopper starts consuming the hop queue on its own channel. The hop consumer doesn't depend on the pipeline's
+// listener, so the paused pipeline's delayed jobs still hop between the tiers.
+func (c *consumer) startHopper() error {
+	if !c.tiered() {
+		return nil
+	}
+
+	var err error
+	c.hopChan, err = c.conn.Channel()
+	if err != nil {
+		return err
+	}
+
+	err = c.hopChan.Qos(c.prefetch, 0, false)
+	if err != nil {
+		return err
+	}
+
+	deliv, err := c.hopChan.Consume(c.hopQueue(), "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for d := range deliv {
+			c.hop(d)
+		}
+
+		c.log.Debug("delivery channel was closed, leaving the delay hop consumer")
+	}()
+
+	return nil
+}
+
+// hop moves the delayed job to the next tier, or to the pipeline's exchange if the rest of its delay is less than
+// the smallest tier
+func (c *consumer) hop(d amqp.Delivery) {
+	pub := amqp.Publishing{
+		Headers:      d.Headers,
+		ContentType:  d.ContentType,
+		Timestamp:    d.Timestamp,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Body:         d.Body,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hopTimeout)
+	defer cancel()
+
+	select {
+	case pch := <-c.publishChan:
+		var err error
+		until, ok := d.Headers[delayUntilHeader].(int64)
+		if rest := time.Until(time.UnixMilli(until)); ok && rest >= minDelayTier {
+			err = c.publishTier(ctx, pch, rest, pub)
+		} else {
+			delete(pub.Headers, delayUntilHeader)
+			err = c.publish(ctx, pch, c.exchangeName, c.routingKey, pub)
+		}
+		c.publishChan <- pch
+
+		if err != nil {
+			c.log.Error("failed to move the delayed job", zap.String("ID", d.MessageId), zap.Error(err))
+			_ = d.Nack(false, true)
+			return
+		}
+	case <-ctx.Done():
+		c.log.Error("no publishing channel to move the delayed job", zap.String("ID", d.MessageId))
+		_ = d.Nack(false, true)
+		return
+	}
+
+	err := d.Ack(false)
+	if err != nil {
+		c.log.Error("delayed job ack", zap.Error(err))
+	}
+}
+
+// tierQueue returns the name of the delay tier queue of the pipeline
+func (c *consumer) tierQueue(tier time.Duration) string {
+	return fmt.Sprintf("delay-tier-%d.%s.%s", tier.Milliseconds(), c.exchangeName, c.queue)
+}
+
+// tierArgs returns the arguments of the delay tier queue, the queue is quorum if the pipeline's queue is replicated
+func (c *consumer) tierArgs(tier time.Duration) amqp.Table {
+	// the default exchange routes the expired job to the hop queue by its name
+	args := amqp.Table{
+		dlx:           "",
+		dlxRoutingKey: c.hopQueue(),
+		dlxTTL:        tier.Milliseconds(),
+	}
+
+	if replicated(c.queueArgs) {
+		args[queueTypeArg] = quorumQueue
+	}
+
+	return args
+}
+
+// delayTier returns the largest tier not exceeding the delay
+func delayTier(delay time.Duration) time.Duration {
+	tier := minDelayTier
+	for tier*2 <= delay && tier < maxDelayTier {
+		tier *= 2
+	}
+
+	return tier
+}
+
+// cleanupDelayed deletes the delay queues declared by the consumer, all delay tiers and the hop queue of the pipeline. The queues
+// with the waiting jobs are kept unless the pipeline's queue is deleted on stop. The x-delayed-message exchange
+// is deleted with the pipeline's queue if it has no other bindings.
+func (c *consumer) cleanupDelayed() {
+	queues := make([]string, 0, len(c.delayQueues)+18)
+	for name := range c.delayQueues {
+		queues = append(queues, name)
+	}
+
+	if c.delayMode != queueDelay {
+		for tier := minDelayTier; tier <= maxDelayTier; tier *= 2 {
+			if _, ok := c.delayQueues[c.tierQueue(tier)]; !ok {
+				queues = append(queues, c.tierQueue(tier))
+			}
+		}
+
+		// deleted after the tiers, the tiers dead-letter the jobs to it
+		queues = append(queues, c.hopQueue())
+	}
+
+	// the failed deletion (not empty or in use) closes the channel
+	var channel *amqp.Channel
+	for i := 0; i < len(queues); i++ {
+		if channel == nil {
+			var err error
+			channel, err = c.conn.Channel()
+			if err != nil {
+				c.log.Error("delay queues cleanup", zap.Error(err))
+				return
+			}
+		}
+
+		_, err := channel.QueueDelete(queues[i], false, !c.deleteQueueOnStop, false)
+		if err != nil {
+			c.log.Debug("delay queue was not deleted", zap.String("queue", queues[i]), zap.Error(err))
+			channel = nil
+			continue
+		}
+
+		delete(c.delayQueues, queues[i])
+	}
+
+	if c.nativeDelay && c.deleteQueueOnStop {
+		if channel == nil {
+			var err error
+			channel, err = c.conn.Channel()
+			if err != nil {
+				c.log.Error("delay queues cleanup", zap.Error(err))
+				return
+			}
+		}
+
+		err := channel.ExchangeDelete(c.delayedExchange(), true, false)
+		if err != nil {
+			c.log.Debug("delayed exchange was not deleted", zap.String("exchange", c.delayedExchange()), zap.Error(err))
+			channel = nil
+		}
+	}
+
+	if channel != nil {
+		_ = channel.Close()
+	}
+}
+
+// validDelayMode checks the delay_mode option
+func validDelayMode(mode string) error {
+	switch mode {
+	case queueDelay, nativeDelay, tiersDelay:
+		return nil
+	default:
+		return errors.Errorf("unknown delay mode: %s, should be one of: queue, native, tiers", mode)
+	}
+}
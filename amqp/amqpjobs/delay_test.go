@@ -0,0 +1,62 @@
+package amqpjobs
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDelayTier(t *testing.T) {
+	assert.Equal(t, time.Second, delayTier(time.Millisecond*500))
+	assert.Equal(t, time.Second, delayTier(time.Millisecond*1999))
+	assert.Equal(t, time.Second*2, delayTier(time.Second*2))
+	assert.Equal(t, time.Second*64, delayTier(time.Second*100))
+	assert.Equal(t, maxDelayTier, delayTier(time.Hour*24*30))
+
+	// every hop at least halves the rest of the delay until the max tier
+	rest := time.Second*100 + time.Millisecond*300
+	hops := 0
+	for rest >= minDelayTier {
+		rest -= delayTier(rest)
+		hops++
+	}
+	assert.Equal(t, 3, hops)
+}
+
+func TestDelayMode(t *testing.T) {
+	require.NoError(t, validDelayMode(queueDelay))
+	require.NoError(t, validDelayMode(nativeDelay))
+	require.NoError(t, validDelayMode(tiersDelay))
+	require.Error(t, validDelayMode("plugin"))
+
+	c := &consumer{routingKey: "a", delayMode: "plugin"}
+	require.Error(t, c.withQueueOptions("", nil, nil, nil))
+}
+
+func TestTierQueue(t *testing.T) {
+	c := &consumer{exchangeName: "rr", queue: "emails", routingKey: "emails"}
+	assert.Equal(t, "delay-tier-4000.rr.emails", c.tierQueue(time.Second*4))
+	assert.Equal(t, amqp.Table{dlx: "", dlxRoutingKey: "delay-hop.rr.emails", dlxTTL: int64(4000)}, c.tierArgs(time.Second*4))
+	assert.Nil(t, c.hopArgs())
+
+	c.queueArgs = amqp.Table{queueTypeArg: quorumQueue}
+	assert.Equal(t, quorumQueue, c.tierArgs(time.Second)[queueTypeArg])
+	assert.Equal(t, amqp.Table{queueTypeArg: quorumQueue}, c.hopArgs())
+}
+
+func TestTiered(t *testing.T) {
+	c := &consumer{delayMode: queueDelay}
+	assert.False(t, c.tiered())
+
+	c.delayMode = tiersDelay
+	assert.True(t, c.tiered())
+
+	// native mode falls back to the tiers
+	c.delayMode = nativeDelay
+	assert.True(t, c.tiered())
+	c.nativeDelay = true
+	assert.False(t, c.tiered())
+}
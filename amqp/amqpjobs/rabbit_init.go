@@ -35,22 +35,39 @@ func (c *consumer) initRabbitMQ() error {
 		false,
 		c.exclusive,
 		false,
-		nil,
+		c.queueArgs,
 	)
 	if err != nil {
 		return errors.E(op, err)
 	}
 
-	// bind queue to the exchange
-	err = channel.QueueBind(
-		q.Name,
-		c.routingKey,
-		c.exchangeName,
-		false,
-		nil,
-	)
-	if err != nil {
-		return errors.E(op, err)
+	// bind queue to the exchange with every routing key
+	for i := 0; i < len(c.routingKeys); i++ {
+		err = channel.QueueBind(
+			q.Name,
+			c.routingKeys[i],
+			c.exchangeName,
+			false,
+			c.bindingArgs,
+		)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if c.delayMode == nativeDelay {
+		c.nativeDelay, err = c.initDelayedExchange(q.Name)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	// the delay tiers dead-letter the jobs to the hop queue
+	if c.tiered() {
+		_, err = channel.QueueDeclare(c.hopQueue(), true, false, false, false, c.hopArgs())
+		if err != nil {
+			return errors.E(op, err)
+		}
 	}
 
 	return channel.Close()
@@ -14,6 +14,7 @@ import (
 func (c *consumer) redialer() { //nolint:gocognit
 	go func() {
 		const op = errors.Op("rabbitmq_redial")
+		defer close(c.stoppedCh)
 
 		for {
 			select {
@@ -59,8 +60,14 @@ func (c *consumer) redialer() { //nolint:gocognit
 						return errors.E(op, errConnCh)
 					}
 
+					// stream queues require the consumer prefetch
+					errQos := c.consumeChan.Qos(c.prefetch, 0, false)
+					if errQos != nil {
+						return errors.E(op, errQos)
+					}
+
 					// redeclare publish channel
-					pch, errPubCh := c.conn.Channel()
+					pch, errPubCh := c.publishChannel()
 					if errPubCh != nil {
 						return errors.E(op, errPubCh)
 					}
@@ -84,6 +91,12 @@ func (c *consumer) redialer() { //nolint:gocognit
 					// restart listener
 					c.listener(deliv)
 
+					// restart the delay hop consumer
+					errHop := c.startHopper()
+					if errHop != nil {
+						return errors.E(op, errHop)
+					}
+
 					c.log.Info("queues and subscribers was redeclared successfully")
 
 					return nil
@@ -102,6 +115,14 @@ func (c *consumer) redialer() { //nolint:gocognit
 			case <-c.stopCh:
 				pch := <-c.publishChan
 
+				// stop moving the delayed jobs, the unacknowledged ones are returned to the hop queue
+				if c.hopChan != nil {
+					err := c.hopChan.Close()
+					if err != nil {
+						c.log.Error("delay hop channel close", zap.Error(err))
+					}
+				}
+
 				if c.deleteQueueOnStop {
 					msg, err := pch.QueueDelete(c.queue, false, false, false)
 					if err != nil {
@@ -110,6 +131,8 @@ func (c *consumer) redialer() { //nolint:gocognit
 					c.log.Debug("number of purged messages", zap.Int("count", msg))
 				}
 
+				c.cleanupDelayed()
+
 				err := pch.Close()
 				if err != nil {
 					c.log.Error("publish channel close", zap.Error(err))
@@ -2,6 +2,7 @@ package beanstalkjobs
 
 import (
 	"context"
+	stderr "errors"
 	"net"
 	"sync"
 	"time"
@@ -108,6 +109,11 @@ func (cp *ConnPool) Delete(_ context.Context, id uint64) error {
 
 	err := cp.conn.Delete(id)
 	if err != nil {
+		// the job was deleted or reserved by another connection
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			return err
+		}
+
 		// errN contains both, err and internal checkAndRedial error
 		errN := cp.checkAndRedial(err)
 		if errN != nil {
@@ -120,6 +126,64 @@ func (cp *ConnPool) Delete(_ context.Context, id uint64) error {
 	return nil
 }
 
+// Peek returns the next job of the tube in the provided state: ready, delayed or buried.
+// beanstalk.ErrNotFound is returned when there are no jobs in the state.
+func (cp *ConnPool) Peek(_ context.Context, state string) (uint64, []byte, error) {
+	cp.RLock()
+	defer cp.RUnlock()
+
+	id, body, err := cp.peek(state)
+	if err != nil {
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			return 0, nil, err
+		}
+
+		errN := cp.checkAndRedial(err)
+		if errN != nil {
+			return 0, nil, errors.Errorf("err: %s\nerr redial: %s", err, errN)
+		} else {
+			// retry Peek only when we redialed
+			return cp.peek(state)
+		}
+	}
+
+	return id, body, nil
+}
+
+// PeekJob returns the job by the beanstalk ID, beanstalk.ErrNotFound is returned when there is no such job
+func (cp *ConnPool) PeekJob(_ context.Context, id uint64) ([]byte, error) {
+	cp.RLock()
+	defer cp.RUnlock()
+
+	body, err := cp.conn.Peek(id)
+	if err != nil {
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			return nil, err
+		}
+
+		errN := cp.checkAndRedial(err)
+		if errN != nil {
+			return nil, errors.Errorf("err: %s\nerr redial: %s", err, errN)
+		} else {
+			// retry Peek only when we redialed
+			return cp.conn.Peek(id)
+		}
+	}
+
+	return body, nil
+}
+
+func (cp *ConnPool) peek(state string) (uint64, []byte, error) {
+	switch state {
+	case peekDelayed:
+		return cp.t.PeekDelayed()
+	case peekBuried:
+		return cp.t.PeekBuried()
+	default:
+		return cp.t.PeekReady()
+	}
+}
+
 func (cp *ConnPool) Stats(_ context.Context) (map[string]string, error) {
 	cp.RLock()
 	defer cp.RUnlock()
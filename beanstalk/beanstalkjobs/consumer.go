@@ -6,6 +6,7 @@ import (
 	"encoding/gob"
 	"strconv"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -41,6 +42,8 @@ type consumer struct {
 	tName        string
 	tubePriority *uint32
 	priority     int64
+	// beanstalk IDs of the delayed jobs pushed by the consumer, keys are the jobs IDs
+	delayedIDs sync.Map
 
 	stopCh chan struct{}
 }
@@ -334,6 +337,11 @@ func (c *consumer) handleItem(ctx context.Context, item *Item) error {
 		return errors.E(op, err)
 	}
 
+	// to cancel the delayed job
+	if item.Options.Delay > 0 {
+		c.delayedIDs.Store(item.ID(), id)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,194 @@
+package beanstalkjobs
+
+import (
+	"context"
+	stderr "errors"
+
+	"github.com/beanstalkd/go-beanstalk"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+)
+
+const (
+	// tube's jobs states available for the peek
+	peekReady   string = "ready"
+	peekDelayed string = "delayed"
+	peekBuried  string = "buried"
+)
+
+// Peek returns the next ready and the next delayed jobs of the tube.
+// The beanstalk protocol can't list the tube's jobs, offset is not supported.
+func (c *consumer) Peek(ctx context.Context, limit, offset int) ([]*jobs.Job, error) {
+	const op = errors.Op("beanstalk_peek")
+
+	if offset > 0 {
+		return nil, nil
+	}
+
+	res := make([]*jobs.Job, 0, 2)
+	for _, state := range []string{peekReady, peekDelayed} {
+		if len(res) >= limit {
+			break
+		}
+
+		id, body, err := c.pool.Peek(ctx, state)
+		if err != nil {
+			if stderr.Is(err, beanstalk.ErrNotFound) {
+				continue
+			}
+
+			return nil, errors.E(op, err)
+		}
+
+		item := &Item{}
+		err = c.unpack(id, body, item)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		res = append(res, item.toJob())
+	}
+
+	return res, nil
+}
+
+// Delete is not supported, the beanstalk protocol can't find the job by the RoadRunner's job ID
+func (c *consumer) Delete(_ context.Context, _ string) (*jobs.Job, error) {
+	const op = errors.Op("beanstalk_delete")
+	return nil, errors.E(op, errors.Unsupported, errors.Str("beanstalk can't find the job by ID, use Purge instead"))
+}
+
+// Cancel deletes the delayed job before its reservation. The beanstalk protocol can't find the job by the RoadRunner's
+// job ID, so the job is looked up among the delayed jobs pushed by the consumer and, if not found, compared with
+// the next delayed job of the tube (e.g. the job pushed by another RR instance or before the restart).
+func (c *consumer) Cancel(ctx context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("beanstalk_cancel")
+
+	bid, ok := c.delayedIDs.Load(id)
+	if !ok {
+		j, err := c.cancelNextDelayed(ctx, id)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		return j, nil
+	}
+
+	body, err := c.pool.PeekJob(ctx, bid.(uint64))
+	if err != nil {
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			c.delayedIDs.Delete(id)
+			return nil, nil
+		}
+
+		return nil, errors.E(op, err)
+	}
+
+	item := &Item{}
+	err = c.unpack(bid.(uint64), body, item)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	j, err := c.deleteDelayed(ctx, bid.(uint64), item)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return j, nil
+}
+
+// cancelNextDelayed deletes the next delayed job of the tube if it has the provided ID
+func (c *consumer) cancelNextDelayed(ctx context.Context, id string) (*jobs.Job, error) {
+	bid, body, err := c.pool.Peek(ctx, peekDelayed)
+	if err != nil {
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	item := &Item{}
+	// the job pushed not by the RoadRunner can't be unpacked
+	if c.unpack(bid, body, item) != nil || item.ID() != id {
+		return nil, nil
+	}
+
+	return c.deleteDelayed(ctx, bid, item)
+}
+
+// deleteDelayed deletes the peeked delayed job, nil job - the job was already reserved
+func (c *consumer) deleteDelayed(ctx context.Context, bid uint64, item *Item) (*jobs.Job, error) {
+	err := c.pool.Delete(ctx, bid)
+	if err != nil {
+		// the job was reserved
+		if stderr.Is(err, beanstalk.ErrNotFound) {
+			c.delayedIDs.Delete(item.ID())
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	c.delayedIDs.Delete(item.ID())
+	return item.toJob(), nil
+}
+
+// Purge deletes the ready, delayed and buried jobs of the tube
+func (c *consumer) Purge(ctx context.Context, fn func(*jobs.Job)) (int64, error) {
+	const op = errors.Op("beanstalk_purge")
+
+	deleted := int64(0)
+	for _, state := range []string{peekReady, peekDelayed, peekBuried} {
+		for {
+			if ctx.Err() != nil {
+				return deleted, errors.E(op, errors.Errorf("deleted: %d, error: %v", deleted, ctx.Err()))
+			}
+
+			id, body, err := c.pool.Peek(ctx, state)
+			if err != nil {
+				if stderr.Is(err, beanstalk.ErrNotFound) {
+					break
+				}
+
+				return deleted, errors.E(op, err)
+			}
+
+			err = c.pool.Delete(ctx, id)
+			if err != nil {
+				// the job was reserved by the listener
+				if stderr.Is(err, beanstalk.ErrNotFound) {
+					continue
+				}
+
+				return deleted, errors.E(op, err)
+			}
+
+			deleted++
+
+			// the job pushed not by the RoadRunner can't be unpacked
+			item := &Item{}
+			if c.unpack(id, body, item) == nil {
+				c.delayedIDs.Delete(item.ID())
+				fn(item.toJob())
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+func (i *Item) toJob() *jobs.Job {
+	return &jobs.Job{
+		Job:     i.Job,
+		Ident:   i.Ident,
+		Payload: i.Payload,
+		Headers: i.Headers,
+		Options: &jobs.Options{
+			Priority: i.Options.Priority,
+			Pipeline: i.Options.Pipeline,
+			Delay:    i.Options.Delay,
+		},
+	}
+}
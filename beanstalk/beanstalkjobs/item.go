@@ -94,6 +94,17 @@ func (i *Item) Nack() error {
 	return i.Options.conn.Delete(i.Options.id)
 }
 
+// Extend touches the reserved job, beanstalk restarts the job's time to run (the duration is not supported)
+func (i *Item) Extend(_ time.Duration) error {
+	const op = errors.Op("beanstalk_extend")
+	err := i.Options.conn.Touch(i.Options.id)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
 func (i *Item) Requeue(headers map[string][]string, delay int64) error {
 	// overwrite the delay
 	i.Options.Delay = delay
@@ -37,6 +37,11 @@ func (c *consumer) listen() {
 				continue
 			}
 
+			// the delayed job can't be canceled after the reservation
+			if bid, ok := c.delayedIDs.Load(item.ID()); ok && bid.(uint64) == id {
+				c.delayedIDs.Delete(item.ID())
+			}
+
 			// insert job into the priority queue
 			c.pq.Insert(item)
 		}
@@ -0,0 +1,217 @@
+package boltjobs
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"sync/atomic"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Peek returns the jobs waiting in the PushBucket, then the jobs in the DelayBucket
+func (c *consumer) Peek(_ context.Context, limit, offset int) ([]*jobs.Job, error) {
+	const op = errors.Op("boltdb_jobs_peek")
+
+	res := make([]*jobs.Job, 0, 10)
+	err := c.db.View(func(tx *bolt.Tx) error {
+		for _, bucket := range []string{PushBucket, DelayBucket} {
+			cursor := tx.Bucket(utils.AsBytes(bucket)).Cursor()
+			for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+				if offset > 0 {
+					offset--
+					continue
+				}
+
+				if len(res) >= limit {
+					return nil
+				}
+
+				item, err := decode(v)
+				if err != nil {
+					return err
+				}
+
+				res = append(res, item.toJob())
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return res, nil
+}
+
+// Delete removes the job from the PushBucket or the DelayBucket
+func (c *consumer) Delete(_ context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("boltdb_jobs_delete")
+
+	var job *jobs.Job
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		pushB := tx.Bucket(utils.AsBytes(PushBucket))
+		if v := pushB.Get(utils.AsBytes(id)); v != nil {
+			item, err := decode(v)
+			if err != nil {
+				return err
+			}
+
+			err = pushB.Delete(utils.AsBytes(id))
+			if err != nil {
+				return err
+			}
+
+			job = item.toJob()
+			return nil
+		}
+
+		var err error
+		job, err = deleteDelayed(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if job != nil {
+		if job.Options.Delay > 0 {
+			atomic.AddUint64(c.delayed, ^uint64(0))
+		} else {
+			atomic.AddUint64(c.active, ^uint64(0))
+		}
+	}
+
+	return job, nil
+}
+
+// Cancel deletes the delayed job from the DelayBucket before its delay expires
+func (c *consumer) Cancel(_ context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("boltdb_jobs_cancel")
+
+	var job *jobs.Job
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		job, err = deleteDelayed(tx, id)
+		return err
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if job != nil {
+		atomic.AddUint64(c.delayed, ^uint64(0))
+	}
+
+	return job, nil
+}
+
+// deleteDelayed deletes the job from the DelayBucket, delayed jobs are stored by the time keys
+func deleteDelayed(tx *bolt.Tx, id string) (*jobs.Job, error) {
+	cursor := tx.Bucket(utils.AsBytes(DelayBucket)).Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.Next() {
+		item, err := decode(v)
+		if err != nil {
+			return nil, err
+		}
+
+		if item.ID() != id {
+			continue
+		}
+
+		err = cursor.Delete()
+		if err != nil {
+			return nil, err
+		}
+
+		return item.toJob(), nil
+	}
+
+	return nil, nil
+}
+
+// Purge removes all jobs from the PushBucket and the DelayBucket, the fn is called after the transaction (it might push the jobs)
+func (c *consumer) Purge(_ context.Context, fn func(*jobs.Job)) (int64, error) {
+	const op = errors.Op("boltdb_jobs_purge")
+
+	var active, delayed uint64
+	var deleted []*jobs.Job
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		var err error
+		deleted = deleted[:0]
+		active, err = purgeBucket(tx.Bucket(utils.AsBytes(PushBucket)), &deleted)
+		if err != nil {
+			return err
+		}
+
+		delayed, err = purgeBucket(tx.Bucket(utils.AsBytes(DelayBucket)), &deleted)
+		return err
+	})
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	for i := 0; i < len(deleted); i++ {
+		fn(deleted[i])
+	}
+
+	if active > 0 {
+		atomic.AddUint64(c.active, ^(active - 1))
+	}
+
+	if delayed > 0 {
+		atomic.AddUint64(c.delayed, ^(delayed - 1))
+	}
+
+	return int64(active + delayed), nil
+}
+
+// purgeBucket deletes all keys of the bucket, collects the deleted jobs and returns their number
+func purgeBucket(b *bolt.Bucket, jbs *[]*jobs.Job) (uint64, error) {
+	deleted := uint64(0)
+	cursor := b.Cursor()
+	for k, v := cursor.First(); k != nil; k, v = cursor.First() {
+		// the value is valid only during the transaction
+		item, errD := decode(v)
+
+		err := cursor.Delete()
+		if err != nil {
+			return deleted, err
+		}
+
+		deleted++
+		if errD == nil {
+			*jbs = append(*jbs, item.toJob())
+		}
+	}
+
+	return deleted, nil
+}
+
+func decode(v []byte) (*Item, error) {
+	item := &Item{}
+	err := gob.NewDecoder(bytes.NewReader(v)).Decode(item)
+	if err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (i *Item) toJob() *jobs.Job {
+	return &jobs.Job{
+		Job:     i.Job,
+		Ident:   i.Ident,
+		Payload: i.Payload,
+		Headers: i.Headers,
+		Options: &jobs.Options{
+			Priority: i.Options.Priority,
+			Pipeline: i.Options.Pipeline,
+			Delay:    i.Options.Delay,
+		},
+	}
+}
@@ -11,6 +11,12 @@ import (
 
 const PluginName = "informer"
 
+// PoolsInformer is an optional interface of the plugins with several workers pools (e.g. jobs)
+type PoolsInformer interface {
+	// PoolsWorkers returns workers grouped by the pool name
+	PoolsWorkers() map[string][]*process.State
+}
+
 type Plugin struct {
 	withJobs    map[string]informer.JobsStat
 	withWorkers map[string]informer.Informer
@@ -32,6 +38,21 @@ func (p *Plugin) Workers(name string) []*process.State {
 	return svc.Workers()
 }
 
+// Pools provides workers grouped by the pool for the requested plugin,
+// plugins with a single pool are reported with the pool named after the plugin
+func (p *Plugin) Pools(name string) map[string][]*process.State {
+	svc, ok := p.withWorkers[name]
+	if !ok {
+		return nil
+	}
+
+	if pi, ok := svc.(PoolsInformer); ok {
+		return pi.PoolsWorkers()
+	}
+
+	return map[string][]*process.State{name: svc.Workers()}
+}
+
 // Jobs provides information about jobs for the registered plugin using jobs
 func (p *Plugin) Jobs(name string) []*jobs.State {
 	svc, ok := p.withJobs[name]
@@ -40,6 +40,27 @@ func (rpc *rpc) Workers(service string, list *WorkerList) error {
 	return nil
 }
 
+// PoolList contains workers grouped by the pool name.
+type PoolList struct {
+	// Pools are workers lists, keys are pools names.
+	Pools map[string]*WorkerList `json:"pools"`
+}
+
+// Pools state of a given service, every pool is reported separately.
+func (rpc *rpc) Pools(service string, list *PoolList) error {
+	pools := rpc.srv.Pools(service)
+	if pools == nil {
+		return nil
+	}
+
+	list.Pools = make(map[string]*WorkerList, len(pools))
+	for name, workers := range pools {
+		list.Pools[name] = &WorkerList{Workers: workers}
+	}
+
+	return nil
+}
+
 func (rpc *rpc) Jobs(service string, out *[]*jobs.State) error {
 	*out = rpc.srv.Jobs(service)
 	return nil
@@ -0,0 +1,278 @@
+package jobs
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+	"go.uber.org/zap"
+)
+
+// batchOptions configures delivering several jobs of the pipeline to the worker in one payload
+type batchOptions struct {
+	// Size is the max number of jobs in the batch
+	Size int `mapstructure:"size"`
+	// MaxWait is the max time to collect the batch, default - 1s
+	MaxWait time.Duration `mapstructure:"max_wait"`
+}
+
+func (b *batchOptions) InitDefaults() error {
+	if b.Size <= 0 {
+		return errors.Errorf("batch size should be positive, provided: %d", b.Size)
+	}
+
+	if b.MaxWait == 0 {
+		b.MaxWait = time.Second
+	}
+
+	if b.MaxWait < 0 {
+		return errors.Errorf("batch max_wait should be positive, provided: %s", b.MaxWait)
+	}
+
+	return nil
+}
+
+// batchContext is the context of the payload with the batch of jobs,
+// payload body is the JSON array of the jobs payloads in the same order
+type batchContext struct {
+	Pipeline string        `json:"pipeline"`
+	Batch    []*jobContext `json:"batch"`
+}
+
+// batcher collects the jobs of the pipeline
+type batcher struct {
+	mu    sync.Mutex
+	items []*item
+	// pool of the collected jobs
+	wp *workersPool
+	// incremented on every taken batch, protects from the stale max_wait timers
+	gen   uint64
+	timer *time.Timer
+}
+
+// addToBatch adds the job to the pipeline's batch and executes the batch when it's full.
+// Not full batch is executed by the max_wait timer.
+func (p *Plugin) addToBatch(wp *workersPool, opts *pipelineOptions, it *item) {
+	b := opts.batcher
+
+	b.mu.Lock()
+	// outcomes of the batch are matched by the job ID, the duplicate starts the next batch
+	prev := b.split(it.ID())
+
+	b.wp = wp
+	b.items = append(b.items, it)
+
+	if len(b.items) == 1 {
+		gen := b.gen
+		b.timer = time.AfterFunc(opts.Batch.MaxWait, func() {
+			p.flushBatch(opts, gen)
+		})
+	}
+
+	if len(b.items) < opts.Batch.Size {
+		b.mu.Unlock()
+		if len(prev) > 0 {
+			p.execBatch(wp, opts, prev)
+		}
+		return
+	}
+
+	items := b.take()
+	b.mu.Unlock()
+
+	if len(prev) > 0 {
+		p.execBatch(wp, opts, prev)
+	}
+	p.execBatch(wp, opts, items)
+}
+
+// split takes the collected jobs if the batch already contains the job with the ID, should be called under the lock
+func (b *batcher) split(id string) []*item {
+	for i := 0; i < len(b.items); i++ {
+		if b.items[i].ID() == id {
+			return b.take()
+		}
+	}
+
+	return nil
+}
+
+// flushBatch executes the collected jobs of the batch generation, gen 0 - the current generation
+func (p *Plugin) flushBatch(opts *pipelineOptions, gen uint64) {
+	b := opts.batcher
+
+	b.mu.Lock()
+	if len(b.items) == 0 || (gen != 0 && gen != b.gen) {
+		b.mu.Unlock()
+		return
+	}
+
+	wp := b.wp
+	items := b.take()
+	b.mu.Unlock()
+
+	p.execBatch(wp, opts, items)
+}
+
+// take returns the collected jobs and starts the next generation, should be called under the lock
+func (b *batcher) take() []*item {
+	items := b.items
+	b.items = nil
+	b.gen++
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	return items
+}
+
+// execBatch sends the jobs to the worker in one payload and applies the per job outcomes
+func (p *Plugin) execBatch(wp *workersPool, opts *pipelineOptions, items []*item) { //nolint:gocognit
+	start := time.Now()
+
+	// the batch is a single execution for the rate limit and concurrency cap
+	if opts.throttle != nil {
+		delay, ok := opts.throttle.tryAcquire()
+		if !ok {
+			for i := 0; i < len(items); i++ {
+				p.throttleJob(items[i], delay)
+				atomic.AddInt64(&opts.drain.active, -1)
+			}
+			return
+		}
+		defer opts.throttle.release()
+	}
+
+	defer func() {
+		for i := 0; i < len(items); i++ {
+			atomic.AddInt64(&opts.drain.active, -1)
+			if opts.drain.isDraining() {
+				atomic.AddInt64(&opts.drain.drained, 1)
+			}
+		}
+	}()
+
+	bctx := &batchContext{
+		Pipeline: items[0].ctx.Pipeline,
+		Batch:    make([]*jobContext, 0, len(items)),
+	}
+	bodies := make([]string, 0, len(items))
+	acks := make(map[string]jobs.Acknowledger, len(items))
+
+	for i := 0; i < len(items); i++ {
+		bctx.Batch = append(bctx.Batch, items[i].ctx.workerContext())
+		bodies = append(bodies, utils.AsString(items[i].Body()))
+		acks[items[i].ID()] = items[i]
+	}
+
+	ctx, err := json.Marshal(bctx)
+	if err != nil {
+		p.failBatch(items, err.Error())
+		return
+	}
+
+	body, err := json.Marshal(bodies)
+	if err != nil {
+		p.failBatch(items, err.Error())
+		return
+	}
+
+	// the batch shares the lease, heartbeat of any job extends it
+	l := newLease(opts.JobTimeout)
+	for i := 0; i < len(items); i++ {
+		items[i].observeStart()
+		items[i].event(EventStarted)
+		p.startProgress(items[i], l)
+	}
+	defer func() {
+		for i := 0; i < len(items); i++ {
+			p.finishProgress(items[i])
+		}
+	}()
+
+	p.log.Debug("jobs batch processing was started", zap.String("pipeline", bctx.Pipeline), zap.Int("size", len(items)), zap.Time("start", start))
+
+	execStart := time.Now()
+	resp, err := p.exec(wp, body, ctx, l)
+	for i := 0; i < len(items); i++ {
+		items[i].observeExec(time.Since(execStart), err)
+	}
+
+	if err != nil {
+		p.log.Error("jobs batch processed with errors", zap.Error(err), zap.String("pipeline", bctx.Pipeline), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		p.failBatch(items, err.Error())
+		return
+	}
+
+	// if response is nil or body is nil, just acknowledge the jobs
+	if resp == nil || resp.Body == nil {
+		for i := 0; i < len(items); i++ {
+			p.batchOutcome(items[i], items[i].Ack(), start)
+		}
+		return
+	}
+
+	handled, err := p.respHandler.HandleBatch(resp, acks)
+	if err != nil {
+		p.log.Error("batch response handler error", zap.Error(err), zap.String("pipeline", bctx.Pipeline), zap.ByteString("response", resp.Body), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		// jobs malformed, acknowledge them (or move to the dead-letter pipeline) to prevent endless loop
+		for i := 0; i < len(items); i++ {
+			atomic.AddUint64(p.metrics.jobsErr, 1)
+			errAck := items[i].DeadLetter(err.Error())
+			if errAck != nil {
+				p.log.Error("acknowledge failed, job might be lost", zap.String("ID", items[i].ID()), zap.Error(err), zap.Error(errAck))
+			}
+		}
+		return
+	}
+
+	for i := 0; i < len(items); i++ {
+		errH, ok := handled[items[i].ID()]
+		if !ok {
+			p.log.Error("no job outcome in the batch response", zap.String("ID", items[i].ID()), zap.String("pipeline", bctx.Pipeline))
+			p.failBatch(items[i:i+1], "no job outcome in the batch response")
+			continue
+		}
+
+		p.batchOutcome(items[i], errH, start)
+	}
+}
+
+// batchOutcome reports the job's outcome, err is the acknowledgement error
+func (p *Plugin) batchOutcome(it *item, err error, start time.Time) {
+	if err != nil {
+		atomic.AddUint64(p.metrics.jobsErr, 1)
+		p.log.Error("acknowledge error, job might be missed", zap.Error(err), zap.String("ID", it.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		return
+	}
+
+	atomic.AddUint64(p.metrics.jobsOk, 1)
+	p.log.Debug("job was processed successfully", zap.String("ID", it.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
+
+// failBatch fails the jobs according to the pipeline's retry policy or negatively acknowledges them
+func (p *Plugin) failBatch(items []*item, reason string) {
+	for i := 0; i < len(items); i++ {
+		atomic.AddUint64(p.metrics.jobsErr, 1)
+		items[i].reason = reason
+
+		if items[i].retryable() {
+			err := items[i].Fail(reason, true, nil, 0)
+			if err != nil {
+				p.log.Error("job retry failed", zap.String("ID", items[i].ID()), zap.Error(err))
+			}
+			continue
+		}
+
+		err := items[i].Nack()
+		if err != nil {
+			p.log.Error("negatively acknowledge failed", zap.String("ID", items[i].ID()), zap.Error(err))
+		}
+	}
+}
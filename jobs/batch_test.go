@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchOptions(t *testing.T) {
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"batch":  `{"size": 100}`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.Batch)
+	assert.Equal(t, 100, opts.Batch.Size)
+	assert.Equal(t, time.Second, opts.Batch.MaxWait)
+	require.NotNil(t, opts.batcher)
+
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, opts.batcher)
+
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"batch": map[string]interface{}{
+			"max_wait": "100ms",
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestBatcherTake(t *testing.T) {
+	b := &batcher{gen: 1}
+	b.items = append(b.items, &item{}, &item{})
+	b.timer = time.NewTimer(time.Hour)
+
+	items := b.take()
+	assert.Len(t, items, 2)
+	assert.Empty(t, b.items)
+	assert.Nil(t, b.timer)
+	assert.Equal(t, uint64(2), b.gen)
+}
+
+func TestBatcherSplit(t *testing.T) {
+	newItem := func(id string) *item {
+		return &item{Item: &testPQItem{id: id}, ctx: &jobContext{ID: id, Pipeline: "test"}}
+	}
+
+	b := &batcher{gen: 1}
+	b.items = append(b.items, newItem("1"), newItem("2"))
+	b.timer = time.NewTimer(time.Hour)
+
+	assert.Nil(t, b.split("3"))
+	assert.Len(t, b.items, 2)
+	assert.Equal(t, uint64(1), b.gen)
+
+	items := b.split("2")
+	require.Len(t, items, 2)
+	assert.Equal(t, "1", items[0].ID())
+	assert.Equal(t, "2", items[1].ID())
+	assert.Empty(t, b.items)
+	assert.Nil(t, b.timer)
+	assert.Equal(t, uint64(2), b.gen)
+}
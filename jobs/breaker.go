@@ -0,0 +1,407 @@
+package jobs
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// circuit breaker states
+	BreakerClosed   string = "closed"
+	BreakerOpen     string = "open"
+	BreakerHalfOpen string = "half_open"
+
+	// number of the sliding window buckets
+	breakerBuckets int64 = 10
+)
+
+// breakerOptions configures the circuit breaker which pauses the pipeline when its jobs fail too often
+type breakerOptions struct {
+	// Window is the sliding window of the jobs outcomes, default - 1m
+	Window time.Duration `mapstructure:"window"`
+	// Threshold is the error ratio (0..1] in the window which opens the breaker, default - 0.5
+	Threshold float64 `mapstructure:"threshold"`
+	// MinJobs is the min number of the jobs outcomes in the window to evaluate the error ratio, default - 10
+	MinJobs int64 `mapstructure:"min_jobs"`
+	// Cooldown is the time the pipeline stays paused before the probe job, default - 30s
+	Cooldown time.Duration `mapstructure:"cooldown"`
+}
+
+func (b *breakerOptions) InitDefaults() error {
+	if b.Window == 0 {
+		b.Window = time.Minute
+	}
+
+	if b.Threshold == 0 {
+		b.Threshold = 0.5
+	}
+
+	if b.MinJobs == 0 {
+		b.MinJobs = 10
+	}
+
+	if b.Cooldown == 0 {
+		b.Cooldown = time.Second * 30
+	}
+
+	if b.Window < time.Second {
+		return errors.Errorf("circuit_breaker window should be at least 1s, provided: %s", b.Window)
+	}
+
+	if b.Threshold < 0 || b.Threshold > 1 {
+		return errors.Errorf("circuit_breaker threshold should be in the range (0, 1], provided: %f", b.Threshold)
+	}
+
+	if b.MinJobs < 0 {
+		return errors.Errorf("circuit_breaker min_jobs should be positive, provided: %d", b.MinJobs)
+	}
+
+	if b.Cooldown < 0 {
+		return errors.Errorf("circuit_breaker cooldown should be positive, provided: %s", b.Cooldown)
+	}
+
+	return nil
+}
+
+// BreakerState is the circuit breaker state of the pipeline, see the Breakers RPC
+type BreakerState struct {
+	Pipeline string `json:"pipeline"`
+	State    string `json:"state"`
+	// Jobs is the number of the jobs outcomes in the window
+	Jobs int64 `json:"jobs"`
+	// ErrorRatio of the jobs in the window
+	ErrorRatio float64 `json:"error_ratio"`
+	// Opens is the number of times the breaker was opened
+	Opens   uint64    `json:"opens"`
+	Changed time.Time `json:"changed_at"`
+}
+
+type breakerBucket struct {
+	// window slot of the bucket, see breaker.slot
+	slot   int64
+	ok     int64
+	failed int64
+}
+
+// admission decisions of the breaker
+const (
+	admitPass int = iota
+	admitProbe
+	admitReject
+)
+
+// breaker is the circuit breaker of the pipeline. The closed breaker counts the jobs outcomes in the sliding window
+// and opens when the error ratio crosses the threshold: the pipeline is paused for the cooldown,
+// then resumed (half_open) to execute a single probe job, which outcome closes or opens the breaker again.
+type breaker struct {
+	opts *breakerOptions
+	// serializes the state transitions with the driver's Pause/Resume calls
+	transMu sync.Mutex
+
+	mu      sync.Mutex
+	state   string
+	buckets [breakerBuckets]breakerBucket
+	// the probe job was taken in the half_open state
+	probing bool
+	opens   uint64
+	changed time.Time
+	// cooldown timer of the open breaker
+	timer *time.Timer
+	// pipeline was stopped or destroyed
+	stopped bool
+}
+
+func newBreaker(opts *breakerOptions) *breaker {
+	if opts == nil {
+		return nil
+	}
+
+	return &breaker{
+		opts:    opts,
+		state:   BreakerClosed,
+		changed: time.Now(),
+	}
+}
+
+// slot returns the window slot number of the time
+func (b *breaker) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(b.opts.Window/time.Duration(breakerBuckets))
+}
+
+// window returns the number of the outcomes and the error ratio in the sliding window, must be called under the lock
+func (b *breaker) window(now time.Time) (int64, float64) {
+	cur := b.slot(now)
+	var ok, failed int64
+	for i := 0; i < len(b.buckets); i++ {
+		if cur-b.buckets[i].slot >= breakerBuckets {
+			continue
+		}
+
+		ok += b.buckets[i].ok
+		failed += b.buckets[i].failed
+	}
+
+	if ok+failed == 0 {
+		return 0, 0
+	}
+
+	return ok + failed, float64(failed) / float64(ok+failed)
+}
+
+// setState must be called under the lock
+func (b *breaker) setState(state string) {
+	b.state = state
+	b.changed = time.Now()
+}
+
+// record adds the job outcome and returns the breaker's state transition, empty strings - no transition.
+// Outcomes of the open breaker and the outcomes of the jobs other than the probe in the half_open state are ignored.
+func (b *breaker) record(success, probe bool) (string, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.stopped {
+		return "", ""
+	}
+
+	switch b.state {
+	case BreakerClosed:
+		now := time.Now()
+		cur := b.slot(now)
+		bucket := &b.buckets[cur%breakerBuckets]
+		if bucket.slot != cur {
+			*bucket = breakerBucket{slot: cur}
+		}
+
+		if success {
+			bucket.ok++
+			return "", ""
+		}
+
+		bucket.failed++
+		jobs, ratio := b.window(now)
+		if jobs < b.opts.MinJobs || ratio < b.opts.Threshold {
+			return "", ""
+		}
+
+		b.opens++
+		b.setState(BreakerOpen)
+		return BreakerClosed, BreakerOpen
+	case BreakerHalfOpen:
+		if !probe {
+			return "", ""
+		}
+
+		b.probing = false
+		if success {
+			b.buckets = [breakerBuckets]breakerBucket{}
+			b.setState(BreakerClosed)
+			return BreakerHalfOpen, BreakerClosed
+		}
+
+		b.opens++
+		b.setState(BreakerOpen)
+		return BreakerHalfOpen, BreakerOpen
+	default:
+		return "", ""
+	}
+}
+
+// admit decides whether the delivered job is executed
+func (b *breaker) admit() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		return admitReject
+	case BreakerHalfOpen:
+		if b.probing {
+			return admitReject
+		}
+
+		b.probing = true
+		return admitProbe
+	default:
+		return admitPass
+	}
+}
+
+// reset closes the breaker and stops the cooldown, stopped breaker ignores the outcomes
+func (b *breaker) reset(stop bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	b.stopped = stop
+	b.probing = false
+	b.buckets = [breakerBuckets]breakerBucket{}
+	if b.state != BreakerClosed {
+		b.setState(BreakerClosed)
+	}
+}
+
+func (b *breaker) getState(pipe string) *BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	jobs, ratio := b.window(time.Now())
+	return &BreakerState{
+		Pipeline:   pipe,
+		State:      b.state,
+		Jobs:       jobs,
+		ErrorRatio: ratio,
+		Opens:      b.opens,
+		Changed:    b.changed,
+	}
+}
+
+// breakerAdmit decides whether the job is executed, the pipeline is paused while the probe job is executed.
+// Jobs rejected by the breaker should be returned to the driver.
+func (p *Plugin) breakerAdmit(b *breaker, it *item) bool {
+	switch b.admit() {
+	case admitPass:
+		return true
+	case admitProbe:
+		it.probe = true
+		b.transMu.Lock()
+		p.pauseConsumer(it.ctx.Pipeline)
+		b.transMu.Unlock()
+
+		p.log.Info("circuit breaker probe job", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline))
+		return true
+	default:
+		return false
+	}
+}
+
+// breakerRecord records the outcome of the finished or retried job in the pipeline's circuit breaker
+func (p *Plugin) breakerRecord(it *item, success bool) {
+	opts := p.options(it.ctx.Pipeline)
+	if opts == nil || opts.breaker == nil {
+		return
+	}
+
+	b := opts.breaker
+	from, to := b.record(success, it.probe)
+	if to == "" {
+		return
+	}
+
+	pipe := it.ctx.Pipeline
+	b.transMu.Lock()
+	defer b.transMu.Unlock()
+
+	switch to {
+	case BreakerOpen:
+		// the pipeline was already paused for the probe
+		if from == BreakerClosed {
+			p.pauseConsumer(pipe)
+		}
+
+		b.mu.Lock()
+		if b.state == BreakerOpen {
+			b.timer = time.AfterFunc(b.opts.Cooldown, func() {
+				p.breakerHalfOpen(pipe, b)
+			})
+		}
+		b.mu.Unlock()
+
+		p.pipeMetrics.breakerOpens.WithLabelValues(pipe, p.pipelineDriver(pipe)).Inc()
+		p.log.Warn("circuit breaker was opened, pipeline was paused", zap.String("pipeline", pipe), zap.String("from", from), zap.Duration("cooldown", b.opts.Cooldown))
+	case BreakerClosed:
+		p.resumeConsumer(pipe)
+		p.log.Info("circuit breaker was closed, pipeline was resumed", zap.String("pipeline", pipe))
+	}
+}
+
+// breakerReturn releases the probe of the job returned to the driver without the outcome,
+// the pipeline is resumed to take another probe job
+func (p *Plugin) breakerReturn(it *item) {
+	if !it.probe {
+		return
+	}
+
+	it.probe = false
+	opts := p.options(it.ctx.Pipeline)
+	if opts == nil || opts.breaker == nil {
+		return
+	}
+
+	b := opts.breaker
+	b.transMu.Lock()
+	defer b.transMu.Unlock()
+
+	b.mu.Lock()
+	probing := b.state == BreakerHalfOpen && b.probing && !b.stopped
+	if probing {
+		b.probing = false
+	}
+	b.mu.Unlock()
+
+	if probing {
+		p.resumeConsumer(it.ctx.Pipeline)
+	}
+}
+
+// breakerHalfOpen resumes the pipeline after the cooldown to take the probe job
+func (p *Plugin) breakerHalfOpen(pipe string, b *breaker) {
+	b.transMu.Lock()
+	defer b.transMu.Unlock()
+
+	b.mu.Lock()
+	open := b.state == BreakerOpen && !b.stopped
+	b.timer = nil
+	b.mu.Unlock()
+
+	// the breaker was reset by Pause/Resume or the pipeline was stopped
+	if !open {
+		return
+	}
+
+	p.resumeConsumer(pipe)
+
+	b.mu.Lock()
+	// state is set after the resume, the probe job pauses the pipeline again
+	if b.state == BreakerOpen {
+		b.setState(BreakerHalfOpen)
+	}
+	b.mu.Unlock()
+
+	p.log.Info("circuit breaker is half-open, waiting for the probe job", zap.String("pipeline", pipe))
+}
+
+// resetBreaker closes the pipeline's circuit breaker, e.g. when the pipeline is paused or resumed manually
+func (p *Plugin) resetBreaker(pipe string, stop bool) {
+	opts := p.options(pipe)
+	if opts == nil || opts.breaker == nil {
+		return
+	}
+
+	opts.breaker.reset(stop)
+}
+
+// Breakers returns the circuit breakers state of the pipelines sorted by the pipeline name
+func (p *Plugin) Breakers() []*BreakerState {
+	res := make([]*BreakerState, 0, 2)
+	p.pipelineOpts.Range(func(key, value interface{}) bool {
+		if b := value.(*pipelineOptions).breaker; b != nil {
+			res = append(res, b.getState(key.(string)))
+		}
+		return true
+	})
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pipeline < res[j].Pipeline
+	})
+
+	return res
+}
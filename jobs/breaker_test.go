@@ -0,0 +1,212 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreakerOptions(t *testing.T) {
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"config": map[string]interface{}{
+			"circuit_breaker": map[string]interface{}{
+				"threshold": 0.2,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.breaker)
+	assert.Equal(t, time.Minute, opts.CircuitBreaker.Window)
+	assert.Equal(t, 0.2, opts.CircuitBreaker.Threshold)
+	assert.Equal(t, int64(10), opts.CircuitBreaker.MinJobs)
+	assert.Equal(t, time.Second*30, opts.CircuitBreaker.Cooldown)
+
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, opts.breaker)
+
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":            "test",
+		"driver":          "memory",
+		"circuit_breaker": `{"threshold": 1.5}`,
+	})
+	assert.Error(t, err)
+}
+
+func TestBreakerWindow(t *testing.T) {
+	b := newBreaker(&breakerOptions{Window: time.Second, Threshold: 0.5, MinJobs: 4, Cooldown: time.Second})
+
+	for i := 0; i < 3; i++ {
+		from, to := b.record(false, false)
+		assert.Empty(t, from)
+		assert.Empty(t, to)
+	}
+
+	// outcomes are out of the window
+	time.Sleep(time.Millisecond * 1100)
+	_, to := b.record(false, false)
+	assert.Empty(t, to)
+
+	b.record(true, false)
+	b.record(true, false)
+	from, to := b.record(false, false)
+	assert.Equal(t, BreakerClosed, from)
+	assert.Equal(t, BreakerOpen, to)
+	assert.Equal(t, admitReject, b.admit())
+}
+
+func TestBreaker(t *testing.T) {
+	p := testInspectPlugin()
+	c := &testConsumer{}
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", c)
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"circuit_breaker": map[string]interface{}{
+			"min_jobs": 2,
+			"cooldown": "200ms",
+		},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+
+	newIt := func(id string) *item {
+		return &item{Item: &testPQItem{id: id}, ctx: &jobContext{ID: id, Pipeline: "test"}, p: p}
+	}
+
+	p.breakerRecord(newIt("1"), true)
+	p.breakerRecord(newIt("1"), true)
+	p.breakerRecord(newIt("2"), false)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.paused))
+	p.breakerRecord(newIt("3"), false)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.paused))
+	assert.Equal(t, BreakerOpen, p.Breakers()[0].State)
+	assert.False(t, p.breakerAdmit(opts.breaker, newIt("4")))
+
+	// cooldown, the pipeline is resumed to take the probe job
+	time.Sleep(time.Millisecond * 400)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.resumed))
+	assert.Equal(t, BreakerHalfOpen, p.Breakers()[0].State)
+
+	probe := newIt("5")
+	require.True(t, p.breakerAdmit(opts.breaker, probe))
+	assert.True(t, probe.probe)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.paused))
+	assert.False(t, p.breakerAdmit(opts.breaker, newIt("6")))
+
+	// failed probe opens the breaker again
+	p.breakerRecord(probe, false)
+	assert.Equal(t, BreakerOpen, p.Breakers()[0].State)
+	assert.Equal(t, uint64(2), p.Breakers()[0].Opens)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.paused))
+
+	time.Sleep(time.Millisecond * 400)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&c.resumed))
+
+	// the throttled probe is returned without the outcome, the pipeline is resumed for another probe
+	probe = newIt("7")
+	require.True(t, p.breakerAdmit(opts.breaker, probe))
+	p.breakerReturn(probe)
+	assert.False(t, probe.probe)
+	assert.Equal(t, BreakerHalfOpen, p.Breakers()[0].State)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&c.resumed))
+
+	probe = newIt("7")
+	require.True(t, p.breakerAdmit(opts.breaker, probe))
+	// outcomes of the other jobs are ignored
+	p.breakerRecord(newIt("8"), true)
+	assert.Equal(t, BreakerHalfOpen, p.Breakers()[0].State)
+
+	p.breakerRecord(probe, true)
+	assert.Equal(t, BreakerClosed, p.Breakers()[0].State)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&c.resumed))
+	assert.True(t, p.breakerAdmit(opts.breaker, newIt("9")))
+}
+
+func TestBreakerManualPause(t *testing.T) {
+	p := testInspectPlugin()
+	c := &testConsumer{}
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", c)
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"circuit_breaker": map[string]interface{}{
+			"min_jobs": 1,
+			"cooldown": "200ms",
+		},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+
+	p.breakerRecord(&item{ctx: &jobContext{Pipeline: "test"}, p: p}, false)
+	assert.Equal(t, BreakerOpen, p.Breakers()[0].State)
+
+	// manual pause closes the breaker, the pipeline is not resumed after the cooldown
+	p.Pause("test")
+	time.Sleep(time.Millisecond * 400)
+	assert.Equal(t, BreakerClosed, p.Breakers()[0].State)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.resumed))
+}
+
+type testStateConsumer struct {
+	testConsumer
+	name string
+}
+
+func (c *testStateConsumer) State(context.Context) (*jobs.State, error) {
+	return &jobs.State{Pipeline: c.name, Driver: "memory", Ready: true}, nil
+}
+
+func TestBreakerOutcomes(t *testing.T) {
+	p := testInspectPlugin()
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", &testStateConsumer{name: "test"})
+	p.consumers.Delete("amqp")
+	p.consumers.Store("amqp", &testStateConsumer{name: "amqp"})
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":            "test",
+		"driver":          "memory",
+		"circuit_breaker": map[string]interface{}{"min_jobs": 10},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+
+	newIt := func(id string) *item {
+		return &item{Item: &testPQItem{id: id}, ack: testAcknowledger{}, ctx: &jobContext{ID: id, Pipeline: "test"}, p: p, start: time.Now()}
+	}
+
+	require.NoError(t, newIt("1").Ack())
+	require.NoError(t, newIt("2").Respond(nil, "responses"))
+	require.NoError(t, newIt("3").Nack())
+	require.NoError(t, newIt("4").Requeue(nil, 0))
+
+	states, err := p.PipelinesState(context.Background())
+	require.NoError(t, err)
+	require.Len(t, states, 2)
+
+	assert.Equal(t, "amqp", states[0].Pipeline)
+	assert.Nil(t, states[0].Breaker)
+
+	assert.Equal(t, "test", states[1].Pipeline)
+	assert.True(t, states[1].Ready)
+	require.NotNil(t, states[1].Breaker)
+	assert.Equal(t, BreakerClosed, states[1].Breaker.State)
+	assert.Equal(t, int64(4), states[1].Breaker.Jobs)
+	assert.Equal(t, 0.5, states[1].Breaker.ErrorRatio)
+}
@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+// Canceler is implemented by the drivers (consumers) able to cancel the delayed jobs before their delay expires
+type Canceler interface {
+	// Cancel deletes the delayed job by its ID, nil job - no such job or the job was already taken
+	Cancel(ctx context.Context, id string) (*jobs.Job, error)
+}
+
+// CancelRequest is the Cancel RPC request
+type CancelRequest struct {
+	// Pipeline of the job, optional, all pipelines are checked if empty
+	Pipeline string `json:"pipeline"`
+	ID       string `json:"id"`
+}
+
+// Cancel cancels the delayed job, false - no such job. The job is looked up in all pipelines if the pipeline is empty.
+func (p *Plugin) Cancel(pp, id string) (bool, error) {
+	const op = errors.Op("jobs_plugin_cancel")
+
+	cancelers := make(map[string]Canceler)
+	if pp != "" {
+		pipe, ok := p.pipelines.Load(pp)
+		if !ok {
+			return false, errors.E(op, errors.Errorf("no such pipeline, requested: %s", pp))
+		}
+
+		c, ok := p.consumers.Load(pp)
+		if !ok {
+			return false, errors.E(op, errors.Errorf("consumer not registered for the requested driver: %s", pipe.(*pipeline.Pipeline).Driver()))
+		}
+
+		cn, ok := c.(Canceler)
+		if !ok {
+			return false, errors.E(op, errors.Unsupported, errors.Errorf("delayed jobs cancellation is not supported by the driver: %s", pipe.(*pipeline.Pipeline).Driver()))
+		}
+
+		cancelers[pp] = cn
+	} else {
+		p.consumers.Range(func(key, value interface{}) bool {
+			if cn, ok := value.(Canceler); ok {
+				cancelers[key.(string)] = cn
+			}
+			return true
+		})
+
+		if len(cancelers) == 0 {
+			return false, errors.E(op, errors.Unsupported, errors.Str("no pipelines support the delayed jobs cancellation"))
+		}
+	}
+
+	var lastErr error
+	for name, cn := range cancelers {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+		j, err := cn.Cancel(ctx, id)
+		cancel()
+		if err != nil {
+			p.log.Error("failed to cancel the delayed job", zap.String("ID", id), zap.String("pipeline", name), zap.Error(err))
+			lastErr = err
+			continue
+		}
+
+		if j == nil {
+			continue
+		}
+
+		p.removed(name, j)
+		p.log.Info("delayed job was canceled", zap.String("ID", id), zap.String("pipeline", name))
+		return true, nil
+	}
+
+	if lastErr != nil {
+		return false, errors.E(op, lastErr)
+	}
+
+	return false, nil
+}
@@ -0,0 +1,59 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testCanceler struct {
+	testConsumer
+	delayed map[string]*jobs.Job
+}
+
+func (c *testCanceler) Cancel(_ context.Context, id string) (*jobs.Job, error) {
+	j, ok := c.delayed[id]
+	if !ok {
+		return nil, nil
+	}
+
+	delete(c.delayed, id)
+	return j, nil
+}
+
+func TestCancel(t *testing.T) {
+	p := &Plugin{cfg: &Config{Timeout: 10}, log: zap.NewNop()}
+
+	p.pipelines.Store("test-1", &pipeline.Pipeline{"name": "test-1", "driver": "memory"})
+	p.pipelines.Store("test-2", &pipeline.Pipeline{"name": "test-2", "driver": "memory"})
+	p.pipelines.Store("amqp", &pipeline.Pipeline{"name": "amqp", "driver": "amqp"})
+	p.consumers.Store("test-1", &testCanceler{delayed: map[string]*jobs.Job{}})
+	p.consumers.Store("test-2", &testCanceler{delayed: map[string]*jobs.Job{"1": testJob("1", "a")}})
+	p.consumers.Store("amqp", &testConsumer{})
+
+	canceled, err := p.Cancel("test-1", "1")
+	require.NoError(t, err)
+	assert.False(t, canceled)
+
+	// all pipelines
+	canceled, err = p.Cancel("", "1")
+	require.NoError(t, err)
+	assert.True(t, canceled)
+
+	canceled, err = p.Cancel("", "1")
+	require.NoError(t, err)
+	assert.False(t, canceled)
+
+	_, err = p.Cancel("amqp", "1")
+	require.Error(t, err)
+	assert.True(t, errors.Is(errors.Unsupported, err))
+
+	_, err = p.Cancel("unknown", "1")
+	require.Error(t, err)
+}
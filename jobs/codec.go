@@ -0,0 +1,332 @@
+package jobs
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/zstd"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+)
+
+const (
+	// CodecHeader contains the codecs applied to the job's payload in the order of application,
+	// the encoded payload is base64 encoded
+	CodecHeader string = "rr_codec"
+
+	codecZstd   string = "zstd"
+	codecGzip   string = "gzip"
+	codecAESGCM string = "aes-gcm"
+)
+
+// codecOptions configures the compression and the encryption of the pipeline's jobs payloads
+type codecOptions struct {
+	// Compress algorithm: zstd or gzip, empty - no compression
+	Compress string `mapstructure:"compress"`
+	// Encrypt algorithm: aes-gcm, empty - no encryption
+	Encrypt string `mapstructure:"encrypt"`
+	// KeyFile contains the base64 encoded AES key (16, 24 or 32 bytes)
+	KeyFile string `mapstructure:"key_file"`
+
+	// AES-GCM cipher, nil if the encryption is not configured
+	aead cipher.AEAD
+}
+
+func (c *codecOptions) InitDefaults() error {
+	switch c.Compress {
+	case "", codecZstd, codecGzip:
+	default:
+		return errors.Errorf("unknown codec compress option: %s, should be one of: zstd, gzip", c.Compress)
+	}
+
+	switch c.Encrypt {
+	case "":
+		return nil
+	case codecAESGCM:
+	default:
+		return errors.Errorf("unknown codec encrypt option: %s, should be: aes-gcm", c.Encrypt)
+	}
+
+	if c.KeyFile == "" {
+		return errors.Str("codec key_file should be set for the encryption")
+	}
+
+	data, err := os.ReadFile(c.KeyFile)
+	if err != nil {
+		return errors.Errorf("failed to read the codec key_file: %v", err)
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return errors.Errorf("codec key should be base64 encoded: %v", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return errors.Errorf("codec key error: %v", err)
+	}
+
+	c.aead, err = cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// codecs returns the codecs in the order of application
+func (c *codecOptions) codecs() []string {
+	res := make([]string, 0, 2)
+	if c.Compress != "" {
+		res = append(res, c.Compress)
+	}
+
+	if c.Encrypt != "" {
+		res = append(res, c.Encrypt)
+	}
+
+	return res
+}
+
+// encode applies the codecs to the payload
+func (c *codecOptions) encode(payload []byte) (string, error) {
+	var err error
+	for _, codec := range c.codecs() {
+		switch codec {
+		case codecZstd:
+			payload, err = zstdCompress(payload)
+		case codecGzip:
+			payload, err = gzipCompress(payload)
+		case codecAESGCM:
+			payload, err = c.seal(payload)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
+
+// decode reverses the codecs listed in the CodecHeader
+func (c *codecOptions) decode(payload []byte, codecs []string) ([]byte, error) {
+	data := make([]byte, base64.StdEncoding.DecodedLen(len(payload)))
+	n, err := base64.StdEncoding.Decode(data, payload)
+	if err != nil {
+		return nil, err
+	}
+	data = data[:n]
+
+	for i := len(codecs) - 1; i >= 0; i-- {
+		switch codecs[i] {
+		case codecZstd:
+			data, err = zstdDecompress(data)
+		case codecGzip:
+			data, err = gzipDecompress(data)
+		case codecAESGCM:
+			data, err = c.open(data)
+		default:
+			return nil, errors.Errorf("unknown payload codec: %s", codecs[i])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return data, nil
+}
+
+// seal encrypts the data, the random nonce is prepended to the ciphertext
+func (c *codecOptions) seal(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *codecOptions) open(data []byte) ([]byte, error) {
+	if c.aead == nil {
+		return nil, errors.Str("payload is encrypted, but the pipeline has no encryption key")
+	}
+
+	if len(data) < c.aead.NonceSize() {
+		return nil, errors.Str("encrypted payload is too short")
+	}
+
+	return c.aead.Open(nil, data[:c.aead.NonceSize()], data[c.aead.NonceSize():], nil)
+}
+
+// zstd encoder and decoder are safe for the concurrent EncodeAll/DecodeAll calls
+var (
+	zstdOnce sync.Once
+	zstdEnc  *zstd.Encoder
+	zstdDec  *zstd.Decoder
+	zstdErr  error
+)
+
+func initZstd() error {
+	zstdOnce.Do(func() {
+		zstdEnc, zstdErr = zstd.NewWriter(nil)
+		if zstdErr != nil {
+			return
+		}
+
+		zstdDec, zstdErr = zstd.NewReader(nil)
+	})
+
+	return zstdErr
+}
+
+func zstdCompress(data []byte) ([]byte, error) {
+	err := initZstd()
+	if err != nil {
+		return nil, err
+	}
+
+	return zstdEnc.EncodeAll(data, make([]byte, 0, len(data))), nil
+}
+
+func zstdDecompress(data []byte) ([]byte, error) {
+	err := initZstd()
+	if err != nil {
+		return nil, err
+	}
+
+	return zstdDec.DecodeAll(data, nil)
+}
+
+func gzipCompress(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	_, err := w.Write(data)
+	if err != nil {
+		return nil, err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func gzipDecompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	return io.ReadAll(r)
+}
+
+// validateCodec rejects the pushed job with the CodecHeader, the payload is encoded by the pipeline's codec only
+func validateCodec(j *jobs.Job) error {
+	if _, ok := j.Headers[CodecHeader]; ok {
+		return errors.Errorf("%s header is reserved for the pipeline's codec", CodecHeader)
+	}
+
+	return nil
+}
+
+// codec returns the pipeline's codec, decompression needs no options
+func (p *Plugin) codec(pipeline string) *codecOptions {
+	if opts := p.options(pipeline); opts != nil && opts.Codec != nil {
+		return opts.Codec
+	}
+
+	return &codecOptions{}
+}
+
+// encodePayload applies the pipeline's codec to the job's payload. Jobs with the CodecHeader are already encoded
+// (e.g. the dead-letter job which payload could not be decoded) and are pushed as is.
+func (p *Plugin) encodePayload(j *jobs.Job) error {
+	opts := p.options(j.Options.Pipeline)
+	if opts == nil || opts.Codec == nil {
+		return nil
+	}
+
+	if _, ok := j.Headers[CodecHeader]; ok {
+		return nil
+	}
+
+	codecs := opts.Codec.codecs()
+	if len(codecs) == 0 {
+		return nil
+	}
+
+	payload, err := opts.Codec.encode(utils.AsBytes(j.Payload))
+	if err != nil {
+		return err
+	}
+
+	if j.Headers == nil {
+		j.Headers = make(map[string][]string, 1)
+	}
+
+	j.Payload = payload
+	j.Headers[CodecHeader] = codecs
+	return nil
+}
+
+// decodePayload reverses the codecs of the job taken from the pipeline (e.g. moved to another pipeline),
+// the job is encoded with the codec of the pipeline it is pushed to
+func (p *Plugin) decodePayload(pipeline string, j *jobs.Job) error {
+	codecs, ok := j.Headers[CodecHeader]
+	if !ok {
+		return nil
+	}
+
+	if len(codecs) > 0 {
+		payload, err := p.codec(pipeline).decode(utils.AsBytes(j.Payload), codecs)
+		if err != nil {
+			return err
+		}
+
+		j.Payload = string(payload)
+	}
+
+	delete(j.Headers, CodecHeader)
+	return nil
+}
+
+// decodeBody reverses the codecs of the job's payload, the decoded payload is sent to the worker
+func (i *item) decodeBody() error {
+	codecs, ok := i.ctx.Headers[CodecHeader]
+	if !ok || len(codecs) == 0 {
+		return nil
+	}
+
+	body, err := i.p.codec(i.ctx.Pipeline).decode(i.Item.Body(), codecs)
+	if err != nil {
+		return err
+	}
+
+	i.body = body
+	i.decoded = true
+	return nil
+}
+
+// Body returns the decoded payload of the job
+func (i *item) Body() []byte {
+	if i.decoded {
+		return i.body
+	}
+
+	return i.Item.Body()
+}
@@ -0,0 +1,228 @@
+package jobs
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKeyFile(t *testing.T, size int) string {
+	file := filepath.Join(t.TempDir(), "key")
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", size)))
+	require.NoError(t, os.WriteFile(file, []byte(key+"\n"), 0600))
+	return file
+}
+
+func TestCodecOptions(t *testing.T) {
+	parse := func(codec map[string]interface{}) error {
+		_, err := parseOptions(&pipeline.Pipeline{
+			"name":   "test",
+			"driver": "memory",
+			"codec":  codec,
+		})
+		return err
+	}
+
+	assert.NoError(t, parse(map[string]interface{}{"compress": "zstd"}))
+	assert.NoError(t, parse(map[string]interface{}{"compress": "gzip", "encrypt": "aes-gcm", "key_file": testKeyFile(t, 32)}))
+	assert.Error(t, parse(map[string]interface{}{"compress": "lz4"}))
+	assert.Error(t, parse(map[string]interface{}{"encrypt": "aes-cbc", "key_file": testKeyFile(t, 32)}))
+	assert.Error(t, parse(map[string]interface{}{"encrypt": "aes-gcm"}))
+	// wrong key size
+	assert.Error(t, parse(map[string]interface{}{"encrypt": "aes-gcm", "key_file": testKeyFile(t, 10)}))
+}
+
+func TestCodec(t *testing.T) {
+	keyFile := testKeyFile(t, 32)
+	payload := strings.Repeat(`{"email": "user@example.com"}`, 100)
+
+	tests := []struct {
+		name  string
+		codec map[string]interface{}
+	}{
+		{"zstd", map[string]interface{}{"compress": "zstd"}},
+		{"gzip", map[string]interface{}{"compress": "gzip"}},
+		{"aes-gcm", map[string]interface{}{"encrypt": "aes-gcm", "key_file": keyFile}},
+		{"zstd+aes-gcm", map[string]interface{}{"compress": "zstd", "encrypt": "aes-gcm", "key_file": keyFile}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := testInspectPlugin()
+			c := &testInspector{}
+			p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+			p.consumers.Store("test", c)
+
+			opts, err := parseOptions(&pipeline.Pipeline{
+				"name":   "test",
+				"driver": "memory",
+				"codec":  tt.codec,
+			})
+			require.NoError(t, err)
+			p.pipelineOpts.Store("test", opts)
+
+			require.NoError(t, p.Push(&jobs.Job{Job: "test", Ident: "1", Payload: payload, Options: &jobs.Options{Pipeline: "test"}}))
+			require.Len(t, c.jobs, 1)
+
+			j := c.jobs[0]
+			assert.Equal(t, opts.Codec.codecs(), j.Headers[CodecHeader])
+			assert.NotContains(t, j.Payload, "user@example.com")
+			if opts.Codec.Compress != "" {
+				assert.Less(t, len(j.Payload), len(payload))
+			}
+
+			it := &item{
+				Item: &testPQItem{id: "1", body: []byte(j.Payload)},
+				ctx:  &jobContext{ID: "1", Pipeline: "test", Headers: j.Headers},
+				p:    p,
+			}
+			require.NoError(t, it.decodeBody())
+			assert.Equal(t, payload, string(it.Body()))
+
+			// the codec header can't be pushed
+			assert.Error(t, p.Push(j))
+			assert.Error(t, p.PushBatch([]*jobs.Job{j}))
+			require.Len(t, c.jobs, 1)
+
+			// already encoded job is pushed as is internally
+			require.NoError(t, p.push(j))
+			assert.Equal(t, j.Payload, c.jobs[1].Payload)
+		})
+	}
+}
+
+func TestCodecPeek(t *testing.T) {
+	p := testInspectPlugin()
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"codec":  map[string]interface{}{"compress": "gzip"},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+
+	encoded, err := opts.Codec.encode([]byte(`{"email": "user@example.com"}`))
+	require.NoError(t, err)
+
+	c := &testInspector{jobs: []*jobs.Job{
+		{
+			Job:     "test",
+			Ident:   "1",
+			Payload: encoded,
+			Headers: map[string][]string{CodecHeader: opts.Codec.codecs(), PushedAt: {"1"}, "foo": {"bar"}},
+			Options: &jobs.Options{Pipeline: "test"},
+		},
+		{
+			Job:     "test",
+			Ident:   "2",
+			Payload: "broken",
+			Headers: map[string][]string{CodecHeader: opts.Codec.codecs()},
+			Options: &jobs.Options{Pipeline: "test"},
+		},
+	}}
+	p.consumers.Store("test", c)
+
+	jbs, err := p.Peek("test", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, jbs, 2)
+
+	// the job as the worker receives it
+	assert.Equal(t, `{"email": "user@example.com"}`, jbs[0].Payload)
+	assert.Equal(t, map[string][]string{"foo": {"bar"}}, jbs[0].Headers)
+	// the waiting job is not modified
+	assert.Equal(t, encoded, c.jobs[0].Payload)
+	assert.Contains(t, c.jobs[0].Headers, CodecHeader)
+	assert.Contains(t, c.jobs[0].Headers, PushedAt)
+
+	// the payload which can't be decoded is returned as is
+	assert.Equal(t, "broken", jbs[1].Payload)
+	assert.Equal(t, opts.Codec.codecs(), jbs[1].Headers[CodecHeader])
+}
+
+func TestCodecDecodeError(t *testing.T) {
+	p := testInspectPlugin()
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"codec":  map[string]interface{}{"encrypt": "aes-gcm", "key_file": testKeyFile(t, 16)},
+	})
+	require.NoError(t, err)
+
+	encoded, err := opts.Codec.encode([]byte("secret"))
+	require.NoError(t, err)
+
+	// no key in the consuming pipeline
+	it := &item{
+		Item: &testPQItem{id: "1", body: []byte(encoded)},
+		ctx:  &jobContext{ID: "1", Pipeline: "test", Headers: map[string][]string{CodecHeader: {codecAESGCM}}},
+		p:    p,
+	}
+	assert.Error(t, it.decodeBody())
+	assert.Equal(t, encoded, string(it.Body()))
+
+	// wrong key
+	other, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"codec":  map[string]interface{}{"encrypt": "aes-gcm", "key_file": testKeyFile(t, 32)},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", other)
+	assert.Error(t, it.decodeBody())
+
+	p.pipelineOpts.Store("test", opts)
+	require.NoError(t, it.decodeBody())
+	assert.Equal(t, "secret", string(it.Body()))
+}
+
+func TestCodecMove(t *testing.T) {
+	p := testInspectPlugin()
+	from := &testInspector{}
+	to := &testInspector{}
+	p.consumers.Store("from", from)
+	p.consumers.Store("to", to)
+
+	fromOpts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "from",
+		"driver": "memory",
+		"codec":  map[string]interface{}{"compress": "zstd", "encrypt": "aes-gcm", "key_file": testKeyFile(t, 16)},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("from", fromOpts)
+
+	toOpts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "to",
+		"driver": "memory",
+		"codec":  map[string]interface{}{"encrypt": "aes-gcm", "key_file": testKeyFile(t, 32)},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("to", toOpts)
+
+	require.NoError(t, p.Push(&jobs.Job{Job: "test", Ident: "1", Payload: "secret", Options: &jobs.Options{Pipeline: "from"}}))
+	require.Len(t, from.jobs, 1)
+
+	moved, err := p.Move("from", "to", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), moved)
+	require.Len(t, to.jobs, 1)
+
+	j := to.jobs[0]
+	assert.Equal(t, []string{codecAESGCM}, j.Headers[CodecHeader])
+
+	// decoded with the destination pipeline's key
+	it := &item{
+		Item: &testPQItem{id: "1", body: []byte(j.Payload)},
+		ctx:  &jobContext{ID: "1", Pipeline: "to", Headers: j.Headers},
+		p:    p,
+	}
+	require.NoError(t, it.decodeBody())
+	assert.Equal(t, "secret", string(it.Body()))
+}
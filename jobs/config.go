@@ -26,14 +26,36 @@ type Config struct {
 	// Timeout in seconds is the per-push limit to put the job into queue
 	Timeout int `mapstructure:"timeout"`
 
+	// DrainTimeout in seconds is the grace period to finish the in-flight jobs on Stop/Destroy,
+	// jobs left in the queue are returned to the drivers. Default - 0, the drain is disabled.
+	DrainTimeout int `mapstructure:"drain_timeout"`
+
 	// Pool configures roadrunner workers pool.
 	Pool *poolImpl.Config `mapstructure:"Pool"`
 
+	// Pools configures named workers pools, which might be shared by a group of pipelines (pool option of the pipeline).
+	Pools map[string]*poolImpl.Config `mapstructure:"pools"`
+
 	// Pipelines defines mapping between PHP job pipeline and associated job broker.
 	Pipelines map[string]*pipeline.Pipeline `mapstructure:"pipelines"`
 
 	// Consuming specifies names of pipelines to be consumed on service start.
 	Consume []string `mapstructure:"consume"`
+
+	// Schedule contains the jobs pushed according to the cron expressions, keys are the schedule names.
+	Schedule map[string]*ScheduledJob `mapstructure:"schedule"`
+
+	// Declarations configures the storage of the pipelines declared via the Declare RPC, so they survive restarts.
+	Declarations *DeclarationsConfig `mapstructure:"declarations"`
+
+	// Events configures the job lifecycle events published via the broadcast plugin.
+	Events *EventsConfig `mapstructure:"events"`
+
+	// Workflow configures the storage of the jobs groups state, see the follow-up jobs of the Response message.
+	Workflow *WorkflowConfig `mapstructure:"workflow"`
+
+	// ScheduleLock configures the kv-backed leader lock, so that only one RR instance pushes the scheduled jobs.
+	ScheduleLock *ScheduleLock `mapstructure:"schedule_lock"`
 }
 
 func (c *Config) InitDefaults() {
@@ -59,4 +81,12 @@ func (c *Config) InitDefaults() {
 	}
 
 	c.Pool.InitDefaults()
+
+	for k := range c.Pools {
+		if c.Pools[k] == nil {
+			c.Pools[k] = &poolImpl.Config{}
+		}
+
+		c.Pools[k].InitDefaults()
+	}
 }
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spiral/errors"
+)
+
+// cronField describes the bounds of the cron expression field
+type cronField struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	cronSeconds = cronField{name: "seconds", min: 0, max: 59}                       //nolint:gochecknoglobals
+	cronMinutes = cronField{name: "minutes", min: 0, max: 59}                       //nolint:gochecknoglobals
+	cronHours   = cronField{name: "hours", min: 0, max: 23}                         //nolint:gochecknoglobals
+	cronDom     = cronField{name: "day of month", min: 1, max: 31}                  //nolint:gochecknoglobals
+	cronMonths  = cronField{name: "month", min: 1, max: 12, names: map[string]uint{ //nolint:gochecknoglobals
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	cronDow = cronField{name: "day of week", min: 0, max: 6, names: map[string]uint{ //nolint:gochecknoglobals
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// cron descriptors
+var cronDescriptors = map[string]string{ //nolint:gochecknoglobals
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// cronSchedule is the parsed cron expression, every field is a bit set of the allowed values
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// every is used for the @every <duration> expressions
+	every time.Duration
+}
+
+// parseCron parses the cron expression with the seconds resolution:
+// `sec min hour dom month dow`. The seconds field might be omitted (standard 5 fields expression).
+// Descriptors (@hourly, @daily, etc.) and `@every <duration>` are supported as well.
+func parseCron(expr string) (*cronSchedule, error) {
+	const op = errors.Op("jobs_parse_cron")
+	expr = strings.TrimSpace(expr)
+
+	if strings.HasPrefix(expr, "@every ") {
+		d, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		if d < time.Second {
+			return nil, errors.E(op, errors.Errorf("@every duration should be at least 1s, provided: %s", d))
+		}
+
+		return &cronSchedule{every: d}, nil
+	}
+
+	if d, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = d
+	}
+
+	fields := strings.Fields(expr)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.E(op, errors.Errorf("cron expression should contain 5 or 6 fields, provided: %s", expr))
+	}
+
+	var err error
+	s := &cronSchedule{}
+
+	bounds := []cronField{cronSeconds, cronMinutes, cronHours, cronDom, cronMonths, cronDow}
+	dst := []*uint64{&s.second, &s.minute, &s.hour, &s.dom, &s.month, &s.dow}
+
+	for i := 0; i < len(fields); i++ {
+		*dst[i], err = parseCronField(fields[i], bounds[i])
+		if err != nil {
+			return nil, errors.E(op, errors.Errorf("expression: %s, error: %v", expr, err))
+		}
+	}
+
+	return s, nil
+}
+
+// parseCronField parses the comma separated list of the ranges: `*`, `?`, `a`, `a-b`, `*/n`, `a-b/n`, `a/n`
+func parseCronField(field string, f cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		var err error
+		var start, end, step uint = f.min, f.max, 1
+
+		rng := part
+		if idx := strings.IndexByte(part, '/'); idx != -1 {
+			rng = part[:idx]
+			step, err = parseCronValue(part[idx+1:], cronField{name: f.name, min: 1, max: f.max})
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		switch {
+		case rng == "*" || rng == "?":
+		case strings.IndexByte(rng, '-') != -1:
+			idx := strings.IndexByte(rng, '-')
+			start, err = parseCronValue(rng[:idx], f)
+			if err != nil {
+				return 0, err
+			}
+
+			end, err = parseCronValue(rng[idx+1:], f)
+			if err != nil {
+				return 0, err
+			}
+
+			if start > end {
+				return 0, errors.Errorf("%s: range start is greater than the range end: %s", f.name, rng)
+			}
+		default:
+			start, err = parseCronValue(rng, f)
+			if err != nil {
+				return 0, err
+			}
+
+			// `a/n` means from a to the max with the step n
+			if step == 1 {
+				end = start
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func parseCronValue(val string, f cronField) (uint, error) {
+	if v, ok := f.names[strings.ToLower(val)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.ParseUint(val, 10, 8)
+	if err != nil {
+		return 0, errors.Errorf("%s: failed to parse value: %s", f.name, val)
+	}
+
+	// 7 is an alias for sunday
+	if f.name == cronDow.name && v == 7 {
+		v = 0
+	}
+
+	if uint(v) < f.min || uint(v) > f.max {
+		return 0, errors.Errorf("%s: value %d is out of range [%d, %d]", f.name, v, f.min, f.max)
+	}
+
+	return uint(v), nil
+}
+
+// next returns the next activation time after the provided time or zero time if there is no such time
+func (s *cronSchedule) next(t time.Time) time.Time {
+	if s.every > 0 {
+		return t.Truncate(time.Second).Add(s.every)
+	}
+
+	t = t.Truncate(time.Second).Add(time.Second)
+	// protection from the impossible expressions like 30 of February
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, 1, 0)
+			continue
+		}
+
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+			continue
+		}
+
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()).Add(time.Hour)
+			continue
+		}
+
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location()).Add(time.Minute)
+			continue
+		}
+
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows the cron semantic: when both day of month and day of week are restricted,
+// the day matches if any of them matches
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	domAll := s.dom == cronAll(cronDom)
+	dowAll := s.dow == cronAll(cronDow)
+
+	if domAll || dowAll {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+func cronAll(f cronField) uint64 {
+	var bits uint64
+	for v := f.min; v <= f.max; v++ {
+		bits |= 1 << v
+	}
+
+	return bits
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronNext(t *testing.T) {
+	start := time.Date(2022, time.January, 1, 10, 15, 30, 500, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * * *", time.Date(2022, time.January, 1, 10, 15, 31, 0, time.UTC)},
+		{"*/10 * * * * *", time.Date(2022, time.January, 1, 10, 15, 40, 0, time.UTC)},
+		{"0 * * * * *", time.Date(2022, time.January, 1, 10, 16, 0, 0, time.UTC)},
+		{"*/5 * * * *", time.Date(2022, time.January, 1, 10, 20, 0, 0, time.UTC)},
+		{"0 0 9-17 * * mon-fri", time.Date(2022, time.January, 3, 9, 0, 0, 0, time.UTC)},
+		{"0 30 10 1,15 * *", time.Date(2022, time.January, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 0 10 1,15 * *", time.Date(2022, time.January, 15, 10, 0, 0, 0, time.UTC)},
+		{"0 0 0 29 feb *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2022, time.January, 1, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"@every 90s", time.Date(2022, time.January, 1, 10, 17, 0, 0, time.UTC)},
+		// day of month OR day of week
+		{"0 0 0 10 * sun", time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 0 * * 7", time.Date(2022, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"15/20 * * * * *", time.Date(2022, time.January, 1, 10, 15, 35, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		s, err := parseCron(tt.expr)
+		require.NoError(t, err, tt.expr)
+		assert.Equal(t, tt.next, s.next(start), tt.expr)
+	}
+}
+
+func TestCronImpossible(t *testing.T) {
+	s, err := parseCron("0 0 0 30 feb *")
+	require.NoError(t, err)
+	assert.True(t, s.next(time.Now()).IsZero())
+}
+
+func TestCronErrors(t *testing.T) {
+	exprs := []string{
+		"",
+		"* * *",
+		"* * * * * * *",
+		"60 * * * * *",
+		"* * 24 * * *",
+		"* * * 0 * *",
+		"* * * * 13 *",
+		"* * * * * 8",
+		"*/0 * * * * *",
+		"10-5 * * * * *",
+		"a * * * * *",
+		"@every 100ms",
+		"@every foo",
+	}
+
+	for _, expr := range exprs {
+		_, err := parseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
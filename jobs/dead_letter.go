@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"go.uber.org/zap"
+)
+
+const (
+	// headers added to the dead-lettered job
+	DeadLetterReason   string = "rr_dead_letter_reason"
+	DeadLetterAttempts string = "rr_dead_letter_attempts"
+	DeadLetterTime     string = "rr_dead_letter_time"
+	DeadLetterPipeline string = "rr_dead_letter_pipeline"
+)
+
+// deadLetter pushes a copy of the failed job with the failure details to the dead-letter pipeline.
+// The copy is not validated, the dead-letter pipeline keeps the jobs as they are. The original job
+// is acknowledged, or negatively acknowledged if the copy could not be pushed.
+func (p *Plugin) deadLetter(it *item, reason string) error {
+	it.reason = reason
+	it.status = StatusFailed
+
+	dl := p.deadLetterPipeline(it.ctx.Pipeline)
+	if dl == "" {
+		// no dead-letter pipeline, silently ACK
+		return it.Ack()
+	}
+
+	headers := make(map[string][]string, len(it.ctx.Headers)+4)
+	for k, v := range it.ctx.Headers {
+		headers[k] = v
+	}
+
+	// attempts are counted from scratch in the dead-letter pipeline
+	delete(headers, attemptHeader)
+	// unique key is released with the original job
+	delete(headers, UniqueKey)
+	delete(headers, UniqueTTL)
+	// the group's job is finished with the original job
+	delete(headers, GroupHeader)
+	// the decoded payload is encoded with the dead-letter pipeline's codec
+	if it.decoded {
+		delete(headers, CodecHeader)
+	}
+
+	headers[DeadLetterReason] = []string{reason}
+	headers[DeadLetterAttempts] = []string{strconv.Itoa(it.attempt())}
+	headers[DeadLetterTime] = []string{time.Now().UTC().Format(time.RFC3339)}
+	headers[DeadLetterPipeline] = []string{it.ctx.Pipeline}
+
+	err := p.send(&jobs.Job{
+		Job:     it.ctx.Job,
+		Ident:   it.ID(),
+		Payload: string(it.Body()),
+		Headers: headers,
+		Options: &jobs.Options{
+			Priority: it.Priority(),
+			Pipeline: dl,
+		},
+	})
+	if err != nil {
+		p.log.Error("failed to push the job to the dead-letter pipeline, job will be negatively acknowledged", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.String("dead_letter", dl), zap.Error(err))
+		return it.Nack()
+	}
+
+	it.status = StatusDead
+	p.log.Warn("job was moved to the dead-letter pipeline", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.String("dead_letter", dl), zap.String("reason", reason))
+
+	return it.Ack()
+}
+
+// deadLetterPipeline returns the name of the dead-letter pipeline for the provided pipeline or an empty string
+func (p *Plugin) deadLetterPipeline(name string) string {
+	opts := p.options(name)
+	if opts == nil {
+		return ""
+	}
+
+	// do not route the job into the same pipeline
+	if opts.DeadLetter == name {
+		p.log.Warn("dead-letter pipeline should not be the same as the source pipeline", zap.String("pipeline", name))
+		return ""
+	}
+
+	return opts.DeadLetter
+}
@@ -0,0 +1,377 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/spiral/errors"
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// default key of the declarations in the kv storage
+	declarationsKey string = "rr_jobs_declarations"
+	// boltdb bucket of the declarations
+	declarationsBucket string = "declarations"
+)
+
+// DeclarationsConfig configures the storage of the pipelines declared via the Declare RPC,
+// one of the Storage or File should be set
+type DeclarationsConfig struct {
+	// Storage is the name of the kv storage (key in the kv section)
+	Storage string `mapstructure:"storage"`
+	// Key in the kv storage, default - rr_jobs_declarations
+	Key string `mapstructure:"key"`
+	// File is the path to the boltdb file
+	File string `mapstructure:"file"`
+	// Permissions of the boltdb file, default - 0755
+	Permissions int `mapstructure:"permissions"`
+}
+
+func (c *DeclarationsConfig) InitDefaults() error {
+	if (c.Storage == "") == (c.File == "") {
+		return errors.Str("declarations should have one of the storage or file options")
+	}
+
+	if c.Key == "" {
+		c.Key = declarationsKey
+	}
+
+	if c.Permissions == 0 {
+		c.Permissions = 0755
+	}
+
+	return nil
+}
+
+// declaration is the stored declared pipeline
+type declaration struct {
+	Pipeline map[string]interface{} `json:"pipeline"`
+	// Consume - pipeline was resumed after the declaration
+	Consume bool `json:"consume"`
+}
+
+// declarationsStore stores the declared pipelines, keys are the pipelines names
+type declarationsStore interface {
+	save(name string, d *declaration) error
+	delete(name string) error
+	list() (map[string]*declaration, error)
+	close() error
+}
+
+// kvDeclarations keeps all declarations in the single key of the kv storage
+type kvDeclarations struct {
+	// protects read-modify-write of the key
+	mu  sync.Mutex
+	p   *Plugin
+	cfg *DeclarationsConfig
+}
+
+func (d *kvDeclarations) list() (map[string]*declaration, error) {
+	st, err := d.p.storage(d.cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := st.MGet(d.cfg.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]*declaration)
+	if _, ok := data[d.cfg.Key]; !ok {
+		return res, nil
+	}
+
+	err = json.Unmarshal(data[d.cfg.Key], &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (d *kvDeclarations) update(fn func(m map[string]*declaration)) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	m, err := d.list()
+	if err != nil {
+		return err
+	}
+
+	fn(m)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	st, err := d.p.storage(d.cfg.Storage)
+	if err != nil {
+		return err
+	}
+
+	return st.Set(&kvv1.Item{
+		Key:   d.cfg.Key,
+		Value: data,
+	})
+}
+
+func (d *kvDeclarations) save(name string, decl *declaration) error {
+	return d.update(func(m map[string]*declaration) {
+		m[name] = decl
+	})
+}
+
+func (d *kvDeclarations) delete(name string) error {
+	return d.update(func(m map[string]*declaration) {
+		delete(m, name)
+	})
+}
+
+func (d *kvDeclarations) close() error {
+	return nil
+}
+
+// boltDeclarations keeps the declarations in the boltdb file, one key per pipeline
+type boltDeclarations struct {
+	db *bolt.DB
+}
+
+func newBoltDeclarations(cfg *DeclarationsConfig) (*boltDeclarations, error) {
+	db, err := bolt.Open(cfg.File, os.FileMode(cfg.Permissions), &bolt.Options{
+		Timeout: time.Second * 20,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, errB := tx.CreateBucketIfNotExists([]byte(declarationsBucket))
+		return errB
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &boltDeclarations{db: db}, nil
+}
+
+func (d *boltDeclarations) save(name string, decl *declaration) error {
+	data, err := json.Marshal(decl)
+	if err != nil {
+		return err
+	}
+
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(declarationsBucket)).Put([]byte(name), data)
+	})
+}
+
+func (d *boltDeclarations) delete(name string) error {
+	return d.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(declarationsBucket)).Delete([]byte(name))
+	})
+}
+
+func (d *boltDeclarations) list() (map[string]*declaration, error) {
+	res := make(map[string]*declaration)
+
+	err := d.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(declarationsBucket)).ForEach(func(k, v []byte) error {
+			decl := &declaration{}
+			errU := json.Unmarshal(v, decl)
+			if errU != nil {
+				return errors.Errorf("pipeline: %s, error: %v", string(k), errU)
+			}
+
+			res[string(k)] = decl
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (d *boltDeclarations) close() error {
+	return d.db.Close()
+}
+
+// initDeclarations creates the declarations store, if configured
+func (p *Plugin) initDeclarations() error {
+	if p.cfg.Declarations == nil {
+		return nil
+	}
+
+	err := p.cfg.Declarations.InitDefaults()
+	if err != nil {
+		return err
+	}
+
+	if p.cfg.Declarations.Storage != "" {
+		p.declarations = &kvDeclarations{p: p, cfg: p.cfg.Declarations}
+		return nil
+	}
+
+	p.declarations, err = newBoltDeclarations(p.cfg.Declarations)
+	return err
+}
+
+// saveDeclaration records the declared pipeline, consume - the pipeline should be consumed after the restart
+func (p *Plugin) saveDeclaration(pipe *pipeline.Pipeline, consume bool) {
+	if p.declarations == nil {
+		return
+	}
+
+	err := p.declarations.save(pipe.Name(), &declaration{
+		Pipeline: *pipe,
+		Consume:  consume,
+	})
+	if err != nil {
+		p.log.Error("failed to save the pipeline declaration", zap.String("pipeline", pipe.Name()), zap.Error(err))
+	}
+}
+
+// consumeDeclaration updates the consume flag of the declared pipeline
+func (p *Plugin) consumeDeclaration(name string, consume bool) {
+	if p.declarations == nil {
+		return
+	}
+
+	if _, ok := p.declared.Load(name); !ok {
+		return
+	}
+
+	pipe, ok := p.pipelines.Load(name)
+	if !ok {
+		return
+	}
+
+	p.saveDeclaration(pipe.(*pipeline.Pipeline), consume)
+}
+
+// deleteDeclaration removes the declaration of the destroyed pipeline
+func (p *Plugin) deleteDeclaration(name string) {
+	if p.declarations == nil {
+		return
+	}
+
+	if _, ok := p.declared.LoadAndDelete(name); !ok {
+		return
+	}
+
+	err := p.declarations.delete(name)
+	if err != nil {
+		p.log.Error("failed to delete the pipeline declaration", zap.String("pipeline", name), zap.Error(err))
+	}
+}
+
+// replayDeclarations declares the stored pipelines, pipelines from the configuration have priority
+func (p *Plugin) replayDeclarations() error {
+	const op = errors.Op("jobs_plugin_replay_declarations")
+	if p.declarations == nil {
+		return nil
+	}
+
+	decls, err := p.declarations.list()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	for name, decl := range decls {
+		if _, ok := p.pipelines.Load(name); ok {
+			p.log.Warn("declared pipeline is defined in the configuration, declaration skipped", zap.String("pipeline", name))
+			continue
+		}
+
+		pipe := pipeline.Pipeline(decl.Pipeline)
+		err = p.declare(&pipe)
+		if err != nil {
+			// do not block other pipelines
+			p.log.Error("failed to declare the stored pipeline", zap.String("pipeline", name), zap.Error(err))
+			continue
+		}
+
+		p.declared.Store(name, struct{}{})
+
+		if decl.Consume {
+			p.Resume(name)
+		}
+
+		p.log.Debug("stored pipeline was declared", zap.String("pipeline", name), zap.String("driver", pipe.Driver()), zap.Bool("consume", decl.Consume))
+	}
+
+	return nil
+}
+
+// Export returns the effective set of the pipelines (configuration and declared) as the jobs configuration YAML
+func (p *Plugin) Export() ([]byte, error) {
+	const op = errors.Op("jobs_plugin_export")
+
+	pipes := make(map[string]map[string]interface{})
+	p.pipelines.Range(func(key, value interface{}) bool {
+		pipe := *value.(*pipeline.Pipeline)
+		m := make(map[string]interface{}, len(pipe))
+		for k, v := range pipe {
+			// name is the key of the pipeline
+			if k == pipelineName {
+				continue
+			}
+			m[k] = v
+		}
+
+		pipes[key.(string)] = m
+		return true
+	})
+
+	// consumed pipelines
+	consume := make([]string, 0, 2)
+	var err error
+	p.consumers.Range(func(key, value interface{}) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+		defer cancel()
+
+		var st *jobs.State
+		st, err = value.(jobs.Consumer).State(ctx)
+		if err != nil {
+			return false
+		}
+
+		if st.Ready {
+			consume = append(consume, key.(string))
+		}
+
+		return true
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	sort.Strings(consume)
+
+	data, err := yaml.Marshal(map[string]interface{}{
+		PluginName: map[string]interface{}{
+			pipelines: pipes,
+			"consume": consume,
+		},
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+func testDeclarations(t *testing.T, st declarationsStore) {
+	pipe := &pipeline.Pipeline{"name": "tenant-1", "driver": "memory", "priority": "10"}
+
+	require.NoError(t, st.save("tenant-1", &declaration{Pipeline: *pipe}))
+	require.NoError(t, st.save("tenant-2", &declaration{Pipeline: pipeline.Pipeline{"name": "tenant-2", "driver": "amqp"}, Consume: true}))
+
+	decls, err := st.list()
+	require.NoError(t, err)
+	require.Len(t, decls, 2)
+	assert.Equal(t, "memory", decls["tenant-1"].Pipeline["driver"])
+	assert.Equal(t, "10", decls["tenant-1"].Pipeline["priority"])
+	assert.False(t, decls["tenant-1"].Consume)
+	assert.True(t, decls["tenant-2"].Consume)
+
+	require.NoError(t, st.delete("tenant-1"))
+	decls, err = st.list()
+	require.NoError(t, err)
+	require.Len(t, decls, 1)
+	_, ok := decls["tenant-2"]
+	assert.True(t, ok)
+
+	require.NoError(t, st.close())
+}
+
+func TestKVDeclarations(t *testing.T) {
+	cfg := &DeclarationsConfig{Storage: "test-kv"}
+	require.NoError(t, cfg.InitDefaults())
+
+	p := &Plugin{
+		log:        zap.NewNop(),
+		kvProvider: testStorageProvider{"test-kv": &testStorage{data: make(map[string][]byte)}},
+	}
+
+	testDeclarations(t, &kvDeclarations{p: p, cfg: cfg})
+}
+
+func TestBoltDeclarations(t *testing.T) {
+	cfg := &DeclarationsConfig{File: filepath.Join(t.TempDir(), "rr.db")}
+	require.NoError(t, cfg.InitDefaults())
+
+	st, err := newBoltDeclarations(cfg)
+	require.NoError(t, err)
+
+	testDeclarations(t, st)
+}
+
+func TestDeclarationsConfig(t *testing.T) {
+	assert.Error(t, (&DeclarationsConfig{}).InitDefaults())
+	assert.Error(t, (&DeclarationsConfig{Storage: "kv", File: "rr.db"}).InitDefaults())
+}
+
+func TestExport(t *testing.T) {
+	p := &Plugin{cfg: &Config{Timeout: 1}, log: zap.NewNop()}
+
+	p.pipelines.Store("test-1", &pipeline.Pipeline{"name": "test-1", "driver": "memory", "priority": 10})
+	p.pipelines.Store("test-2", &pipeline.Pipeline{"name": "test-2", "driver": "amqp", "config": map[string]interface{}{"queue": "q"}})
+	p.consumers.Store("test-1", &testConsumer{ready: true})
+
+	data, err := p.Export()
+	require.NoError(t, err)
+
+	out := struct {
+		Jobs struct {
+			Pipelines map[string]map[string]interface{} `yaml:"pipelines"`
+			Consume   []string                          `yaml:"consume"`
+		} `yaml:"jobs"`
+	}{}
+	require.NoError(t, yaml.Unmarshal(data, &out))
+
+	require.Len(t, out.Jobs.Pipelines, 2)
+	assert.Equal(t, "memory", out.Jobs.Pipelines["test-1"]["driver"])
+	assert.Equal(t, 10, out.Jobs.Pipelines["test-1"]["priority"])
+	_, ok := out.Jobs.Pipelines["test-1"]["name"]
+	assert.False(t, ok)
+	assert.Equal(t, map[string]interface{}{"queue": "q"}, out.Jobs.Pipelines["test-2"]["config"])
+	assert.Equal(t, []string{"test-1"}, out.Jobs.Consume)
+}
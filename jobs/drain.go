@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"go.uber.org/zap"
+)
+
+const (
+	// drain progress polling interval
+	drainPollInterval = time.Millisecond * 100
+)
+
+// drainState tracks the jobs of the pipeline taken from the driver but not acknowledged yet
+type drainState struct {
+	// jobs in the pool's queue
+	queued int64
+	// jobs executed by the workers
+	active int64
+	// jobs finished during the drain
+	drained int64
+	// jobs returned to the driver during the drain
+	returned int64
+	// 1 - pipeline is draining, queued jobs are returned to the driver
+	draining uint32
+}
+
+func (d *drainState) isDraining() bool {
+	return atomic.LoadUint32(&d.draining) == 1
+}
+
+// pipelineQueue is the pool's queue passed to the pipeline's driver, it counts the jobs inserted by the driver
+type pipelineQueue struct {
+	pq.Queue
+	st *drainState
+}
+
+func (q *pipelineQueue) Insert(item pq.Item) {
+	atomic.AddInt64(&q.st.queued, 1)
+	q.Queue.Insert(item)
+}
+
+// pipelineQueue wraps the pool's queue for the pipeline's driver
+func (wp *workersPool) pipelineQueue(opts *pipelineOptions) pq.Queue {
+	return &pipelineQueue{
+		Queue: wp.queue,
+		st:    opts.drain,
+	}
+}
+
+// drain pauses the pipelines' drivers and waits for the in-flight jobs within the drain_timeout.
+// Jobs left in the queue are returned to the drivers by the pollers.
+func (p *Plugin) drain(consumers map[string]jobs.Consumer) {
+	if len(consumers) == 0 || p.cfg.DrainTimeout <= 0 {
+		return
+	}
+
+	start := time.Now()
+	states := make(map[string]*drainState, len(consumers))
+
+	for name, c := range consumers {
+		opts := p.options(name)
+		if opts == nil {
+			continue
+		}
+
+		atomic.StoreInt64(&opts.drain.drained, 0)
+		atomic.StoreInt64(&opts.drain.returned, 0)
+		atomic.StoreUint32(&opts.drain.draining, 1)
+		states[name] = opts.drain
+
+		// do not wait for the max_wait of the collected batch
+		if opts.batcher != nil {
+			go p.flushBatch(opts, 0)
+		}
+
+		// stop consuming new jobs, acknowledgements are still possible
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+		st, err := c.State(ctx)
+		if err != nil || st.Ready {
+			c.Pause(ctx, name)
+		}
+		cancel()
+	}
+
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	timeout := time.NewTimer(time.Second * time.Duration(p.cfg.DrainTimeout))
+	defer timeout.Stop()
+
+loop:
+	for {
+		pending := int64(0)
+		for _, st := range states {
+			pending += atomic.LoadInt64(&st.queued) + atomic.LoadInt64(&st.active)
+		}
+
+		if pending == 0 {
+			break
+		}
+
+		select {
+		case <-timeout.C:
+			p.log.Warn("drain timeout exceeded, jobs might be redelivered", zap.Int64("pending", pending), zap.Int("drain_timeout", p.cfg.DrainTimeout))
+			break loop
+		case <-ticker.C:
+		}
+	}
+
+	for name, st := range states {
+		p.log.Info("pipeline was drained",
+			zap.String("pipeline", name),
+			zap.Int64("drained", atomic.LoadInt64(&st.drained)),
+			zap.Int64("returned", atomic.LoadInt64(&st.returned)),
+			zap.Int64("pending", atomic.LoadInt64(&st.queued)+atomic.LoadInt64(&st.active)),
+			zap.Time("start", start),
+			zap.Duration("elapsed", time.Since(start)),
+		)
+	}
+}
+
+// returnJob puts the job taken from the queue of the draining pipeline back to the driver
+func (p *Plugin) returnJob(it *item, st *drainState) {
+	err := it.ack.Requeue(it.ctx.Headers, 0)
+	if err != nil {
+		p.log.Error("failed to return the job to the driver", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.Error(err))
+		return
+	}
+
+	atomic.AddInt64(&st.returned, 1)
+	p.log.Debug("job was returned to the driver", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline))
+}
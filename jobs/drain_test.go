@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testConsumer struct {
+	ready   bool
+	paused  int32
+	resumed int32
+}
+
+func (c *testConsumer) Push(context.Context, *jobs.Job) error              { return nil }
+func (c *testConsumer) Register(context.Context, *pipeline.Pipeline) error { return nil }
+func (c *testConsumer) Run(context.Context, *pipeline.Pipeline) error      { return nil }
+func (c *testConsumer) Stop(context.Context) error                         { return nil }
+
+func (c *testConsumer) Pause(context.Context, string) {
+	atomic.AddInt32(&c.paused, 1)
+}
+
+func (c *testConsumer) Resume(context.Context, string) {
+	atomic.AddInt32(&c.resumed, 1)
+}
+
+func (c *testConsumer) State(context.Context) (*jobs.State, error) {
+	return &jobs.State{Ready: c.ready}, nil
+}
+
+func testDrainPlugin(t *testing.T, drainTimeout int) (*Plugin, *pipelineOptions) {
+	p := &Plugin{
+		cfg: &Config{Timeout: 10, DrainTimeout: drainTimeout},
+		log: zap.NewNop(),
+	}
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+
+	return p, opts
+}
+
+func TestPipelineQueue(t *testing.T) {
+	wp := &workersPool{queue: pq.NewBinHeap(10)}
+	opts := &pipelineOptions{drain: &drainState{}}
+
+	q := wp.pipelineQueue(opts)
+	q.Insert(&testPQItem{id: "1"})
+	q.Insert(&testPQItem{id: "2"})
+
+	assert.Equal(t, int64(2), atomic.LoadInt64(&opts.drain.queued))
+	assert.Equal(t, uint64(2), wp.queue.Len())
+}
+
+func TestDrain(t *testing.T) {
+	p, opts := testDrainPlugin(t, 5)
+	c := &testConsumer{ready: true}
+
+	atomic.StoreInt64(&opts.drain.active, 1)
+	go func() {
+		time.Sleep(time.Millisecond * 300)
+		atomic.AddInt64(&opts.drain.drained, 1)
+		atomic.AddInt64(&opts.drain.active, -1)
+	}()
+
+	start := time.Now()
+	p.drain(map[string]jobs.Consumer{"test": c})
+
+	assert.Less(t, time.Since(start), time.Second*2)
+	assert.True(t, opts.drain.isDraining())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&c.paused))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&opts.drain.drained))
+}
+
+func TestDrainTimeout(t *testing.T) {
+	p, opts := testDrainPlugin(t, 1)
+	// paused pipeline
+	c := &testConsumer{}
+
+	atomic.StoreInt64(&opts.drain.queued, 1)
+
+	start := time.Now()
+	p.drain(map[string]jobs.Consumer{"test": c})
+
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&c.paused))
+
+	// disabled
+	p.cfg.DrainTimeout = -1
+	start = time.Now()
+	p.drain(map[string]jobs.Consumer{"test": c})
+	assert.Less(t, time.Since(start), time.Millisecond*100)
+
+	// disabled by default
+	cfg := &Config{}
+	cfg.InitDefaults()
+	assert.Equal(t, 0, cfg.DrainTimeout)
+
+	p.cfg.DrainTimeout = cfg.DrainTimeout
+	start = time.Now()
+	p.drain(map[string]jobs.Consumer{"test": c})
+	assert.Less(t, time.Since(start), time.Millisecond*100)
+}
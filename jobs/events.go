@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"strconv"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/pubsub"
+	endure "github.com/spiral/endure/pkg/container"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// job lifecycle events
+	EventPushed       string = "pushed"
+	EventDelivered    string = "delivered"
+	EventStarted      string = "started"
+	EventAcked        string = "acked"
+	EventFailed       string = "failed"
+	EventRequeued     string = "requeued"
+	EventDeadLettered string = "dead_lettered"
+)
+
+// Broadcaster publishes the messages to the pubsub drivers (broadcast plugin)
+type Broadcaster interface {
+	pubsub.Broadcaster
+	PublishAsync(m *pubsub.Message)
+}
+
+// EventsConfig configures the job lifecycle events published via the broadcast plugin
+type EventsConfig struct {
+	// Topic to publish the events to, required
+	Topic string `mapstructure:"topic"`
+	// Events to publish, default - all events
+	Events []string `mapstructure:"events"`
+
+	// set of the Events
+	filter map[string]struct{}
+}
+
+func (c *EventsConfig) InitDefaults() error {
+	if c.Topic == "" {
+		return errors.Str("events topic should not be empty")
+	}
+
+	if len(c.Events) == 0 {
+		return nil
+	}
+
+	c.filter = make(map[string]struct{}, len(c.Events))
+	for _, e := range c.Events {
+		switch e {
+		case EventPushed, EventDelivered, EventStarted, EventAcked, EventFailed, EventRequeued, EventDeadLettered:
+			c.filter[e] = struct{}{}
+		default:
+			return errors.Errorf("unknown job event: %s", e)
+		}
+	}
+
+	return nil
+}
+
+// Event is the job lifecycle event published to the events topic
+type Event struct {
+	Event    string `json:"event"`
+	Pipeline string `json:"pipeline"`
+	ID       string `json:"id"`
+	Job      string `json:"job"`
+	Attempt  int    `json:"attempt"`
+	// Duration in milliseconds: push duration for the pushed event, time since the delivery for the others
+	Duration int64     `json:"duration_ms"`
+	Error    string    `json:"error,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+func (p *Plugin) CollectBroadcaster(_ endure.Named, b Broadcaster) {
+	p.broadcaster = b
+}
+
+// eventsEnabled reports whether the event should be published
+func (p *Plugin) eventsEnabled(event string) bool {
+	if p.cfg == nil || p.cfg.Events == nil || p.broadcaster == nil {
+		return false
+	}
+
+	if p.cfg.Events.filter == nil {
+		return true
+	}
+
+	_, ok := p.cfg.Events.filter[event]
+	return ok
+}
+
+func (p *Plugin) publishEvent(ev *Event) {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		p.log.Error("failed to marshal the job event", zap.String("ID", ev.ID), zap.String("event", ev.Event), zap.Error(err))
+		return
+	}
+
+	p.broadcaster.PublishAsync(&pubsub.Message{
+		Topic:   p.cfg.Events.Topic,
+		Payload: data,
+	})
+}
+
+// pushedEvent publishes the pushed event of the job
+func (p *Plugin) pushedEvent(j *jobs.Job, start time.Time) {
+	if !p.eventsEnabled(EventPushed) {
+		return
+	}
+
+	attempt := 1
+	if h, ok := j.Headers[attemptHeader]; ok && len(h) > 0 {
+		if a, err := strconv.Atoi(h[0]); err == nil && a > 0 {
+			attempt = a
+		}
+	}
+
+	p.publishEvent(&Event{
+		Event:    EventPushed,
+		Pipeline: j.Options.Pipeline,
+		ID:       j.Ident,
+		Job:      j.Job,
+		Attempt:  attempt,
+		Duration: time.Since(start).Milliseconds(),
+		Time:     time.Now(),
+	})
+}
+
+// event publishes the event of the job taken from the queue
+func (i *item) event(event string) {
+	if !i.p.eventsEnabled(event) {
+		return
+	}
+
+	ev := &Event{
+		Event:    event,
+		Pipeline: i.ctx.Pipeline,
+		ID:       i.ID(),
+		Job:      i.ctx.Job,
+		Attempt:  i.attempt(),
+		Duration: time.Since(i.start).Milliseconds(),
+		Time:     time.Now(),
+	}
+
+	switch event {
+	case EventFailed, EventRequeued, EventDeadLettered:
+		ev.Error = i.reason
+	}
+
+	i.p.publishEvent(ev)
+}
+
+// finishedEvent returns the event of the acknowledged job according to its status
+func finishedEvent(status string) string {
+	switch status {
+	case StatusFailed:
+		return EventFailed
+	case StatusDead:
+		return EventDeadLettered
+	default:
+		return EventAcked
+	}
+}
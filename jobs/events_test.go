@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/pubsub"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testBroadcaster struct {
+	mu   sync.Mutex
+	msgs []*pubsub.Message
+}
+
+func (b *testBroadcaster) GetDriver(string) (pubsub.SubReader, error) {
+	return nil, nil
+}
+
+func (b *testBroadcaster) PublishAsync(m *pubsub.Message) {
+	b.mu.Lock()
+	b.msgs = append(b.msgs, m)
+	b.mu.Unlock()
+}
+
+func (b *testBroadcaster) events(t *testing.T) []*Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	evs := make([]*Event, 0, len(b.msgs))
+	for _, m := range b.msgs {
+		assert.Equal(t, "jobs-events", m.Topic)
+		ev := &Event{}
+		require.NoError(t, json.Unmarshal(m.Payload, ev))
+		evs = append(evs, ev)
+	}
+
+	return evs
+}
+
+type testAcknowledger struct{}
+
+func (testAcknowledger) Ack() error                               { return nil }
+func (testAcknowledger) Nack() error                              { return nil }
+func (testAcknowledger) Requeue(map[string][]string, int64) error { return nil }
+func (testAcknowledger) Respond([]byte, string) error             { return nil }
+
+func TestEvents(t *testing.T) {
+	b := &testBroadcaster{}
+	p := &Plugin{
+		cfg:         &Config{Events: &EventsConfig{Topic: "jobs-events", Events: []string{EventPushed, EventAcked, EventRequeued}}},
+		log:         zap.NewNop(),
+		broadcaster: b,
+		pipeMetrics: newPipelineMetrics(),
+	}
+	require.NoError(t, p.cfg.Events.InitDefaults())
+
+	p.pushedEvent(&jobs.Job{Ident: "1", Job: "test-job", Options: &jobs.Options{Pipeline: "test"}}, time.Now())
+
+	it := &item{
+		Item:  &testPQItem{id: "1"},
+		ack:   testAcknowledger{},
+		ctx:   &jobContext{ID: "1", Job: "test-job", Pipeline: "test"},
+		p:     p,
+		start: time.Now(),
+	}
+
+	// filtered
+	it.event(EventDelivered)
+
+	it.reason = "error"
+	require.NoError(t, it.Requeue(nil, 0))
+	require.NoError(t, it.Ack())
+
+	evs := b.events(t)
+	require.Len(t, evs, 3)
+
+	assert.Equal(t, EventPushed, evs[0].Event)
+	assert.Equal(t, "test", evs[0].Pipeline)
+	assert.Equal(t, "test-job", evs[0].Job)
+	assert.Equal(t, 1, evs[0].Attempt)
+
+	assert.Equal(t, EventRequeued, evs[1].Event)
+	assert.Equal(t, "error", evs[1].Error)
+
+	assert.Equal(t, EventAcked, evs[2].Event)
+	assert.Equal(t, "1", evs[2].ID)
+	assert.Empty(t, evs[2].Error)
+
+	assert.Error(t, (&EventsConfig{}).InitDefaults())
+	assert.Error(t, (&EventsConfig{Topic: "foo", Events: []string{"foo"}}).InitDefaults())
+}
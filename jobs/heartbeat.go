@@ -0,0 +1,193 @@
+package jobs
+
+import (
+	"sync"
+	"time"
+
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
+	"go.uber.org/zap"
+)
+
+// Extender is implemented by the drivers' items which lease can be extended in the broker
+type Extender interface {
+	// Extend extends the job's lease (visibility timeout, time to run) by the duration from now
+	Extend(d time.Duration) error
+}
+
+// lease is the execution deadline of the job, extended by the heartbeats
+type lease struct {
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// newLease returns the lease for the job timeout, nil - no timeout
+func newLease(timeout time.Duration) *lease {
+	if timeout == 0 {
+		return nil
+	}
+
+	return &lease{deadline: time.Now().Add(timeout)}
+}
+
+// extend moves the deadline to the duration from now, the lease is never shortened
+func (l *lease) extend(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if deadline := time.Now().Add(d); deadline.After(l.deadline) {
+		l.deadline = deadline
+	}
+}
+
+func (l *lease) remaining() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return time.Until(l.deadline)
+}
+
+func (l *lease) getDeadline() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.deadline
+}
+
+// Progress of the job in progress reported by the heartbeats
+type Progress struct {
+	ID       string  `json:"id"`
+	Pipeline string  `json:"pipeline"`
+	Progress float64 `json:"progress"`
+	// Heartbeats is the number of the received heartbeats
+	Heartbeats int       `json:"heartbeats"`
+	Started    time.Time `json:"started_at"`
+	// Updated is the time of the last heartbeat
+	Updated time.Time `json:"updated_at"`
+	// Deadline of the job's lease, zero - no job timeout
+	Deadline time.Time `json:"deadline"`
+}
+
+// ProgressRequest is the Progress RPC request
+type ProgressRequest struct {
+	Pipeline string `json:"pipeline"`
+	ID       string `json:"id"`
+}
+
+// progressState of the job in progress
+type progressState struct {
+	mu         sync.Mutex
+	lease      *lease
+	progress   float64
+	heartbeats int
+	updated    time.Time
+}
+
+func progressKey(pipe, id string) string {
+	return pipe + ":" + id
+}
+
+// startProgress registers the job sent to the worker, l - job's lease (nil - no timeout)
+func (p *Plugin) startProgress(it *item, l *lease) {
+	it.progress = &progressState{lease: l}
+	p.inflight.Store(progressKey(it.ctx.Pipeline, it.ID()), it)
+}
+
+// finishProgress removes the finished job
+func (p *Plugin) finishProgress(it *item) {
+	key := progressKey(it.ctx.Pipeline, it.ID())
+	// the job with the same ID might be started again
+	if v, ok := p.inflight.Load(key); ok && v.(*item) == it {
+		p.inflight.Delete(key)
+	}
+}
+
+// inflightItem returns the job in progress, the job is looked up in all pipelines if the pipeline is empty
+func (p *Plugin) inflightItem(pp, id string) *item {
+	if pp != "" {
+		v, ok := p.inflight.Load(progressKey(pp, id))
+		if !ok {
+			return nil
+		}
+
+		return v.(*item)
+	}
+
+	var it *item
+	p.inflight.Range(func(_, value interface{}) bool {
+		if value.(*item).ID() == id {
+			it = value.(*item)
+			return false
+		}
+
+		return true
+	})
+
+	return it
+}
+
+// Heartbeat extends the lease of the job in progress in the plugin (job timeout) and in the broker, and saves the job's progress
+func (p *Plugin) Heartbeat(hb *protocol.HeartbeatMessage) error {
+	const op = errors.Op("jobs_plugin_heartbeat")
+
+	it := p.inflightItem(hb.Pipeline, hb.ID)
+	if it == nil {
+		return errors.E(op, errors.Errorf("no such job in progress, ID: %s, pipeline: %s", hb.ID, hb.Pipeline))
+	}
+
+	st := it.progress
+	if hb.Extend > 0 {
+		d := time.Second * time.Duration(hb.Extend)
+		if st.lease != nil {
+			st.lease.extend(d)
+		}
+
+		if ext, ok := it.Item.(Extender); ok {
+			err := ext.Extend(d)
+			if err != nil {
+				return errors.E(op, err)
+			}
+		}
+	}
+
+	st.mu.Lock()
+	if hb.Progress != nil {
+		st.progress = *hb.Progress
+	}
+	st.heartbeats++
+	st.updated = time.Now()
+	st.mu.Unlock()
+
+	p.log.Debug("job heartbeat", zap.String("ID", hb.ID), zap.String("pipeline", it.ctx.Pipeline), zap.Int64("extend", hb.Extend))
+
+	return nil
+}
+
+// Progress returns the progress of the job in progress
+func (p *Plugin) Progress(pp, id string) (*Progress, error) {
+	const op = errors.Op("jobs_plugin_progress")
+
+	it := p.inflightItem(pp, id)
+	if it == nil {
+		return nil, errors.E(op, errors.Errorf("no such job in progress, ID: %s, pipeline: %s", id, pp))
+	}
+
+	st := it.progress
+	res := &Progress{
+		ID:       id,
+		Pipeline: it.ctx.Pipeline,
+		Started:  it.start,
+	}
+
+	if st.lease != nil {
+		res.Deadline = st.lease.getDeadline()
+	}
+
+	st.mu.Lock()
+	res.Progress = st.progress
+	res.Heartbeats = st.heartbeats
+	res.Updated = st.updated
+	st.mu.Unlock()
+
+	return res, nil
+}
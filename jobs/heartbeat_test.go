@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/spiral/roadrunner/v2/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testExtendedItem struct {
+	testPQItem
+	extended time.Duration
+}
+
+func (i *testExtendedItem) Extend(d time.Duration) error {
+	i.extended = d
+	return nil
+}
+
+func TestLease(t *testing.T) {
+	assert.Nil(t, newLease(0))
+
+	l := newLease(time.Second)
+	assert.LessOrEqual(t, l.remaining(), time.Second)
+
+	l.extend(time.Minute)
+	assert.Greater(t, l.remaining(), time.Second*59)
+
+	// the lease is never shortened
+	l.extend(time.Second)
+	assert.Greater(t, l.remaining(), time.Second*59)
+}
+
+func TestHeartbeat(t *testing.T) {
+	p := &Plugin{log: zap.NewNop()}
+
+	drv := &testExtendedItem{testPQItem: testPQItem{id: "1"}}
+	it := &item{
+		Item:  drv,
+		ctx:   &jobContext{ID: "1", Pipeline: "test"},
+		p:     p,
+		start: time.Now(),
+	}
+
+	progress := 50.0
+	hb := &protocol.HeartbeatMessage{ID: "1", Pipeline: "test", Extend: 60, Progress: &progress}
+	assert.Error(t, p.Heartbeat(hb))
+
+	p.startProgress(it, newLease(time.Second))
+	require.NoError(t, p.Heartbeat(hb))
+	assert.Equal(t, time.Minute, drv.extended)
+	assert.Greater(t, it.progress.lease.remaining(), time.Second*59)
+
+	// the pipeline is optional
+	hb = &protocol.HeartbeatMessage{ID: "1"}
+	require.NoError(t, p.Heartbeat(hb))
+
+	res, err := p.Progress("test", "1")
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, res.Progress)
+	assert.Equal(t, 2, res.Heartbeats)
+	assert.Equal(t, "test", res.Pipeline)
+	assert.False(t, res.Deadline.IsZero())
+
+	p.finishProgress(it)
+	_, err = p.Progress("test", "1")
+	assert.Error(t, err)
+}
+
+func TestHeartbeatExtendsExec(t *testing.T) {
+	p := &Plugin{log: zap.NewNop(), cfg: &Config{PipelineSize: 10}}
+	p.pldPool = sync.Pool{New: func() interface{} { return new(payload.Payload) }}
+
+	// the dedicated pool of the pipeline with the job timeout has no exec_ttl
+	wp, err := p.pipelinePool("test", &pipelineOptions{JobTimeout: time.Millisecond * 100, PoolConfig: &pool.Config{NumWorkers: 1}})
+	require.NoError(t, err)
+	tp := newTestPool(1)
+	wp.pool = tp
+
+	l := newLease(time.Millisecond * 100)
+	go func() {
+		time.Sleep(time.Millisecond * 50)
+		l.extend(time.Second)
+	}()
+
+	rsp, err := p.exec(wp, []byte("300ms"), []byte("{}"), l)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("300ms"), rsp.Body)
+
+	select {
+	case <-tp.workers[0].killed:
+		t.Fatal("worker of the extended job was killed")
+	default:
+	}
+}
@@ -0,0 +1,323 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// default number of the jobs returned by the Peek
+	peekLimit int = 100
+	// number of the jobs inspected by the Move at once
+	moveBatch int = 100
+)
+
+// Inspector is implemented by the drivers (consumers) able to inspect and manage the jobs waiting in the queue.
+// Methods not supported by the driver should return the errors.Unsupported error.
+type Inspector interface {
+	// Peek returns up to the limit waiting jobs, skipping the first offset jobs
+	Peek(ctx context.Context, limit, offset int) ([]*jobs.Job, error)
+	// Delete removes the waiting job by its ID, nil job - no such job
+	Delete(ctx context.Context, id string) (*jobs.Job, error)
+	// Purge removes all waiting jobs, calls the fn for every removed job and returns their number
+	Purge(ctx context.Context, fn func(*jobs.Job)) (int64, error)
+}
+
+// deletedItem is implemented by the drivers' items which might be deleted while waiting in the pool's queue
+type deletedItem interface {
+	Deleted() bool
+}
+
+// MoveFilter selects the moved jobs, empty filter matches all jobs
+type MoveFilter struct {
+	// Job name
+	Job string `json:"job"`
+	// IDs of the jobs
+	IDs []string `json:"ids"`
+}
+
+func (f *MoveFilter) match(j *jobs.Job) bool {
+	if f == nil {
+		return true
+	}
+
+	if f.Job != "" && f.Job != j.Job {
+		return false
+	}
+
+	if len(f.IDs) == 0 {
+		return true
+	}
+
+	for i := 0; i < len(f.IDs); i++ {
+		if f.IDs[i] == j.Ident {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PeekRequest is the Peek RPC request
+type PeekRequest struct {
+	Pipeline string `json:"pipeline"`
+	// Limit of the returned jobs, default - 100
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+}
+
+// DeleteRequest is the Delete RPC request
+type DeleteRequest struct {
+	Pipeline string `json:"pipeline"`
+	ID       string `json:"id"`
+}
+
+// MoveRequest is the Move RPC request
+type MoveRequest struct {
+	From   string      `json:"from"`
+	To     string      `json:"to"`
+	Filter *MoveFilter `json:"filter"`
+}
+
+// inspector returns the inspector of the pipeline's driver
+func (p *Plugin) inspector(pp string) (Inspector, error) {
+	pipe, ok := p.pipelines.Load(pp)
+	if !ok {
+		return nil, errors.Errorf("no such pipeline, requested: %s", pp)
+	}
+
+	c, ok := p.consumers.Load(pp)
+	if !ok {
+		return nil, errors.Errorf("consumer not registered for the requested driver: %s", pipe.(*pipeline.Pipeline).Driver())
+	}
+
+	ins, ok := c.(Inspector)
+	if !ok {
+		return nil, errors.E(errors.Unsupported, errors.Errorf("jobs inspection is not supported by the driver: %s", pipe.(*pipeline.Pipeline).Driver()))
+	}
+
+	return ins, nil
+}
+
+// Peek returns the jobs waiting in the pipeline's queue
+func (p *Plugin) Peek(pp string, limit, offset int) ([]*jobs.Job, error) {
+	const op = errors.Op("jobs_plugin_peek")
+
+	ins, err := p.inspector(pp)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if limit <= 0 {
+		limit = peekLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+	defer cancel()
+
+	jbs, err := ins.Peek(ctx, limit, offset)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	res := make([]*jobs.Job, 0, len(jbs))
+	for i := 0; i < len(jbs); i++ {
+		res = append(res, p.peeked(pp, jbs[i]))
+	}
+
+	return res, nil
+}
+
+// peeked returns the copy of the waiting job as the worker receives it: the payload is decoded and the internal
+// PushedAt header is stripped. The payload which can't be decoded is returned as is with the CodecHeader.
+func (p *Plugin) peeked(pp string, j *jobs.Job) *jobs.Job {
+	// headers might be shared with the driver's item
+	headers := make(map[string][]string, len(j.Headers))
+	for k, v := range j.Headers {
+		if k != PushedAt {
+			headers[k] = v
+		}
+	}
+
+	res := &jobs.Job{
+		Job:     j.Job,
+		Ident:   j.Ident,
+		Payload: j.Payload,
+		Headers: headers,
+		Options: j.Options,
+	}
+
+	err := p.decodePayload(pp, res)
+	if err != nil {
+		p.log.Warn("failed to decode the payload of the peeked job", zap.String("ID", j.Ident), zap.String("pipeline", pp), zap.Error(err))
+	}
+
+	return res
+}
+
+// Delete removes the job waiting in the pipeline's queue, false - no such job
+func (p *Plugin) Delete(pp, id string) (bool, error) {
+	const op = errors.Op("jobs_plugin_delete")
+
+	ins, err := p.inspector(pp)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+	defer cancel()
+
+	j, err := ins.Delete(ctx, id)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	if j == nil {
+		return false, nil
+	}
+
+	p.removed(pp, j)
+	p.log.Info("job was deleted", zap.String("ID", id), zap.String("pipeline", pp))
+
+	return true, nil
+}
+
+// removed finishes the group's job removed from the queue, the job is counted as failed
+func (p *Plugin) removed(pp string, j *jobs.Job) {
+	p.releaseUnique(pp, j.Headers)
+	p.finishGroupJob(j.Ident, j.Headers, StatusFailed)
+}
+
+// Purge removes all jobs waiting in the pipeline's queue
+func (p *Plugin) Purge(pp string) (int64, error) {
+	const op = errors.Op("jobs_plugin_purge")
+
+	ins, err := p.inspector(pp)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+	defer cancel()
+
+	n, err := ins.Purge(ctx, func(j *jobs.Job) {
+		p.removed(pp, j)
+	})
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	p.log.Info("pipeline was purged", zap.String("pipeline", pp), zap.Int64("deleted", n))
+
+	return n, nil
+}
+
+// Move moves the waiting jobs matching the filter to another pipeline.
+// The job is deleted from the source pipeline first, and returned back if the push fails.
+func (p *Plugin) Move(from, to string, filter *MoveFilter) (int64, error) {
+	const op = errors.Op("jobs_plugin_move")
+
+	if from == to {
+		return 0, errors.E(op, errors.Str("source and destination pipelines should be different"))
+	}
+
+	ins, err := p.inspector(from)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	if _, ok := p.pipelines.Load(to); !ok {
+		return 0, errors.E(op, errors.Errorf("no such pipeline, requested: %s", to))
+	}
+
+	moved := int64(0)
+	offset := 0
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+		jbs, errP := ins.Peek(ctx, moveBatch, offset)
+		cancel()
+		if errP != nil {
+			return moved, errors.E(op, errP)
+		}
+
+		if len(jbs) == 0 {
+			break
+		}
+
+		for i := 0; i < len(jbs); i++ {
+			if !filter.match(jbs[i]) {
+				offset++
+				continue
+			}
+
+			ok, errM := p.moveJob(ins, from, to, jbs[i].Ident)
+			if errM != nil {
+				return moved, errors.E(op, errM)
+			}
+
+			// the job was taken by the consumer, skip it to not inspect it again
+			if !ok {
+				offset++
+				continue
+			}
+
+			moved++
+		}
+	}
+
+	p.log.Info("jobs were moved", zap.String("from", from), zap.String("to", to), zap.Int64("moved", moved))
+
+	return moved, nil
+}
+
+// moveJob deletes the job from the source pipeline and pushes it to the destination pipeline, false - no such job
+func (p *Plugin) moveJob(ins Inspector, from, to, id string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
+	defer cancel()
+
+	j, err := ins.Delete(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	if j == nil {
+		return false, nil
+	}
+
+	p.releaseUnique(from, j.Headers)
+
+	// the payload is encoded again with the destination pipeline's codec
+	err = p.decodePayload(from, j)
+	if err == nil {
+		j.Options.Pipeline = to
+		err = p.push(j)
+		if err == nil {
+			return true, nil
+		}
+
+		// the payload might be already encoded with the destination pipeline's codec
+		errD := p.decodePayload(to, j)
+		if errD != nil {
+			p.log.Error("failed to decode the moved job", zap.String("ID", id), zap.String("pipeline", to), zap.Error(errD))
+		}
+	}
+
+	// return the job to the source pipeline
+	j.Options.Pipeline = from
+	errR := p.push(j)
+	if errR != nil {
+		p.log.Error("failed to return the moved job, job might be lost", zap.String("ID", id), zap.String("pipeline", from), zap.Error(errR))
+	}
+
+	return false, err
+}
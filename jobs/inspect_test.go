@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// testInspector keeps the waiting jobs in the push order
+type testInspector struct {
+	testConsumer
+	jobs []*jobs.Job
+}
+
+func (c *testInspector) Push(_ context.Context, j *jobs.Job) error {
+	c.jobs = append(c.jobs, j)
+	return nil
+}
+
+func (c *testInspector) Peek(_ context.Context, limit, offset int) ([]*jobs.Job, error) {
+	if offset >= len(c.jobs) {
+		return nil, nil
+	}
+
+	res := c.jobs[offset:]
+	if len(res) > limit {
+		res = res[:limit]
+	}
+
+	return res, nil
+}
+
+func (c *testInspector) Delete(_ context.Context, id string) (*jobs.Job, error) {
+	for i := 0; i < len(c.jobs); i++ {
+		if c.jobs[i].Ident == id {
+			j := c.jobs[i]
+			c.jobs = append(c.jobs[:i], c.jobs[i+1:]...)
+			return j, nil
+		}
+	}
+
+	return nil, nil
+}
+
+func (c *testInspector) Purge(_ context.Context, fn func(*jobs.Job)) (int64, error) {
+	purged := c.jobs
+	c.jobs = nil
+	for i := 0; i < len(purged); i++ {
+		fn(purged[i])
+	}
+
+	return int64(len(purged)), nil
+}
+
+func testInspectPlugin() *Plugin {
+	p := &Plugin{
+		cfg: &Config{Timeout: 10},
+		log: zap.NewNop(),
+		metrics: &metrics{
+			jobsOk:  utils.Uint64(0),
+			pushOk:  utils.Uint64(0),
+			jobsErr: utils.Uint64(0),
+			pushErr: utils.Uint64(0),
+		},
+		pipeMetrics: newPipelineMetrics(),
+	}
+
+	p.pipelines.Store("from", &pipeline.Pipeline{"name": "from", "driver": "memory"})
+	p.pipelines.Store("to", &pipeline.Pipeline{"name": "to", "driver": "memory"})
+	p.pipelines.Store("amqp", &pipeline.Pipeline{"name": "amqp", "driver": "amqp"})
+	p.consumers.Store("amqp", &testConsumer{})
+
+	return p
+}
+
+func testJob(id, name string) *jobs.Job {
+	return &jobs.Job{
+		Job:     name,
+		Ident:   id,
+		Options: &jobs.Options{Pipeline: "from"},
+	}
+}
+
+func TestInspectUnsupported(t *testing.T) {
+	p := testInspectPlugin()
+
+	_, err := p.Peek("amqp", 10, 0)
+	require.Error(t, err)
+	assert.True(t, errors.Is(errors.Unsupported, err))
+
+	_, err = p.Purge("unknown")
+	require.Error(t, err)
+	assert.False(t, errors.Is(errors.Unsupported, err))
+}
+
+func TestPeekDeletePurge(t *testing.T) {
+	p := testInspectPlugin()
+	from := &testInspector{jobs: []*jobs.Job{testJob("1", "a"), testJob("2", "b"), testJob("3", "a")}}
+	p.consumers.Store("from", from)
+
+	jbs, err := p.Peek("from", 0, 1)
+	require.NoError(t, err)
+	require.Len(t, jbs, 2)
+	assert.Equal(t, "2", jbs[0].Ident)
+
+	deleted, err := p.Delete("from", "2")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = p.Delete("from", "2")
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	n, err := p.Purge("from")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Empty(t, from.jobs)
+}
+
+func TestMove(t *testing.T) {
+	p := testInspectPlugin()
+	from := &testInspector{}
+	for i := 0; i < moveBatch+10; i++ {
+		name := "a"
+		if i%2 == 0 {
+			name = "b"
+		}
+		from.jobs = append(from.jobs, testJob(strconv.Itoa(i), name))
+	}
+	to := &testInspector{}
+	p.consumers.Store("from", from)
+	p.consumers.Store("to", to)
+
+	moved, err := p.Move("from", "to", &MoveFilter{Job: "a"})
+	require.NoError(t, err)
+	assert.Equal(t, int64((moveBatch+10)/2), moved)
+	assert.Len(t, from.jobs, (moveBatch+10)/2)
+	assert.Len(t, to.jobs, (moveBatch+10)/2)
+
+	for i := 0; i < len(to.jobs); i++ {
+		assert.Equal(t, "a", to.jobs[i].Job)
+		assert.Equal(t, "to", to.jobs[i].Options.Pipeline)
+	}
+
+	moved, err = p.Move("from", "to", &MoveFilter{IDs: []string{from.jobs[0].Ident}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), moved)
+
+	_, err = p.Move("from", "from", nil)
+	assert.Error(t, err)
+
+	_, err = p.Move("from", "unknown", nil)
+	assert.Error(t, err)
+}
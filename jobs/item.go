@@ -0,0 +1,220 @@
+package jobs
+
+import (
+	"strconv"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"go.uber.org/zap"
+)
+
+const (
+	// attempt header contains the current attempt number of the job (starting from 1)
+	attemptHeader string = "rr_attempt"
+)
+
+// jobContext is the context shared by all drivers, see Item.Context() in the drivers
+type jobContext struct {
+	ID       string              `json:"id"`
+	Job      string              `json:"job"`
+	Headers  map[string][]string `json:"headers"`
+	Pipeline string              `json:"pipeline"`
+}
+
+// item wraps the driver's item extracted from the priority queue.
+// It holds the parsed job context and routes the acknowledgement through the jobs plugin.
+type item struct {
+	pq.Item
+	ack jobs.Acknowledger
+	ctx *jobContext
+	p   *Plugin
+
+	// processing start time
+	start time.Time
+	// worker's response, saved only if the pipeline stores the results
+	response []byte
+	// final status and the failure reason of the job
+	status string
+	reason string
+	// lease and progress of the job sent to the worker, see Heartbeat
+	progress *progressState
+	// the job probes the pipeline of the half-open circuit breaker
+	probe bool
+	// payload decoded by the pipeline's codec, see decodeBody
+	body    []byte
+	decoded bool
+}
+
+func (p *Plugin) newItem(jb pq.Item, ack jobs.Acknowledger, rawCtx []byte, start time.Time) (*item, error) {
+	ctx := &jobContext{}
+	err := json.Unmarshal(rawCtx, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &item{
+		Item:  jb,
+		ack:   ack,
+		ctx:   ctx,
+		p:     p,
+		start: start,
+	}, nil
+}
+
+// workerContext returns the context sent to the worker, the internal PushedAt header is stripped
+func (c *jobContext) workerContext() *jobContext {
+	if _, ok := c.Headers[PushedAt]; !ok {
+		return c
+	}
+
+	headers := make(map[string][]string, len(c.Headers)-1)
+	for k, v := range c.Headers {
+		if k != PushedAt {
+			headers[k] = v
+		}
+	}
+
+	return &jobContext{ID: c.ID, Job: c.Job, Headers: headers, Pipeline: c.Pipeline}
+}
+
+// Context returns the job's context sent to the worker w/o the internal headers
+func (i *item) Context() ([]byte, error) {
+	if _, ok := i.ctx.Headers[PushedAt]; !ok {
+		return i.Item.Context()
+	}
+
+	return json.Marshal(i.ctx.workerContext())
+}
+
+// Ack acknowledges the job, releases its unique key and stores the result
+func (i *item) Ack() error {
+	err := i.ack.Ack()
+	if err != nil {
+		return err
+	}
+
+	if i.status == "" {
+		i.status = StatusOk
+	}
+
+	i.p.releaseUnique(i.ctx.Pipeline, i.ctx.Headers)
+	i.p.storeResult(i, i.status)
+	i.p.finishGroupMember(i, i.status)
+	i.observeFinished(i.status)
+	i.p.breakerRecord(i, i.status == StatusOk)
+	i.event(finishedEvent(i.status))
+	return nil
+}
+
+// Nack negatively acknowledges the job, releases its unique key and stores the result
+func (i *item) Nack() error {
+	err := i.ack.Nack()
+	if err != nil {
+		return err
+	}
+
+	i.p.releaseUnique(i.ctx.Pipeline, i.ctx.Headers)
+	i.p.storeResult(i, StatusFailed)
+	i.p.finishGroupMember(i, StatusFailed)
+	i.observeFinished(StatusFailed)
+	i.p.breakerRecord(i, false)
+	i.event(EventFailed)
+	return nil
+}
+
+// Requeue increases the attempt counter and requeues the job. The worker's headers are merged on top of the job's ones,
+// so the internal headers (codec, unique key, group, timeout) are kept.
+func (i *item) Requeue(headers map[string][]string, delay int64) error {
+	merged := make(map[string][]string, len(i.ctx.Headers)+len(headers)+1)
+	for k, v := range i.ctx.Headers {
+		merged[k] = v
+	}
+
+	for k, v := range headers {
+		merged[k] = v
+	}
+	headers = merged
+
+	headers[attemptHeader] = []string{strconv.Itoa(i.attempt() + 1)}
+	headers = i.p.withPushedAt(headers)
+
+	err := i.ack.Requeue(headers, delay)
+	if err != nil {
+		return err
+	}
+
+	i.observeRetry()
+	i.p.breakerRecord(i, false)
+	i.event(EventRequeued)
+	return nil
+}
+
+// Respond sends the response to the queue, releases the job's unique key and stores the result
+func (i *item) Respond(payload []byte, queue string) error {
+	err := i.ack.Respond(payload, queue)
+	if err != nil {
+		return err
+	}
+
+	i.p.releaseUnique(i.ctx.Pipeline, i.ctx.Headers)
+	i.p.storeResult(i, StatusOk)
+	i.p.finishGroupMember(i, StatusOk)
+	i.observeFinished(StatusOk)
+	i.p.breakerRecord(i, true)
+	i.event(EventAcked)
+	return nil
+}
+
+// DeadLetter routes the job into the dead-letter pipeline (if configured) and acknowledges it
+func (i *item) DeadLetter(reason string) error {
+	return i.p.deadLetter(i, reason)
+}
+
+// Fail handles the job failure. When the pipeline has a retry policy, the job is requeued with the policy's
+// backoff until the attempts are exhausted, the worker's requeue flag is ignored in that case.
+// Jobs which should not (or could not) be retried are moved to the dead-letter pipeline.
+func (i *item) Fail(reason string, requeue bool, headers map[string][]string, delay int64) error {
+	opts := i.p.options(i.ctx.Pipeline)
+	if opts == nil || opts.Retry == nil {
+		if requeue {
+			return i.Requeue(headers, delay)
+		}
+
+		return i.DeadLetter(reason)
+	}
+
+	attempt := i.attempt()
+	if opts.Retry.exhausted(attempt) {
+		i.p.log.Warn("job retry attempts exhausted", zap.String("ID", i.ID()), zap.String("pipeline", i.ctx.Pipeline), zap.Int("attempt", attempt), zap.String("reason", reason))
+		return i.DeadLetter(reason)
+	}
+
+	// worker's delay has priority over the policy
+	if delay <= 0 {
+		delay = opts.Retry.delay(attempt)
+	}
+
+	i.p.log.Debug("job will be retried", zap.String("ID", i.ID()), zap.String("pipeline", i.ctx.Pipeline), zap.Int("attempt", attempt), zap.Int64("delay", delay))
+
+	return i.Requeue(headers, delay)
+}
+
+// retryable reports whether the job's pipeline has a retry policy
+func (i *item) retryable() bool {
+	opts := i.p.options(i.ctx.Pipeline)
+	return opts != nil && opts.Retry != nil
+}
+
+// attempt returns the current attempt number, 1 if the job was not requeued before
+func (i *item) attempt() int {
+	if h, ok := i.ctx.Headers[attemptHeader]; ok && len(h) > 0 {
+		a, err := strconv.Atoi(h[0])
+		if err == nil && a > 0 {
+			return a
+		}
+	}
+
+	return 1
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"github.com/roadrunner-server/api/v2/plugins/kv"
+	endure "github.com/spiral/endure/pkg/container"
+	"github.com/spiral/errors"
+)
+
+// StorageProvider provides the storages configured in the kv plugin
+type StorageProvider interface {
+	// Storage returns the storage by its name (key in the kv section)
+	Storage(name string) (kv.Storage, error)
+}
+
+func (p *Plugin) CollectKV(_ endure.Named, sp StorageProvider) {
+	p.kvProvider = sp
+}
+
+// storage returns the kv storage by its name
+func (p *Plugin) storage(name string) (kv.Storage, error) {
+	const op = errors.Op("jobs_plugin_kv_storage")
+	if p.kvProvider == nil {
+		return nil, errors.E(op, errors.Errorf("kv plugin is not configured, requested storage: %s", name))
+	}
+
+	st, err := p.kvProvider.Storage(name)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return st, nil
+}
@@ -5,129 +5,234 @@ import (
 	"time"
 
 	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
 	"go.uber.org/zap"
 )
 
-func (p *Plugin) listener() { //nolint:gocognit
+func (p *Plugin) listener(wp *workersPool) {
 	for i := uint8(0); i < p.cfg.NumPollers; i++ {
 		go func() {
 			for {
 				select {
-				case <-p.stopCh:
-					p.log.Debug("------> job poller was stopped <------")
+				case <-wp.stopCh:
+					p.log.Debug("------> job poller was stopped <------", zap.String("pool", wp.name))
 					return
 				default:
 					start := time.Now()
-					// get prioritized JOB from the queue
-					jb := p.queue.ExtractMin()
-
-					// parse the context
-					// for each job, context contains:
-					/*
-						1. Job class
-						2. Job ID provided from the outside
-						3. Job Headers map[string][]string
-						4. Timeout in seconds
-						5. Pipeline name
-					*/
-
-					p.log.Debug("job processing was started", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-
-					ctx, err := jb.Context()
-					if err != nil {
-						atomic.AddUint64(p.metrics.jobsErr, 1)
-						p.log.Error("job marshal error", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-
-						errNack := jb.(jobs.Acknowledger).Nack()
-						if errNack != nil {
-							p.log.Error("negatively acknowledge was failed", zap.String("ID", jb.ID()), zap.Error(errNack))
-						}
-						continue
-					}
-
-					// get payload from the sync.Pool
-					exec := p.getPayload(jb.Body(), ctx)
-
-					// protect from the pool reset
-					p.RLock()
-					resp, err := p.workersPool.Exec(exec)
-					p.RUnlock()
-					if err != nil {
-						atomic.AddUint64(p.metrics.jobsErr, 1)
-						p.log.Error("job processed with errors", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-						if _, ok := jb.(jobs.Acknowledger); !ok {
-							p.log.Error("job execute failed, job is not a Acknowledger, skipping Ack/Nack")
-							p.putPayload(exec)
-							continue
-						}
-						// RR protocol level error, Nack the job
-						errNack := jb.(jobs.Acknowledger).Nack()
-						if errNack != nil {
-							p.log.Error("negatively acknowledge failed", zap.String("ID", jb.ID()), zap.Error(errNack))
-						}
-
-						p.log.Error("job execute failed", zap.Error(err))
-						p.putPayload(exec)
-						jb = nil
-						continue
-					}
-
-					if _, ok := jb.(jobs.Acknowledger); !ok {
-						// can't acknowledge, just continue
-						p.putPayload(exec)
-						continue
-					}
-
-					// if response is nil or body is nil, just acknowledge the job
-					if resp == nil || resp.Body == nil {
-						p.putPayload(exec)
-						err = jb.(jobs.Acknowledger).Ack()
-						if err != nil {
-							atomic.AddUint64(p.metrics.jobsErr, 1)
-							p.log.Error("acknowledge error, job might be missed", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-							jb = nil
-							continue
-						}
-
-						p.log.Debug("job was processed successfully", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-						// metrics
-						atomic.AddUint64(p.metrics.jobsOk, 1)
-
-						jb = nil
-						continue
-					}
-
-					// handle the response protocol
-					err = p.respHandler.Handle(resp, jb.(jobs.Acknowledger))
-					if err != nil {
-						atomic.AddUint64(p.metrics.jobsErr, 1)
-						p.log.Error("response handler error", zap.Error(err), zap.String("ID", jb.ID()), zap.ByteString("response", resp.Body), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-						p.putPayload(exec)
-						/*
-							Job malformed, acknowledge it to prevent endless loop
-						*/
-						errAck := jb.(jobs.Acknowledger).Ack()
-						if errAck != nil {
-							p.log.Error("acknowledge failed, job might be lost", zap.String("ID", jb.ID()), zap.Error(err), zap.Error(errAck))
-							jb = nil
-							continue
-						}
-
-						p.log.Error("job acknowledged, but contains error", zap.Error(err))
-						jb = nil
-						continue
-					}
-
-					// metrics
-					atomic.AddUint64(p.metrics.jobsOk, 1)
-
-					p.log.Debug("job was processed successfully", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
-
-					// return payload
-					p.putPayload(exec)
-					jb = nil
+					// get prioritized JOB from the pool's queue
+					jb := wp.queue.ExtractMin()
+
+					p.handle(wp, jb, start)
 				}
 			}
 		}()
 	}
 }
+
+// handle executes the job in the pool's worker and acknowledges it according to the worker's response
+func (p *Plugin) handle(wp *workersPool, jb pq.Item, start time.Time) { //nolint:gocognit
+	// parse the context
+	// for each job, context contains:
+	/*
+		1. Job class
+		2. Job ID provided from the outside
+		3. Job Headers map[string][]string
+		4. Timeout in seconds
+		5. Pipeline name
+	*/
+
+	p.log.Debug("job processing was started", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+
+	ctx, err := jb.Context()
+	if err != nil {
+		atomic.AddUint64(p.metrics.jobsErr, 1)
+		p.log.Error("job marshal error", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+
+		errNack := jb.(jobs.Acknowledger).Nack()
+		if errNack != nil {
+			p.log.Error("negatively acknowledge was failed", zap.String("ID", jb.ID()), zap.Error(errNack))
+		}
+		return
+	}
+
+	// wrap the job to route the acknowledgements through the plugin (dead-letter, attempts)
+	if ack, ok := jb.(jobs.Acknowledger); ok {
+		it, errW := p.newItem(jb, ack, ctx, start)
+		if errW != nil {
+			atomic.AddUint64(p.metrics.jobsErr, 1)
+			p.log.Error("job context unmarshal error", zap.Error(errW), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+
+			errNack := ack.Nack()
+			if errNack != nil {
+				p.log.Error("negatively acknowledge was failed", zap.String("ID", jb.ID()), zap.Error(errNack))
+			}
+			return
+		}
+
+		jb = it
+		it.event(EventDelivered)
+
+		// the internal headers are not sent to the worker
+		ctx, err = it.Context()
+		if err != nil {
+			atomic.AddUint64(p.metrics.jobsErr, 1)
+			p.log.Error("job marshal error", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+
+			errNack := ack.Nack()
+			if errNack != nil {
+				p.log.Error("negatively acknowledge was failed", zap.String("ID", jb.ID()), zap.Error(errNack))
+			}
+			return
+		}
+
+		if opts := p.options(it.ctx.Pipeline); opts != nil {
+			st := opts.drain
+			atomic.AddInt64(&st.queued, -1)
+
+			// the job was deleted via the Delete/Purge RPC while waiting in the pool's queue
+			if d, ok := it.Item.(deletedItem); ok && d.Deleted() {
+				p.log.Debug("deleted job was skipped", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline))
+				return
+			}
+
+			// pipeline is stopping, return the job to the driver
+			if st.isDraining() {
+				p.returnJob(it, st)
+				return
+			}
+
+			// the payload is sent to the worker decoded, the job can't be processed without its payload
+			errD := it.decodeBody()
+			if errD != nil {
+				atomic.AddUint64(p.metrics.jobsErr, 1)
+				p.log.Error("job payload decode error", zap.Error(errD), zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline))
+
+				errDl := it.DeadLetter("payload decode error: " + errD.Error())
+				if errDl != nil {
+					p.log.Error("acknowledge failed, job might be lost", zap.String("ID", it.ID()), zap.Error(errDl))
+				}
+				return
+			}
+
+			// circuit breaker is open or the probe job is executed, return the job to the driver
+			if opts.breaker != nil && !p.breakerAdmit(opts.breaker, it) {
+				p.returnJob(it, st)
+				return
+			}
+
+			// the job above the pipeline's rate limit or concurrency cap is returned to the driver,
+			// the batch is throttled as a whole, see execBatch
+			if opts.throttle != nil && opts.batcher == nil {
+				delay, ok := opts.throttle.tryAcquire()
+				if !ok {
+					p.throttleJob(it, delay)
+					return
+				}
+				defer opts.throttle.release()
+			}
+
+			atomic.AddInt64(&st.active, 1)
+
+			// the job is executed with the batch, see execBatch
+			if opts.batcher != nil {
+				p.addToBatch(wp, opts, it)
+				return
+			}
+
+			defer func() {
+				atomic.AddInt64(&st.active, -1)
+				if st.isDraining() {
+					atomic.AddInt64(&st.drained, 1)
+				}
+			}()
+		}
+	}
+
+	var l *lease
+	it, isItem := jb.(*item)
+	if isItem {
+		l = newLease(it.timeout())
+		it.observeStart()
+		it.event(EventStarted)
+		p.startProgress(it, l)
+		defer p.finishProgress(it)
+	}
+
+	execStart := time.Now()
+	resp, err := p.exec(wp, jb.Body(), ctx, l)
+	if isItem {
+		it.observeExec(time.Since(execStart), err)
+	}
+
+	if err != nil {
+		atomic.AddUint64(p.metrics.jobsErr, 1)
+		p.log.Error("job processed with errors", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		if it, ok := jb.(*item); ok {
+			it.reason = err.Error()
+		}
+		if _, ok := jb.(jobs.Acknowledger); !ok {
+			p.log.Error("job execute failed, job is not a Acknowledger, skipping Ack/Nack")
+			return
+		}
+		// retry the job according to the pipeline's retry policy
+		if it, ok := jb.(*item); ok && it.retryable() {
+			errF := it.Fail(err.Error(), true, nil, 0)
+			if errF != nil {
+				p.log.Error("job retry failed", zap.String("ID", jb.ID()), zap.Error(errF))
+			}
+		} else {
+			// RR protocol level error, Nack the job
+			errNack := jb.(jobs.Acknowledger).Nack()
+			if errNack != nil {
+				p.log.Error("negatively acknowledge failed", zap.String("ID", jb.ID()), zap.Error(errNack))
+			}
+		}
+
+		p.log.Error("job execute failed", zap.Error(err))
+		return
+	}
+
+	if _, ok := jb.(jobs.Acknowledger); !ok {
+		// can't acknowledge, just continue
+		return
+	}
+
+	// if response is nil or body is nil, just acknowledge the job
+	if resp == nil || resp.Body == nil {
+		err = jb.(jobs.Acknowledger).Ack()
+		if err != nil {
+			atomic.AddUint64(p.metrics.jobsErr, 1)
+			p.log.Error("acknowledge error, job might be missed", zap.Error(err), zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+			return
+		}
+
+		p.log.Debug("job was processed successfully", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		// metrics
+		atomic.AddUint64(p.metrics.jobsOk, 1)
+
+		return
+	}
+
+	// handle the response protocol
+	err = p.respHandler.Handle(resp, jb.(jobs.Acknowledger))
+	if err != nil {
+		atomic.AddUint64(p.metrics.jobsErr, 1)
+		p.log.Error("response handler error", zap.Error(err), zap.String("ID", jb.ID()), zap.ByteString("response", resp.Body), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+		/*
+			Job malformed, acknowledge it (or move to the dead-letter pipeline) to prevent endless loop
+		*/
+		errAck := jb.(*item).DeadLetter(err.Error())
+		if errAck != nil {
+			p.log.Error("acknowledge failed, job might be lost", zap.String("ID", jb.ID()), zap.Error(err), zap.Error(errAck))
+			return
+		}
+
+		p.log.Error("job acknowledged, but contains error", zap.Error(err))
+		return
+	}
+
+	// metrics
+	atomic.AddUint64(p.metrics.jobsOk, 1)
+
+	p.log.Debug("job was processed successfully", zap.String("ID", jb.ID()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
@@ -1,24 +1,62 @@
 package jobs
 
 import (
+	"context"
+	"strconv"
 	"sync/atomic"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/roadrunner-server/api/v2/plugins/informer"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"go.uber.org/zap"
 )
 
 func (p *Plugin) MetricsCollector() []prometheus.Collector {
+	// the queue wait histogram is collected, the pushed jobs carry the push time.
+	// The collector might be requested while the jobs are pushed.
+	atomic.StoreUint32(&p.queueWait, 1)
+
 	// p - implements Exporter interface (workers)
 	// other - request duration and count
-	return []prometheus.Collector{p.statsExporter}
+	return []prometheus.Collector{
+		p.statsExporter,
+		p.pipeMetrics.pushTotal,
+		p.pipeMetrics.pushDuration,
+		p.pipeMetrics.queueWait,
+		p.pipeMetrics.execDuration,
+		p.pipeMetrics.jobsTotal,
+		p.pipeMetrics.retries,
+		p.pipeMetrics.deadLetters,
+		p.pipeMetrics.breakerOpens,
+	}
 }
 
 const (
 	namespace = "rr_jobs"
+
+	// PushedAt header contains the push (or requeue) time of the job in the unix nanoseconds, used for the queue wait time
+	PushedAt string = "rr_pushed_at"
+
+	// metrics labels
+	labelPipeline string = "pipeline"
+	labelDriver   string = "driver"
+	labelJob      string = "job"
+	labelStatus   string = "status"
+	labelState    string = "state"
+
+	statusError string = "error"
 )
 
 type statsExporter struct {
-	workers       informer.Informer
+	workers informer.Informer
+	// drivers state, see Plugin.JobsState
+	state func(ctx context.Context) ([]*jobs.State, error)
+	// circuit breakers state, see Plugin.Breakers
+	breakers      func() []*BreakerState
+	log           *zap.Logger
+	timeout       time.Duration
 	workersMemory uint64
 	jobsOk        *uint64
 	pushOk        *uint64
@@ -30,11 +68,19 @@ type statsExporter struct {
 	pushErrDesc *prometheus.Desc
 	jobsErrDesc *prometheus.Desc
 	jobsOkDesc  *prometheus.Desc
+	// per pipeline gauges
+	pipelineJobsDesc  *prometheus.Desc
+	pipelineReadyDesc *prometheus.Desc
+	breakerStateDesc  *prometheus.Desc
 }
 
-func newStatsExporter(stats informer.Informer, jobsOk, pushOk, jobsErr, pushErr *uint64) *statsExporter {
+func newStatsExporter(p *Plugin, jobsOk, pushOk, jobsErr, pushErr *uint64) *statsExporter {
 	return &statsExporter{
-		workers:       stats,
+		workers:       p,
+		state:         p.JobsState,
+		breakers:      p.Breakers,
+		log:           p.log,
+		timeout:       time.Second * time.Duration(p.cfg.Timeout),
 		workersMemory: 0,
 		jobsOk:        jobsOk,
 		pushOk:        pushOk,
@@ -46,6 +92,13 @@ func newStatsExporter(stats informer.Informer, jobsOk, pushOk, jobsErr, pushErr
 		pushErrDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "push_err"), "Number of jobs push which was failed.", nil, nil),
 		jobsErrDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "jobs_err"), "Number of jobs error while processing in the worker.", nil, nil),
 		jobsOkDesc:  prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "jobs_ok"), "Number of successfully processed jobs.", nil, nil),
+
+		pipelineJobsDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pipeline", "jobs"),
+			"Number of the pipeline's jobs in the driver by state: active (ready to be processed), delayed, reserved.", []string{labelPipeline, labelDriver, labelState}, nil),
+		pipelineReadyDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pipeline", "ready"),
+			"Pipeline status: 1 - consumed, 0 - paused.", []string{labelPipeline, labelDriver}, nil),
+		breakerStateDesc: prometheus.NewDesc(prometheus.BuildFQName(namespace, "pipeline", "breaker_state"),
+			"Circuit breaker state of the pipeline: 1 - current state (closed, open, half_open).", []string{labelPipeline, labelState}, nil),
 	}
 }
 
@@ -56,6 +109,9 @@ func (se *statsExporter) Describe(d chan<- *prometheus.Desc) {
 	d <- se.pushOkDesc
 	d <- se.jobsErrDesc
 	d <- se.jobsOkDesc
+	d <- se.pipelineJobsDesc
+	d <- se.pipelineReadyDesc
+	d <- se.breakerStateDesc
 }
 
 func (se *statsExporter) Collect(ch chan<- prometheus.Metric) {
@@ -77,4 +133,183 @@ func (se *statsExporter) Collect(ch chan<- prometheus.Metric) {
 	ch <- prometheus.MustNewConstMetric(se.jobsErrDesc, prometheus.GaugeValue, float64(atomic.LoadUint64(se.jobsErr)))
 	ch <- prometheus.MustNewConstMetric(se.pushOkDesc, prometheus.GaugeValue, float64(atomic.LoadUint64(se.pushOk)))
 	ch <- prometheus.MustNewConstMetric(se.pushErrDesc, prometheus.GaugeValue, float64(atomic.LoadUint64(se.pushErr)))
+
+	for _, b := range se.breakers() {
+		for _, state := range []string{BreakerClosed, BreakerOpen, BreakerHalfOpen} {
+			v := 0.0
+			if b.State == state {
+				v = 1
+			}
+			ch <- prometheus.MustNewConstMetric(se.breakerStateDesc, prometheus.GaugeValue, v, b.Pipeline, state)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), se.timeout)
+	defer cancel()
+
+	states, err := se.state(ctx)
+	if err != nil {
+		se.log.Error("failed to collect the pipelines state", zap.Error(err))
+		return
+	}
+
+	for i := 0; i < len(states); i++ {
+		st := states[i]
+		ch <- prometheus.MustNewConstMetric(se.pipelineJobsDesc, prometheus.GaugeValue, float64(st.Active), st.Pipeline, st.Driver, "active")
+		ch <- prometheus.MustNewConstMetric(se.pipelineJobsDesc, prometheus.GaugeValue, float64(st.Delayed), st.Pipeline, st.Driver, "delayed")
+		ch <- prometheus.MustNewConstMetric(se.pipelineJobsDesc, prometheus.GaugeValue, float64(st.Reserved), st.Pipeline, st.Driver, "reserved")
+
+		ready := 0.0
+		if st.Ready {
+			ready = 1
+		}
+		ch <- prometheus.MustNewConstMetric(se.pipelineReadyDesc, prometheus.GaugeValue, ready, st.Pipeline, st.Driver)
+	}
+}
+
+// pipelineMetrics are the jobs metrics labeled by the pipeline, driver and job name
+type pipelineMetrics struct {
+	pushTotal    *prometheus.CounterVec
+	pushDuration *prometheus.HistogramVec
+	// time between the push (or requeue) and the execution start
+	queueWait    *prometheus.HistogramVec
+	execDuration *prometheus.HistogramVec
+	// finished jobs by the final status: ok, failed, dead
+	jobsTotal   *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	deadLetters *prometheus.CounterVec
+	// circuit breaker opens by the pipeline and driver
+	breakerOpens *prometheus.CounterVec
+}
+
+func newPipelineMetrics() *pipelineMetrics {
+	labels := []string{labelPipeline, labelDriver, labelJob}
+	// from 5ms to ~45 minutes, jobs might be long-running
+	buckets := prometheus.ExponentialBuckets(0.005, 2, 20)
+
+	return &pipelineMetrics{
+		pushTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "push_total",
+			Help:      "Total number of pushed jobs by status: ok, error.",
+		}, append(labels, labelStatus)),
+		pushDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "push_duration_seconds",
+			Help:      "Job push duration.",
+		}, labels),
+		queueWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "queue_wait_seconds",
+			Help:      "Time between the job push (or requeue) and the execution start.",
+			Buckets:   buckets,
+		}, labels),
+		execDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "exec_duration_seconds",
+			Help:      "Job execution duration in the worker by status: ok, error.",
+			Buckets:   buckets,
+		}, append(labels, labelStatus)),
+		jobsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "jobs_total",
+			Help:      "Total number of finished jobs by status: ok, failed, dead.",
+		}, append(labels, labelStatus)),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Total number of requeued jobs.",
+		}, labels),
+		deadLetters: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "dead_letters_total",
+			Help:      "Total number of jobs moved to the dead-letter pipeline.",
+		}, labels),
+		breakerOpens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "breaker_opens_total",
+			Help:      "Total number of the circuit breaker opens.",
+		}, []string{labelPipeline, labelDriver}),
+	}
+}
+
+// pipelineDriver returns the driver name of the pipeline
+func (p *Plugin) pipelineDriver(name string) string {
+	pipe, ok := p.pipelines.Load(name)
+	if !ok {
+		return ""
+	}
+
+	return pipe.(*pipeline.Pipeline).Driver()
+}
+
+// observePush records the push latency and status
+func (p *Plugin) observePush(j *jobs.Job, driver string, start time.Time, err error) {
+	status := StatusOk
+	if err != nil {
+		status = statusError
+	}
+
+	p.pipeMetrics.pushTotal.WithLabelValues(j.Options.Pipeline, driver, j.Job, status).Inc()
+	p.pipeMetrics.pushDuration.WithLabelValues(j.Options.Pipeline, driver, j.Job).Observe(time.Since(start).Seconds())
+}
+
+// withPushedAt sets the push time header of the job if the queue wait histogram is collected
+func (p *Plugin) withPushedAt(headers map[string][]string) map[string][]string {
+	if atomic.LoadUint32(&p.queueWait) == 0 {
+		return headers
+	}
+
+	if headers == nil {
+		headers = make(map[string][]string, 1)
+	}
+
+	headers[PushedAt] = []string{strconv.FormatInt(time.Now().UnixNano(), 10)}
+	return headers
+}
+
+// observeStart records the queue wait time of the job
+func (i *item) observeStart() {
+	h, ok := i.ctx.Headers[PushedAt]
+	if !ok || len(h) == 0 {
+		return
+	}
+
+	ns, err := strconv.ParseInt(h[0], 10, 64)
+	if err != nil {
+		return
+	}
+
+	wait := time.Since(time.Unix(0, ns))
+	if wait < 0 {
+		// clock skew between the RR instances
+		wait = 0
+	}
+
+	i.p.pipeMetrics.queueWait.WithLabelValues(i.ctx.Pipeline, i.p.pipelineDriver(i.ctx.Pipeline), i.ctx.Job).Observe(wait.Seconds())
+}
+
+// observeExec records the job execution duration
+func (i *item) observeExec(elapsed time.Duration, err error) {
+	status := StatusOk
+	if err != nil {
+		status = statusError
+	}
+
+	i.p.pipeMetrics.execDuration.WithLabelValues(i.ctx.Pipeline, i.p.pipelineDriver(i.ctx.Pipeline), i.ctx.Job, status).Observe(elapsed.Seconds())
+}
+
+// observeFinished counts the finished job by the final status
+func (i *item) observeFinished(status string) {
+	driver := i.p.pipelineDriver(i.ctx.Pipeline)
+	i.p.pipeMetrics.jobsTotal.WithLabelValues(i.ctx.Pipeline, driver, i.ctx.Job, status).Inc()
+
+	if status == StatusDead {
+		i.p.pipeMetrics.deadLetters.WithLabelValues(i.ctx.Pipeline, driver, i.ctx.Job).Inc()
+	}
+}
+
+// observeRetry counts the requeued job
+func (i *item) observeRetry() {
+	i.p.pipeMetrics.retries.WithLabelValues(i.ctx.Pipeline, i.p.pipelineDriver(i.ctx.Pipeline), i.ctx.Job).Inc()
 }
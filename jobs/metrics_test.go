@@ -0,0 +1,129 @@
+package jobs
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPipelineMetrics(t *testing.T) {
+	p := &Plugin{
+		log:         zap.NewNop(),
+		pipeMetrics: newPipelineMetrics(),
+	}
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+
+	j := &jobs.Job{Job: "test-job", Options: &jobs.Options{Pipeline: "test"}}
+	// the queue wait histogram is not collected
+	assert.Nil(t, p.withPushedAt(j.Headers))
+
+	p.MetricsCollector()
+	j.Headers = p.withPushedAt(j.Headers)
+	assert.Len(t, j.Headers[PushedAt], 1)
+	p.observePush(j, "memory", time.Now(), nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.pipeMetrics.pushTotal.WithLabelValues("test", "memory", "test-job", StatusOk)))
+
+	it := &item{
+		Item: &testPQItem{id: "1"},
+		ack:  testAcknowledger{},
+		ctx: &jobContext{
+			ID:       "1",
+			Job:      "test-job",
+			Pipeline: "test",
+			Headers:  map[string][]string{PushedAt: {strconv.FormatInt(time.Now().Add(-time.Second).UnixNano(), 10)}},
+		},
+		p: p,
+	}
+
+	it.observeStart()
+	assert.Equal(t, 1, testutil.CollectAndCount(p.pipeMetrics.queueWait))
+
+	require.NoError(t, it.Requeue(nil, 0))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.pipeMetrics.retries.WithLabelValues("test", "memory", "test-job")))
+
+	it.status = StatusDead
+	require.NoError(t, it.Ack())
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.pipeMetrics.jobsTotal.WithLabelValues("test", "memory", "test-job", StatusDead)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(p.pipeMetrics.deadLetters.WithLabelValues("test", "memory", "test-job")))
+}
+
+func TestQueueWaitConcurrent(t *testing.T) {
+	p := &Plugin{
+		log:         zap.NewNop(),
+		pipeMetrics: newPipelineMetrics(),
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		p.MetricsCollector()
+	}()
+
+	for i := 0; i < 100; i++ {
+		p.withPushedAt(make(map[string][]string, 1))
+	}
+	wg.Wait()
+
+	assert.Len(t, p.withPushedAt(nil)[PushedAt], 1)
+}
+
+func TestPipelineStateGauges(t *testing.T) {
+	p := &Plugin{cfg: &Config{Timeout: 1}, log: zap.NewNop()}
+	exp := newStatsExporter(p, new(uint64), new(uint64), new(uint64), new(uint64))
+	exp.state = func(context.Context) ([]*jobs.State, error) {
+		return []*jobs.State{{Pipeline: "test", Driver: "amqp", Active: 10, Delayed: 2, Reserved: 1, Ready: true}}, nil
+	}
+
+	reg := prometheus.NewPedanticRegistry()
+	require.NoError(t, reg.Register(exp))
+
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+
+	values := make(map[string]float64)
+	for _, mf := range mfs {
+		for _, m := range mf.GetMetric() {
+			key := mf.GetName()
+			for _, l := range m.GetLabel() {
+				if l.GetName() == labelState {
+					key += ":" + l.GetValue()
+				}
+			}
+			values[key] = m.GetGauge().GetValue()
+		}
+	}
+
+	assert.Equal(t, 10.0, values["rr_jobs_pipeline_jobs:active"])
+	assert.Equal(t, 2.0, values["rr_jobs_pipeline_jobs:delayed"])
+	assert.Equal(t, 1.0, values["rr_jobs_pipeline_jobs:reserved"])
+	assert.Equal(t, 1.0, values["rr_jobs_pipeline_ready"])
+}
+
+func TestWorkerContext(t *testing.T) {
+	it := &item{
+		Item: &testPQItem{id: "1"},
+		ctx: &jobContext{
+			ID:       "1",
+			Job:      "test-job",
+			Pipeline: "test",
+			Headers:  map[string][]string{PushedAt: {"1"}, "foo": {"bar"}},
+		},
+	}
+
+	data, err := it.Context()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"1","job":"test-job","pipeline":"test","headers":{"foo":["bar"]}}`, string(data))
+	// the item's headers are kept for the metrics
+	assert.Len(t, it.ctx.Headers, 2)
+}
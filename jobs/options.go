@@ -0,0 +1,230 @@
+package jobs
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/mitchellh/mapstructure"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/pool"
+	"github.com/spiral/roadrunner/v2/utils"
+)
+
+const (
+	// pipeline's driver specific section (v2.7 and newer)
+	pipelineConfig string = "config"
+)
+
+// pipelineOptions are the driver independent options of the pipeline handled by the jobs plugin.
+// Options might be placed on the pipeline level or inside the pipeline's `config` section.
+type pipelineOptions struct {
+	// DeadLetter is the name of the pipeline for the failed jobs
+	DeadLetter string `mapstructure:"dead_letter"`
+	// Retry policy for the failed jobs
+	Retry *retryPolicy `mapstructure:"retry"`
+	// Unique configures deduplication of the jobs by the idempotency key
+	Unique *uniqueOptions `mapstructure:"unique"`
+	// Results configures the storage of the job results
+	Results *resultsOptions `mapstructure:"results"`
+	// RateLimit limits the number of jobs per second handed to the workers
+	RateLimit *rateLimit `mapstructure:"rate_limit"`
+	// MaxConcurrency is the max number of the pipeline's jobs executed at the same time
+	MaxConcurrency int `mapstructure:"max_concurrency"`
+	// Batch configures delivering several jobs to the worker in one payload
+	Batch *batchOptions `mapstructure:"batch"`
+	// CircuitBreaker pauses the pipeline when the error ratio of its jobs crosses the threshold
+	CircuitBreaker *breakerOptions `mapstructure:"circuit_breaker"`
+	// Schemas are the JSON schema files of the jobs payloads, keys are the jobs names
+	Schemas map[string]string `mapstructure:"schemas"`
+	// Codec configures the compression and the encryption of the jobs payloads
+	Codec *codecOptions `mapstructure:"codec"`
+	// JobTimeout is the default execution timeout of the pipeline's jobs, 0 - no timeout
+	JobTimeout time.Duration `mapstructure:"job_timeout"`
+	// PoolRaw is the name of the pool (jobs.pools) or the dedicated pool configuration
+	PoolRaw interface{} `mapstructure:"pool"`
+
+	// parsed PoolRaw
+	Pool       string       `mapstructure:"-"`
+	PoolConfig *pool.Config `mapstructure:"-"`
+
+	// rate limit and concurrency cap state
+	throttle *throttle
+	// queued and in-flight jobs of the pipeline
+	drain *drainState
+	// collected jobs of the batch, nil if the batch is not configured
+	batcher *batcher
+	// circuit breaker state, nil if the circuit breaker is not configured
+	breaker *breaker
+	// compiled Schemas
+	schemas map[string]*jsonschema.Schema
+}
+
+func (o *pipelineOptions) InitDefaults() error {
+	if o.Retry != nil {
+		err := o.Retry.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.Unique != nil {
+		err := o.Unique.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.Results != nil {
+		err := o.Results.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.RateLimit != nil {
+		err := o.RateLimit.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.Batch != nil {
+		err := o.Batch.InitDefaults()
+		if err != nil {
+			return err
+		}
+
+		// generation 0 is reserved
+		o.batcher = &batcher{gen: 1}
+	}
+
+	if o.CircuitBreaker != nil {
+		err := o.CircuitBreaker.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.Codec != nil {
+		err := o.Codec.InitDefaults()
+		if err != nil {
+			return err
+		}
+	}
+
+	schemas, err := compileSchemas(o.Schemas)
+	if err != nil {
+		return err
+	}
+	o.schemas = schemas
+
+	if o.JobTimeout < 0 {
+		return errors.Errorf("job_timeout should be positive, provided: %s", o.JobTimeout)
+	}
+
+	if o.MaxConcurrency < 0 {
+		return errors.Errorf("max_concurrency should be positive, provided: %d", o.MaxConcurrency)
+	}
+
+	o.throttle = newThrottle(o.RateLimit, o.MaxConcurrency)
+	o.drain = &drainState{}
+	o.breaker = newBreaker(o.CircuitBreaker)
+
+	return nil
+}
+
+// parseOptions decodes the jobs plugin options from the pipeline.
+// Values might be nested maps (configuration file) or JSON strings (Declare RPC call).
+func parseOptions(pipe *pipeline.Pipeline) (*pipelineOptions, error) {
+	const op = errors.Op("jobs_parse_pipeline_options")
+	opts := &pipelineOptions{}
+
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			// should be the last one, skipped values are nil
+			jsonStringToMapHookFunc(),
+		),
+		WeaklyTypedInput: true,
+		Result:           opts,
+	})
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	err = dec.Decode(map[string]interface{}(*pipe))
+	if err != nil {
+		return nil, errors.E(op, errors.Errorf("pipeline: %s, error: %v", pipe.Name(), err))
+	}
+
+	// driver specific section overrides the pipeline level options
+	if cfg, ok := (*pipe)[pipelineConfig].(map[string]interface{}); ok {
+		err = dec.Decode(cfg)
+		if err != nil {
+			return nil, errors.E(op, errors.Errorf("pipeline: %s, error: %v", pipe.Name(), err))
+		}
+	}
+
+	err = parsePoolOption(opts)
+	if err != nil {
+		return nil, errors.E(op, errors.Errorf("pipeline: %s, error: %v", pipe.Name(), err))
+	}
+
+	err = opts.InitDefaults()
+	if err != nil {
+		return nil, errors.E(op, errors.Errorf("pipeline: %s, error: %v", pipe.Name(), err))
+	}
+
+	return opts, nil
+}
+
+// jsonStringToMapHookFunc converts JSON objects received as strings (Declare RPC) into maps.
+// Scalar values of the struct options are skipped, because drivers might use the same keys for their own options
+// (e.g. NATS rate_limit).
+func jsonStringToMapHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		if t.Kind() != reflect.Struct && t.Kind() != reflect.Map {
+			return data, nil
+		}
+
+		switch f.Kind() { //nolint:exhaustive
+		case reflect.String:
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			return nil, nil
+		default:
+			return data, nil
+		}
+
+		str := strings.TrimSpace(data.(string))
+		if !strings.HasPrefix(str, "{") {
+			return nil, nil
+		}
+
+		out := make(map[string]interface{})
+		err := json.Unmarshal(utils.AsBytes(str), &out)
+		if err != nil {
+			return nil, err
+		}
+
+		return out, nil
+	}
+}
+
+// options returns the parsed options of the pipeline or nil if there is no such pipeline
+func (p *Plugin) options(name string) *pipelineOptions {
+	opts, ok := p.pipelineOpts.Load(name)
+	if !ok {
+		return nil
+	}
+
+	return opts.(*pipelineOptions)
+}
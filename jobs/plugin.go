@@ -3,6 +3,7 @@ package jobs
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,8 +17,6 @@ import (
 	"github.com/spiral/goridge/v3/pkg/frame"
 	rh "github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
 	"github.com/spiral/roadrunner/v2/payload"
-	"github.com/spiral/roadrunner/v2/pool"
-	pq "github.com/spiral/roadrunner/v2/priority_queue"
 	"github.com/spiral/roadrunner/v2/state/process"
 	"github.com/spiral/roadrunner/v2/utils"
 	"go.uber.org/zap"
@@ -40,35 +39,50 @@ type metrics struct {
 }
 
 type Plugin struct {
-	sync.RWMutex
-
 	// Jobs plugin configuration
-	cfg         *Config `structure:"jobs"`
-	log         *zap.Logger
-	workersPool pool.Pool
-	server      server.Server
+	cfg    *Config `structure:"jobs"`
+	log    *zap.Logger
+	server server.Server
+
+	// workers pools, keys are pools names, values - *workersPool
+	pools sync.Map
 
 	jobConstructors map[string]jobs.Constructor
 	consumers       sync.Map // map[string]jobs.Consumer
+	// kv storages provider (optional)
+	kvProvider StorageProvider
+	// broadcast plugin to publish the job events (optional)
+	broadcaster Broadcaster
+	// storage of the declared pipelines, nil if not configured
+	declarations declarationsStore
+	// names of the pipelines declared via the Declare RPC
+	declared sync.Map
+	// protects unique keys check and set
+	uniqueMu sync.Mutex
+	// protects the groups state read-modify-write
+	groupMu sync.Mutex
+	// jobs sent to the workers, keys are pipeline:id, values - *item
+	inflight sync.Map
 
 	metrics *metrics
 
-	// priority queue implementation
-	queue pq.Queue
-
 	// parent config for broken options. keys are pipelines names, values - pointers to the associated pipeline
 	pipelines sync.Map
+	// jobs plugin options of the pipelines, keys are pipelines names, values - *pipelineOptions
+	pipelineOpts sync.Map
 
 	// initial set of the pipelines to consume
 	consume map[string]struct{}
 
-	// signal channel to stop the pollers
-	stopCh chan struct{}
-
 	// internal payloads pool
 	pldPool       sync.Pool
 	statsExporter *statsExporter
-	respHandler   *rh.RespHandler
+	pipeMetrics   *pipelineMetrics
+	// 1 - the queue wait histogram is collected by the metrics plugin, see withPushedAt
+	queueWait   uint32
+	respHandler *rh.RespHandler
+	// cron scheduler, nil if there are no scheduled jobs
+	scheduler *scheduler
 }
 
 func (p *Plugin) Init(cfg config.Configurer, log *zap.Logger, server server.Server) error {
@@ -88,7 +102,6 @@ func (p *Plugin) Init(cfg config.Configurer, log *zap.Logger, server server.Serv
 
 	p.jobConstructors = make(map[string]jobs.Constructor)
 	p.consume = make(map[string]struct{})
-	p.stopCh = make(chan struct{}, 1)
 
 	p.pldPool = sync.Pool{New: func() interface{} {
 		// with nil fields
@@ -106,10 +119,18 @@ func (p *Plugin) Init(cfg config.Configurer, log *zap.Logger, server server.Serv
 		}
 	}
 
-	// initialize priority queue
-	p.queue = pq.NewBinHeap(p.cfg.PipelineSize)
 	p.log = new(zap.Logger)
 	*p.log = *log
+
+	// initialize the default and the named pools (each with its own priority queue)
+	p.addPool(defaultPool, p.cfg.Pool)
+	for name, cfg := range p.cfg.Pools {
+		if name == defaultPool {
+			return errors.E(op, errors.Errorf("pool name is reserved: %s", defaultPool))
+		}
+
+		p.addPool(name, cfg)
+	}
 	p.metrics = &metrics{
 		jobsOk:  utils.Uint64(0),
 		pushOk:  utils.Uint64(0),
@@ -118,13 +139,36 @@ func (p *Plugin) Init(cfg config.Configurer, log *zap.Logger, server server.Serv
 	}
 
 	// metrics
+	p.pipeMetrics = newPipelineMetrics()
 	p.statsExporter = newStatsExporter(p, p.metrics.jobsOk, p.metrics.pushOk, p.metrics.jobsErr, p.metrics.pushErr)
 	p.respHandler = rh.NewResponseHandler(log)
 
+	if p.cfg.Events != nil {
+		err = p.cfg.Events.InitDefaults()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	if p.cfg.Workflow != nil {
+		err = p.cfg.Workflow.InitDefaults()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	err = p.initDeclarations()
 	if err != nil {
 		return errors.E(op, err)
 	}
 
+	if len(p.cfg.Schedule) > 0 {
+		p.scheduler, err = newScheduler(p, p.log, p.cfg.Schedule, p.cfg.ScheduleLock)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
 	return nil
 }
 
@@ -148,6 +192,20 @@ func (p *Plugin) Serve() chan error {
 			return true
 		}
 
+		opts, err := parseOptions(pipe)
+		if err != nil {
+			errCh <- errors.E(op, err)
+			return false
+		}
+
+		p.pipelineOpts.Store(name, opts)
+
+		wp, err := p.pipelinePool(name, opts)
+		if err != nil {
+			errCh <- errors.E(op, err)
+			return false
+		}
+
 		// jobConstructors contains constructors for the drivers
 		// we need here to initialize these drivers for the pipelines
 		if _, ok := p.jobConstructors[dr]; ok {
@@ -155,8 +213,8 @@ func (p *Plugin) Serve() chan error {
 			// config key for the particular sub-driver jobs.pipelines.test-local
 			configKey := fmt.Sprintf("%s.%s.%s.%s", PluginName, pipelines, name, cfgKey)
 
-			// init the driver
-			initializedDriver, err := p.jobConstructors[dr].ConsumerFromConfig(configKey, p.queue)
+			// init the driver, driver pushes the jobs into the queue of the pipeline's pool
+			initializedDriver, err := p.jobConstructors[dr].ConsumerFromConfig(configKey, wp.pipelineQueue(opts))
 			if err != nil {
 				errCh <- errors.E(op, err)
 				return false
@@ -196,34 +254,63 @@ func (p *Plugin) Serve() chan error {
 		return errCh
 	}
 
+	// restore the pipelines declared via the Declare RPC
+	err := p.replayDeclarations()
+	if err != nil {
+		errCh <- errors.E(op, err)
+		return errCh
+	}
+
 	go func() {
-		p.Lock()
 		var err error
-		p.workersPool, err = p.server.NewWorkerPool(context.Background(), p.cfg.Pool, map[string]string{RrMode: RrModeJobs})
+		p.pools.Range(func(_, value interface{}) bool {
+			err = p.startPool(value.(*workersPool))
+			return err == nil
+		})
+
 		if err != nil {
-			p.Unlock()
 			errCh <- err
-			return
 		}
-		p.Unlock()
-
-		// start listening
-		p.listener()
 	}()
 
+	if p.scheduler != nil {
+		p.scheduler.start()
+	}
+
+	if p.cfg.Events != nil && p.broadcaster == nil {
+		p.log.Warn("jobs events are configured, but the broadcast plugin is not enabled, events will not be published")
+	}
+
 	return errCh
 }
 
 func (p *Plugin) Stop() error {
-	// this function can block forever, but we don't care, because we might have a chance to exit from the pollers,
-	// but if not, this is not a problem at all.
-	// The main target is to stop the drivers
-	go func() {
-		for i := uint8(0); i < p.cfg.NumPollers; i++ {
-			// stop jobs plugin pollers
-			p.stopCh <- struct{}{}
-		}
-	}()
+	// stop pushing the scheduled jobs before stopping the drivers
+	if p.scheduler != nil {
+		p.scheduler.stop()
+	}
+
+	// finish the in-flight jobs and return the queued ones before stopping the pollers
+	consumers := make(map[string]jobs.Consumer)
+	p.consumers.Range(func(key, value interface{}) bool {
+		consumers[key.(string)] = value.(jobs.Consumer)
+		// the circuit breaker should not resume the stopping pipeline
+		p.resetBreaker(key.(string), true)
+		return true
+	})
+	p.drain(consumers)
+
+	// stop the pollers, the main target is to stop the drivers
+	p.pools.Range(func(_, value interface{}) bool {
+		wp := value.(*workersPool)
+		go func() {
+			for i := uint8(0); i < p.cfg.NumPollers; i++ {
+				// stop jobs plugin pollers
+				wp.stopCh <- struct{}{}
+			}
+		}()
+		return true
+	})
 
 	// range over all consumers and call stop
 	p.consumers.Range(func(key, value interface{}) bool {
@@ -241,15 +328,25 @@ func (p *Plugin) Stop() error {
 
 	p.pipelines.Range(func(key, _ interface{}) bool {
 		p.pipelines.Delete(key)
+		p.pipelineOpts.Delete(key)
 		return true
 	})
 
+	if p.declarations != nil {
+		err := p.declarations.close()
+		if err != nil {
+			p.log.Error("failed to close the declarations storage", zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
 func (p *Plugin) Collects() []interface{} {
 	return []interface{}{
 		p.CollectMQBrokers,
+		p.CollectKV,
+		p.CollectBroadcaster,
 	}
 }
 
@@ -257,21 +354,12 @@ func (p *Plugin) CollectMQBrokers(name endure.Named, c jobs.Constructor) {
 	p.jobConstructors[name.Name()] = c
 }
 
+// Workers returns the workers of all pools, see PoolsWorkers for the workers grouped by the pool
 func (p *Plugin) Workers() []*process.State {
-	p.RLock()
-	wrk := p.workersPool.Workers()
-	p.RUnlock()
-
-	ps := make([]*process.State, len(wrk))
-
-	for i := 0; i < len(wrk); i++ {
-		st, err := process.WorkerProcessState(wrk[i])
-		if err != nil {
-			p.log.Error("jobs workers state", zap.Error(err))
-			return nil
-		}
+	ps := make([]*process.State, 0, 10)
 
-		ps[i] = st
+	for _, st := range p.PoolsWorkers() {
+		ps = append(ps, st...)
 	}
 
 	return ps
@@ -303,20 +391,86 @@ func (p *Plugin) JobsState(ctx context.Context) ([]*jobs.State, error) {
 	return jst, nil
 }
 
+// PipelineState is the state of the pipeline reported by the driver with the circuit breaker state
+type PipelineState struct {
+	Pipeline string `json:"pipeline"`
+	Driver   string `json:"driver"`
+	Queue    string `json:"queue"`
+	Active   int64  `json:"active"`
+	Delayed  int64  `json:"delayed"`
+	Reserved int64  `json:"reserved"`
+	Ready    bool   `json:"ready"`
+	// Breaker is nil if the pipeline has no circuit breaker
+	Breaker *BreakerState `json:"breaker,omitempty"`
+}
+
+// PipelinesState returns the state of the pipelines sorted by the pipeline name
+func (p *Plugin) PipelinesState(ctx context.Context) ([]*PipelineState, error) {
+	const op = errors.Op("jobs_plugin_pipelines_state")
+
+	jst, err := p.JobsState(ctx)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	res := make([]*PipelineState, 0, len(jst))
+	for i := 0; i < len(jst); i++ {
+		st := &PipelineState{
+			Pipeline: jst[i].Pipeline,
+			Driver:   jst[i].Driver,
+			Queue:    jst[i].Queue,
+			Active:   jst[i].Active,
+			Delayed:  jst[i].Delayed,
+			Reserved: jst[i].Reserved,
+			Ready:    jst[i].Ready,
+		}
+
+		if opts := p.options(jst[i].Pipeline); opts != nil && opts.breaker != nil {
+			st.Breaker = opts.breaker.getState(jst[i].Pipeline)
+		}
+
+		res = append(res, st)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Pipeline < res[j].Pipeline
+	})
+
+	return res, nil
+}
+
 func (p *Plugin) Name() string {
 	return PluginName
 }
 
 func (p *Plugin) Reset() error {
-	p.Lock()
-	defer p.Unlock()
-
 	const op = errors.Op("jobs_plugin_reset")
 	p.log.Info("reset signal was received")
-	err := p.workersPool.Reset(context.Background())
+
+	var err error
+	p.pools.Range(func(_, value interface{}) bool {
+		wp := value.(*workersPool)
+
+		wp.Lock()
+		defer wp.Unlock()
+
+		if !wp.started {
+			return true
+		}
+
+		err = wp.pool.Reset(context.Background())
+		if err != nil {
+			err = errors.E(op, errors.Errorf("pool: %s, error: %v", wp.name, err))
+			return false
+		}
+
+		p.log.Info("pool was successfully reset", zap.String("pool", wp.name))
+		return true
+	})
 	if err != nil {
-		return errors.E(op, err)
+		return err
 	}
+
 	p.log.Info("plugin was successfully reset")
 
 	return nil
@@ -325,6 +479,41 @@ func (p *Plugin) Reset() error {
 func (p *Plugin) Push(j *jobs.Job) error {
 	const op = errors.Op("jobs_plugin_push")
 
+	err := validateCodec(j)
+	if err != nil {
+		atomic.AddUint64(p.metrics.pushErr, 1)
+		return errors.E(op, err)
+	}
+
+	return p.push(j)
+}
+
+// push validates the job and pushes it to the pipeline's driver
+func (p *Plugin) push(j *jobs.Job) error {
+	const op = errors.Op("jobs_plugin_push")
+
+	err := p.validateTimeout(j)
+	if err != nil {
+		atomic.AddUint64(p.metrics.pushErr, 1)
+		return errors.E(op, err)
+	}
+
+	// the typed error is returned as is, see ValidationError
+	err = p.validatePayload(j)
+	if err != nil {
+		atomic.AddUint64(p.metrics.pushErr, 1)
+		p.log.Warn("job payload validation failed", zap.String("ID", j.Ident), zap.String("pipeline", j.Options.Pipeline), zap.Error(err))
+		return err
+	}
+
+	return p.send(j)
+}
+
+// send pushes the job to the pipeline's driver w/o the validation. The job's payload might be already encoded (CodecHeader),
+// e.g. the dead-letter job which payload could not be decoded.
+func (p *Plugin) send(j *jobs.Job) error {
+	const op = errors.Op("jobs_plugin_push")
+
 	start := time.Now()
 	// get the pipeline for the job
 	pipe, ok := p.pipelines.Load(j.Options.Pipeline)
@@ -345,17 +534,40 @@ func (p *Plugin) Push(j *jobs.Job) error {
 		j.Options.Priority = ppl.Priority()
 	}
 
+	p.withAttempt(j)
+	j.Headers = p.withPushedAt(j.Headers)
+
+	err := p.encodePayload(j)
+	if err != nil {
+		atomic.AddUint64(p.metrics.pushErr, 1)
+		return errors.E(op, err)
+	}
+
+	acquired, err := p.acquireUnique(j)
+	if err != nil {
+		atomic.AddUint64(p.metrics.pushErr, 1)
+		return errors.E(op, err)
+	}
+
+	// duplicate, skip the job
+	if !acquired {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
 	defer cancel()
 
-	err := d.(jobs.Consumer).Push(ctx, j)
+	err = d.(jobs.Consumer).Push(ctx, j)
+	p.observePush(j, ppl.Driver(), start, err)
 	if err != nil {
+		p.releaseUnique(j.Options.Pipeline, j.Headers)
 		atomic.AddUint64(p.metrics.pushErr, 1)
 		p.log.Error("job push error", zap.String("ID", j.Ident), zap.String("pipeline", ppl.Name()), zap.String("driver", ppl.Driver()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)), zap.Error(err))
 		return errors.E(op, err)
 	}
 
 	atomic.AddUint64(p.metrics.pushOk, 1)
+	p.pushedEvent(j, start)
 	p.log.Debug("job was pushed successfully", zap.String("ID", j.Ident), zap.String("pipeline", ppl.Name()), zap.String("driver", ppl.Driver()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
 
 	return nil
@@ -365,6 +577,22 @@ func (p *Plugin) PushBatch(j []*jobs.Job) error {
 	const op = errors.Op("jobs_plugin_push")
 	start := time.Now()
 
+	// the batch is rejected before any job reaches the broker
+	for i := 0; i < len(j); i++ {
+		err := validateCodec(j[i])
+		if err != nil {
+			atomic.AddUint64(p.metrics.pushErr, 1)
+			return errors.E(op, err)
+		}
+
+		err = p.validatePayload(j[i])
+		if err != nil {
+			atomic.AddUint64(p.metrics.pushErr, 1)
+			p.log.Warn("job payload validation failed", zap.String("ID", j[i].Ident), zap.String("pipeline", j[i].Options.Pipeline), zap.Error(err))
+			return err
+		}
+	}
+
 	for i := 0; i < len(j); i++ {
 		// get the pipeline for the job
 		pipe, ok := p.pipelines.Load(j[i].Options.Pipeline)
@@ -384,22 +612,69 @@ func (p *Plugin) PushBatch(j []*jobs.Job) error {
 			j[i].Options.Priority = ppl.Priority()
 		}
 
+		p.withAttempt(j[i])
+		j[i].Headers = p.withPushedAt(j[i].Headers)
+
+		err := p.validateTimeout(j[i])
+		if err != nil {
+			atomic.AddUint64(p.metrics.pushErr, 1)
+			return errors.E(op, err)
+		}
+
+		err = p.encodePayload(j[i])
+		if err != nil {
+			atomic.AddUint64(p.metrics.pushErr, 1)
+			return errors.E(op, err)
+		}
+
+		acquired, err := p.acquireUnique(j[i])
+		if err != nil {
+			atomic.AddUint64(p.metrics.pushErr, 1)
+			return errors.E(op, err)
+		}
+
+		// duplicate, skip the job
+		if !acquired {
+			continue
+		}
+
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
-		err := d.(jobs.Consumer).Push(ctx, j[i])
+		pushStart := time.Now()
+		err = d.(jobs.Consumer).Push(ctx, j[i])
+		p.observePush(j[i], ppl.Driver(), pushStart, err)
 		if err != nil {
 			cancel()
+			p.releaseUnique(j[i].Options.Pipeline, j[i].Headers)
 			atomic.AddUint64(p.metrics.pushErr, 1)
 			p.log.Error("job push batch error", zap.String("ID", j[i].Ident), zap.String("pipeline", ppl.Name()), zap.String("driver", ppl.Driver()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)), zap.Error(err))
 			return errors.E(op, err)
 		}
 
 		cancel()
+		p.pushedEvent(j[i], pushStart)
 	}
 
 	return nil
 }
 
 func (p *Plugin) Pause(pp string) {
+	// manual pause overrides the circuit breaker
+	p.resetBreaker(pp, false)
+	if p.pauseConsumer(pp) {
+		p.consumeDeclaration(pp, false)
+	}
+}
+
+func (p *Plugin) Resume(pp string) {
+	// manual resume overrides the circuit breaker
+	p.resetBreaker(pp, false)
+	if p.resumeConsumer(pp) {
+		p.consumeDeclaration(pp, true)
+	}
+}
+
+// pauseConsumer pauses the pipeline's driver, false - no such pipeline or driver
+func (p *Plugin) pauseConsumer(pp string) bool {
 	pipe, ok := p.pipelines.Load(pp)
 
 	if !ok {
@@ -408,7 +683,7 @@ func (p *Plugin) Pause(pp string) {
 
 	if pipe == nil {
 		p.log.Error("no pipe registered, value is nil")
-		return
+		return false
 	}
 
 	ppl := pipe.(*pipeline.Pipeline)
@@ -416,15 +691,17 @@ func (p *Plugin) Pause(pp string) {
 	d, ok := p.consumers.Load(ppl.Name())
 	if !ok {
 		p.log.Warn("driver for the pipeline not found", zap.String("pipeline", pp))
-		return
+		return false
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
 	defer cancel()
 	// redirect call to the underlying driver
 	d.(jobs.Consumer).Pause(ctx, ppl.Name())
+	return true
 }
 
-func (p *Plugin) Resume(pp string) {
+// resumeConsumer resumes the pipeline's driver, false - no such pipeline or driver
+func (p *Plugin) resumeConsumer(pp string) bool {
 	pipe, ok := p.pipelines.Load(pp)
 	if !ok {
 		p.log.Error("no such pipeline", zap.String("requested", pp))
@@ -432,7 +709,7 @@ func (p *Plugin) Resume(pp string) {
 
 	if pipe == nil {
 		p.log.Error("no pipe registered, value is nil")
-		return
+		return false
 	}
 
 	ppl := pipe.(*pipeline.Pipeline)
@@ -440,17 +717,32 @@ func (p *Plugin) Resume(pp string) {
 	d, ok := p.consumers.Load(ppl.Name())
 	if !ok {
 		p.log.Warn("driver for the pipeline not found", zap.String("pipeline", pp))
-		return
+		return false
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
 	defer cancel()
 	// redirect call to the underlying driver
 	d.(jobs.Consumer).Resume(ctx, ppl.Name())
+	return true
 }
 
 // Declare a pipeline.
 func (p *Plugin) Declare(pipeline *pipeline.Pipeline) error {
+	err := p.declare(pipeline)
+	if err != nil {
+		return err
+	}
+
+	// record the declaration to restore the pipeline after the restart
+	p.declared.Store(pipeline.Name(), struct{}{})
+	_, consume := p.consume[pipeline.Name()]
+	p.saveDeclaration(pipeline, consume)
+
+	return nil
+}
+
+func (p *Plugin) declare(pipeline *pipeline.Pipeline) error {
 	const op = errors.Op("jobs_plugin_declare")
 	// driver for the pipeline (ie amqp, ephemeral, etc)
 	dr := pipeline.Driver()
@@ -458,18 +750,37 @@ func (p *Plugin) Declare(pipeline *pipeline.Pipeline) error {
 		return errors.E(op, errors.Errorf("no associated driver with the pipeline, pipeline name: %s", pipeline.Name()))
 	}
 
+	opts, err := parseOptions(pipeline)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
 	// jobConstructors contains constructors for the drivers
 	// we need here to initialize these drivers for the pipelines
 	if _, ok := p.jobConstructors[dr]; ok {
+		wp, err := p.pipelinePool(pipeline.Name(), opts)
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		// dedicated pool of the declared pipeline
+		err = p.startPool(wp)
+		if err != nil {
+			p.releasePool(pipeline.Name(), opts, wp)
+			return errors.E(op, err)
+		}
+
 		// init the driver from pipeline
-		initializedDriver, err := p.jobConstructors[dr].ConsumerFromPipeline(pipeline, p.queue)
+		initializedDriver, err := p.jobConstructors[dr].ConsumerFromPipeline(pipeline, wp.pipelineQueue(opts))
 		if err != nil {
+			p.releasePool(pipeline.Name(), opts, wp)
 			return errors.E(op, err)
 		}
 
 		// register pipeline for the initialized driver
 		err = initializedDriver.Register(context.Background(), pipeline)
 		if err != nil {
+			p.releasePool(pipeline.Name(), opts, wp)
 			return errors.E(op, errors.Errorf("pipe register failed for the driver: %s with pipe name: %s", pipeline.Driver(), pipeline.Name()))
 		}
 
@@ -480,13 +791,15 @@ func (p *Plugin) Declare(pipeline *pipeline.Pipeline) error {
 			defer cancel()
 			err = initializedDriver.Run(ctx, pipeline)
 			if err != nil {
+				p.releasePool(pipeline.Name(), opts, wp)
 				return errors.E(op, err)
 			}
 		}
 
 		// add driver to the set of the consumers (name - pipeline name, value - associated driver)
 		p.consumers.Store(pipeline.Name(), initializedDriver)
-		// save the pipeline
+		// save the pipeline and its options
+		p.pipelineOpts.Store(pipeline.Name(), opts)
 		p.pipelines.Store(pipeline.Name(), pipeline)
 	}
 
@@ -516,6 +829,11 @@ func (p *Plugin) Destroy(pp string) error {
 
 	// delete old pipeline
 	p.pipelines.LoadAndDelete(pp)
+	p.resetBreaker(pp, true)
+
+	// finish the in-flight jobs and return the queued ones before stopping the driver
+	p.drain(map[string]jobs.Consumer{pp: d.(jobs.Consumer)})
+	opts, _ := p.pipelineOpts.LoadAndDelete(pp)
 
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*time.Duration(p.cfg.Timeout))
 	err := d.(jobs.Consumer).Stop(ctx)
@@ -525,6 +843,16 @@ func (p *Plugin) Destroy(pp string) error {
 	}
 
 	cancel()
+
+	// destroy the dedicated pool of the pipeline
+	if opts != nil && opts.(*pipelineOptions).PoolConfig != nil {
+		if wp, ok := p.pools.LoadAndDelete(pp); ok {
+			p.stopPool(wp.(*workersPool))
+		}
+	}
+
+	p.deleteDeclaration(pp)
+
 	return nil
 }
 
@@ -0,0 +1,233 @@
+package jobs
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	json "github.com/json-iterator/go"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/pool"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"github.com/spiral/roadrunner/v2/state/process"
+	"github.com/spiral/roadrunner/v2/utils"
+	"go.uber.org/zap"
+)
+
+const (
+	// name of the pool configured by the jobs.pool section
+	defaultPool string = "default"
+
+	// RrPool env variable contains the name of the jobs pool
+	RrPool string = "RR_JOBS_POOL"
+)
+
+// workersPool is the pool of workers with its own priority queue and pollers.
+// Drivers of the pipelines attached to the pool push jobs into the pool's queue.
+type workersPool struct {
+	// protects the pool from the concurrent reset
+	sync.RWMutex
+
+	name  string
+	cfg   *pool.Config
+	queue pq.Queue
+	pool  pool.Pool
+
+	// executions take the workers one by one, see taken
+	takeMu sync.Mutex
+
+	// signal channel to stop the pollers
+	stopCh chan struct{}
+	// pool workers were started
+	started bool
+}
+
+// addPool registers the pool, workers are allocated by the startPool
+func (p *Plugin) addPool(name string, cfg *pool.Config) *workersPool {
+	wp := &workersPool{
+		name:   name,
+		cfg:    cfg,
+		queue:  pq.NewBinHeap(p.cfg.PipelineSize),
+		stopCh: make(chan struct{}, 1),
+	}
+
+	p.pools.Store(name, wp)
+	return wp
+}
+
+// startPool allocates the pool workers and starts the pollers
+func (p *Plugin) startPool(wp *workersPool) error {
+	const op = errors.Op("jobs_plugin_start_pool")
+
+	wp.Lock()
+	if wp.started {
+		wp.Unlock()
+		return nil
+	}
+
+	var err error
+	wp.pool, err = p.server.NewWorkerPool(context.Background(), wp.cfg, map[string]string{RrMode: RrModeJobs, RrPool: wp.name})
+	if err != nil {
+		wp.Unlock()
+		return errors.E(op, errors.Errorf("pool: %s, error: %v", wp.name, err))
+	}
+
+	wp.started = true
+	wp.Unlock()
+
+	p.log.Debug("jobs pool was started", zap.String("pool", wp.name), zap.Uint64("num_workers", wp.cfg.NumWorkers))
+
+	// start listening
+	p.listener(wp)
+	return nil
+}
+
+// stopPool stops the pollers and destroys the pool workers
+func (p *Plugin) stopPool(wp *workersPool) {
+	// this function can block forever, but we don't care, because we might have a chance to exit from the pollers,
+	// but if not, this is not a problem at all.
+	go func() {
+		for i := uint8(0); i < p.cfg.NumPollers; i++ {
+			// stop jobs plugin pollers
+			wp.stopCh <- struct{}{}
+		}
+	}()
+
+	wp.Lock()
+	defer wp.Unlock()
+
+	if wp.started {
+		wp.pool.Destroy(context.Background())
+		wp.started = false
+	}
+}
+
+// releasePool destroys the dedicated pool of the pipeline which declaration failed, shared pools are kept
+func (p *Plugin) releasePool(name string, opts *pipelineOptions, wp *workersPool) {
+	if opts.PoolConfig == nil {
+		return
+	}
+
+	// the pollers are started with the workers
+	wp.RLock()
+	started := wp.started
+	wp.RUnlock()
+
+	if started {
+		p.stopPool(wp)
+	}
+
+	p.pools.Delete(name)
+}
+
+// pipelinePool returns the pool for the pipeline: dedicated (pool section in the pipeline),
+// named (pool name from the jobs.pools) or the default one
+func (p *Plugin) pipelinePool(name string, opts *pipelineOptions) (*workersPool, error) {
+	const op = errors.Op("jobs_plugin_pipeline_pool")
+
+	switch {
+	case opts.PoolConfig != nil:
+		if _, ok := p.cfg.Pools[name]; ok || name == defaultPool {
+			return nil, errors.E(op, errors.Errorf("pipeline: %s, dedicated pool name conflicts with the named pool", name))
+		}
+
+		if wp, ok := p.pools.Load(name); ok {
+			return wp.(*workersPool), nil
+		}
+
+		opts.PoolConfig.InitDefaults()
+		wp := p.addPool(name, opts.PoolConfig)
+		p.checkExecTTL(name, opts, wp)
+		return wp, nil
+	case opts.Pool != "":
+		wp, ok := p.pools.Load(opts.Pool)
+		if !ok {
+			return nil, errors.E(op, errors.Errorf("pipeline: %s, no such pool: %s", name, opts.Pool))
+		}
+
+		p.checkExecTTL(name, opts, wp.(*workersPool))
+		return wp.(*workersPool), nil
+	default:
+		wp, _ := p.pools.Load(defaultPool)
+		p.checkExecTTL(name, opts, wp.(*workersPool))
+		return wp.(*workersPool), nil
+	}
+}
+
+// PoolsWorkers returns the workers state grouped by the pool name
+func (p *Plugin) PoolsWorkers() map[string][]*process.State {
+	res := make(map[string][]*process.State)
+
+	p.pools.Range(func(key, value interface{}) bool {
+		wp := value.(*workersPool)
+
+		wp.RLock()
+		if !wp.started {
+			wp.RUnlock()
+			return true
+		}
+		wrk := wp.pool.Workers()
+		wp.RUnlock()
+
+		ps := make([]*process.State, 0, len(wrk))
+		for i := 0; i < len(wrk); i++ {
+			st, err := process.WorkerProcessState(wrk[i])
+			if err != nil {
+				p.log.Error("jobs workers state", zap.String("pool", wp.name), zap.Error(err))
+				continue
+			}
+
+			ps = append(ps, st)
+		}
+
+		res[key.(string)] = ps
+		return true
+	})
+
+	return res
+}
+
+// parsePoolOption parses the pool option of the pipeline: pool name or the pool configuration
+func parsePoolOption(opts *pipelineOptions) error {
+	if opts.PoolRaw == nil {
+		return nil
+	}
+
+	raw := opts.PoolRaw
+	if str, ok := raw.(string); ok {
+		str = strings.TrimSpace(str)
+		// name of the pool
+		if !strings.HasPrefix(str, "{") {
+			opts.Pool = str
+			return nil
+		}
+
+		// pool configuration received as a JSON object (Declare RPC)
+		m := make(map[string]interface{})
+		err := json.Unmarshal(utils.AsBytes(str), &m)
+		if err != nil {
+			return errors.Errorf("pool: %v", err)
+		}
+
+		raw = m
+	}
+
+	cfg := &pool.Config{}
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		WeaklyTypedInput: true,
+		Result:           cfg,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = dec.Decode(raw)
+	if err != nil {
+		return errors.Errorf("pool: %v", err)
+	}
+
+	opts.PoolConfig = cfg
+	return nil
+}
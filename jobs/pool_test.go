@@ -0,0 +1,84 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/roadrunner/v2/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParsePoolOption(t *testing.T) {
+	// named pool
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"pool":   "images",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "images", opts.Pool)
+	assert.Nil(t, opts.PoolConfig)
+
+	// dedicated pool, configuration file
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"config": map[string]interface{}{
+			"pool": map[string]interface{}{
+				"num_workers":      "2",
+				"max_jobs":         10,
+				"allocate_timeout": "10s",
+				"supervisor": map[string]interface{}{
+					"max_worker_memory": 100,
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "", opts.Pool)
+	require.NotNil(t, opts.PoolConfig)
+	assert.Equal(t, uint64(2), opts.PoolConfig.NumWorkers)
+	assert.Equal(t, uint64(10), opts.PoolConfig.MaxJobs)
+	assert.Equal(t, time.Second*10, opts.PoolConfig.AllocateTimeout)
+	require.NotNil(t, opts.PoolConfig.Supervisor)
+	assert.Equal(t, uint64(100), opts.PoolConfig.Supervisor.MaxWorkerMemory)
+
+	// dedicated pool, Declare RPC
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"pool":   `{"num_workers": 3, "destroy_timeout": "5s"}`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.PoolConfig)
+	assert.Equal(t, uint64(3), opts.PoolConfig.NumWorkers)
+	assert.Equal(t, time.Second*5, opts.PoolConfig.DestroyTimeout)
+
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"pool":   `{"num_workers": "foo"}`,
+	})
+	assert.Error(t, err)
+}
+
+func TestReleasePool(t *testing.T) {
+	p := &Plugin{log: zap.NewNop(), cfg: &Config{PipelineSize: 10}}
+	shared := p.addPool(defaultPool, &pool.Config{})
+
+	// shared pool is kept
+	p.releasePool("test", &pipelineOptions{}, shared)
+	_, ok := p.pools.Load(defaultPool)
+	assert.True(t, ok)
+
+	// dedicated pool of the failed declaration
+	opts := &pipelineOptions{PoolConfig: &pool.Config{NumWorkers: 1}}
+	wp, err := p.pipelinePool("test", opts)
+	require.NoError(t, err)
+	p.releasePool("test", opts, wp)
+	_, ok = p.pools.Load("test")
+	assert.False(t, ok)
+}
@@ -0,0 +1,65 @@
+package protocol
+
+import (
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/payload"
+	"go.uber.org/zap"
+)
+
+// batchResp is the outcome of the job in the Batch response
+type batchResp struct {
+	// job ID
+	ID   string          `json:"id"`
+	T    Type            `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// HandleBatch applies the worker's response to the jobs sent in one payload, keys of the jbs are the jobs IDs.
+// The Batch response contains the outcome per job, any other response type is applied to all jobs.
+// Returned map contains the jobs handled by the response with the handling errors (nil on success),
+// jobs missing in the map have no outcome in the response.
+func (rh *RespHandler) HandleBatch(pld *payload.Payload, jbs map[string]jobs.Acknowledger) (map[string]error, error) {
+	const op = errors.Op("jobs_handle_batch_response")
+	p := rh.getProtocol()
+	defer rh.putProtocol(p)
+
+	err := json.Unmarshal(pld.Body, p)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	handled := make(map[string]error, len(jbs))
+
+	if p.T != Batch {
+		for id, jb := range jbs {
+			handled[id] = rh.handle(p, jb)
+		}
+
+		return handled, nil
+	}
+
+	var outcomes []*batchResp
+	err = json.Unmarshal(p.Data, &outcomes)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	for i := 0; i < len(outcomes); i++ {
+		jb, ok := jbs[outcomes[i].ID]
+		if !ok {
+			rh.log.Warn("no such job in the batch", zap.String("ID", outcomes[i].ID))
+			continue
+		}
+
+		if _, ok := handled[outcomes[i].ID]; ok {
+			rh.log.Warn("duplicated job outcome in the batch response", zap.String("ID", outcomes[i].ID))
+			continue
+		}
+
+		handled[outcomes[i].ID] = rh.handle(&protocol{T: outcomes[i].T, Data: outcomes[i].Data}, jb)
+	}
+
+	return handled, nil
+}
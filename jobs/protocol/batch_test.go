@@ -0,0 +1,100 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testAck struct {
+	acked, nacked, requeued, responded bool
+}
+
+func (a *testAck) Ack() error {
+	a.acked = true
+	return nil
+}
+
+func (a *testAck) Nack() error {
+	a.nacked = true
+	return nil
+}
+
+func (a *testAck) Requeue(map[string][]string, int64) error {
+	a.requeued = true
+	return nil
+}
+
+func (a *testAck) Respond([]byte, string) error {
+	a.responded = true
+	return nil
+}
+
+func TestHandleBatch(t *testing.T) {
+	rh := NewResponseHandler(zap.NewNop())
+
+	acks := map[string]*testAck{"1": {}, "2": {}, "3": {}}
+	jbs := make(map[string]jobs.Acknowledger, len(acks))
+	for id, a := range acks {
+		jbs[id] = a
+	}
+
+	handled, err := rh.HandleBatch(&payload.Payload{Body: []byte(`{"type":3,"data":[
+		{"id":"1","type":0,"data":{}},
+		{"id":"2","type":1,"data":{"message":"error","requeue":true}},
+		{"id":"4","type":0,"data":{}}
+	]}`)}, jbs)
+	require.NoError(t, err)
+
+	assert.Len(t, handled, 2)
+	assert.NoError(t, handled["1"])
+	assert.NoError(t, handled["2"])
+	assert.True(t, acks["1"].acked)
+	assert.True(t, acks["2"].requeued)
+	// no outcome
+	_, ok := handled["3"]
+	assert.False(t, ok)
+	assert.False(t, acks["3"].acked)
+
+	// not a batch response is applied to all jobs
+	handled, err = rh.HandleBatch(&payload.Payload{Body: []byte(`{"type":0,"data":{}}`)}, jbs)
+	require.NoError(t, err)
+	assert.Len(t, handled, 3)
+	assert.True(t, acks["3"].acked)
+
+	_, err = rh.HandleBatch(&payload.Payload{Body: []byte(`{"type":3,"data":{}}`)}, jbs)
+	assert.Error(t, err)
+}
+
+type testRecorder struct {
+	testAck
+	data []byte
+}
+
+func (r *testRecorder) Record(data []byte) {
+	r.data = append([]byte(nil), data...)
+}
+
+func TestHandleRecord(t *testing.T) {
+	rh := NewResponseHandler(zap.NewNop())
+
+	r := &testRecorder{}
+	err := rh.Handle(&payload.Payload{Body: []byte(`{"type":0,"data":{"foo":"bar"}}`)}, r)
+	require.NoError(t, err)
+	assert.True(t, r.acked)
+	assert.Equal(t, `{"foo":"bar"}`, string(r.data))
+
+	// batch outcome data
+	r1, r2 := &testRecorder{}, &testRecorder{}
+	_, err = rh.HandleBatch(&payload.Payload{Body: []byte(`{"type":3,"data":[
+		{"id":"1","type":0,"data":{"foo":"bar"}},
+		{"id":"2","type":0}
+	]}`)}, map[string]jobs.Acknowledger{"1": r1, "2": r2})
+	require.NoError(t, err)
+	assert.Equal(t, `{"foo":"bar"}`, string(r1.data))
+	assert.Empty(t, r2.data)
+}
@@ -18,6 +18,21 @@ func (rh *RespHandler) handleErrResp(data []byte, jb jobs.Acknowledger) error {
 
 	rh.log.Error("jobs protocol error", zap.Error(errors.E(er.Msg)), zap.Int64("delay", er.Delay), zap.Bool("requeue", er.Requeue))
 
+	// let the job decide (retry policy, dead-letter pipeline) if supported
+	if f, ok := jb.(Failer); ok {
+		errF := f.Fail(er.Msg, er.Requeue, er.Headers, er.Delay)
+		if errF != nil {
+			// requeue errors are reported to the caller
+			if er.Requeue {
+				return errF
+			}
+			rh.log.Error("job dead-letter was failed", zap.Error(errors.E(er.Msg)), zap.Error(errF))
+			// do not return any error
+		}
+
+		return nil
+	}
+
 	// requeue the job
 	if er.Requeue {
 		err = jb.Requeue(er.Headers, er.Delay)
@@ -27,7 +42,7 @@ func (rh *RespHandler) handleErrResp(data []byte, jb jobs.Acknowledger) error {
 		return nil
 	}
 
-	// user don't want to requeue the job - silently ACK and return nil
+	// silently ACK and return nil
 	errAck := jb.Ack()
 	if errAck != nil {
 		rh.log.Error("job acknowledge was failed", zap.Error(errors.E(er.Msg)), zap.Error(errAck))
@@ -16,6 +16,10 @@ const (
 	NoError Type = iota
 	Error
 	Response
+	// Batch contains the outcomes of the jobs sent to the worker in one payload
+	Batch
+	// Heartbeat extends the lease of the job in progress, sent by the worker mid-execution via the jobs.Heartbeat RPC
+	Heartbeat
 )
 
 // internal worker protocol (jobs mode)
@@ -68,30 +72,49 @@ func (rh *RespHandler) Handle(pld *payload.Payload, jb jobs.Acknowledger) error
 		return errors.E(op, err)
 	}
 
+	return rh.handle(p, jb)
+}
+
+// handle applies the worker's response to the job
+func (rh *RespHandler) handle(p *protocol, jb jobs.Acknowledger) error {
+	const op = errors.Op("jobs_handle_response")
+
+	if r, ok := jb.(Recorder); ok && len(p.Data) > 0 {
+		r.Record(p.Data)
+	}
+
 	switch p.T {
 	// likely case
 	case NoError:
-		err = jb.Ack()
+		err := jb.Ack()
 		if err != nil {
 			return errors.E(op, err)
 		}
 		return nil
 		// error returned from the PHP
 	case Error:
-		err = rh.handleErrResp(p.Data, jb)
+		err := rh.handleErrResp(p.Data, jb)
+		if err != nil {
+			return errors.E(op, err)
+		}
+		return nil
+		// heartbeat is not a final response, the job is failed
+	case Heartbeat:
+		rh.log.Warn("heartbeat message should be sent via the jobs.Heartbeat RPC, job is failed")
+		err := failHeartbeat(jb)
 		if err != nil {
 			return errors.E(op, err)
 		}
 		return nil
 		// RR should send a response to the queue/tube/subject
 	case Response:
-		err = rh.handleQueueResp(p.Data, jb)
+		err := rh.handleQueueResp(p.Data, jb)
 		if err != nil {
 			return err
 		}
 		return nil
 	default:
-		err = jb.Ack()
+		err := jb.Ack()
 		if err != nil {
 			return errors.E(op, err)
 		}
@@ -128,5 +151,8 @@ func (rh *RespHandler) getQResp() *queueResp {
 
 func (rh *RespHandler) putQResp(p *queueResp) {
 	p.Queue = ""
+	p.Payload = ""
+	p.Jobs = nil
+	p.Group = nil
 	rh.qPool.Put(p)
 }
@@ -0,0 +1,61 @@
+package protocol
+
+import (
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+)
+
+// heartbeatResp is the failure reason of the job which worker returned the heartbeat as the final response
+const heartbeatResp string = "heartbeat is not a final response"
+
+// HeartbeatMessage is the data of the Heartbeat message
+type HeartbeatMessage struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline"`
+	// Extend the job's lease by the number of seconds from now, 0 - do not extend
+	Extend int64 `json:"extend_seconds"`
+	// Progress of the job, optional
+	Progress *float64 `json:"progress,omitempty"`
+}
+
+// ParseHeartbeat parses the Heartbeat message: {"type": 4, "data": {"id": "...", "pipeline": "...", ...}}
+func ParseHeartbeat(data []byte) (*HeartbeatMessage, error) {
+	const op = errors.Op("jobs_parse_heartbeat")
+
+	p := &protocol{}
+	err := json.Unmarshal(data, p)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if p.T != Heartbeat {
+		return nil, errors.E(op, errors.Errorf("not a heartbeat message, type: %d", p.T))
+	}
+
+	hb := &HeartbeatMessage{}
+	err = json.Unmarshal(p.Data, hb)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if hb.ID == "" {
+		return nil, errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	if hb.Extend < 0 {
+		return nil, errors.E(op, errors.Errorf("lease extension should not be negative, provided: %d", hb.Extend))
+	}
+
+	return hb, nil
+}
+
+// failHeartbeat fails the job which worker returned the heartbeat as the final response: the job is requeued,
+// or retried according to the pipeline's retry policy if supported
+func failHeartbeat(jb jobs.Acknowledger) error {
+	if f, ok := jb.(Failer); ok {
+		return f.Fail(heartbeatResp, true, nil, 0)
+	}
+
+	return jb.Requeue(nil, 0)
+}
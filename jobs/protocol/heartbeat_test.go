@@ -0,0 +1,61 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestParseHeartbeat(t *testing.T) {
+	hb, err := ParseHeartbeat([]byte(`{"type":4,"data":{"id":"1","pipeline":"test","extend_seconds":60,"progress":42.5}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "1", hb.ID)
+	assert.Equal(t, "test", hb.Pipeline)
+	assert.Equal(t, int64(60), hb.Extend)
+	require.NotNil(t, hb.Progress)
+	assert.Equal(t, 42.5, *hb.Progress)
+
+	hb, err = ParseHeartbeat([]byte(`{"type":4,"data":{"id":"1","extend_seconds":60}}`))
+	require.NoError(t, err)
+	assert.Nil(t, hb.Progress)
+
+	_, err = ParseHeartbeat([]byte(`{"type":0,"data":{"id":"1"}}`))
+	assert.Error(t, err)
+
+	_, err = ParseHeartbeat([]byte(`{"type":4,"data":{"extend_seconds":60}}`))
+	assert.Error(t, err)
+
+	_, err = ParseHeartbeat([]byte(`{"type":4,"data":{"id":"1","extend_seconds":-1}}`))
+	assert.Error(t, err)
+}
+
+type testFailer struct {
+	testAck
+	reason  string
+	requeue bool
+}
+
+func (f *testFailer) Fail(reason string, requeue bool, _ map[string][]string, _ int64) error {
+	f.reason = reason
+	f.requeue = requeue
+	return nil
+}
+
+func TestHandleHeartbeat(t *testing.T) {
+	rh := NewResponseHandler(zap.NewNop())
+	pld := &payload.Payload{Body: []byte(`{"type":4,"data":{"id":"1","extend_seconds":60}}`)}
+
+	a := &testAck{}
+	require.NoError(t, rh.Handle(pld, a))
+	assert.False(t, a.acked)
+	assert.True(t, a.requeued)
+
+	f := &testFailer{}
+	require.NoError(t, rh.Handle(pld, f))
+	assert.False(t, f.acked)
+	assert.Equal(t, heartbeatResp, f.reason)
+	assert.True(t, f.requeue)
+}
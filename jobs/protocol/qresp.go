@@ -3,10 +3,11 @@ package protocol
 import (
 	json "github.com/json-iterator/go"
 	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
 	"github.com/spiral/roadrunner/v2/utils"
 )
 
-// data - data to redirect to the queue
+// data - data to redirect to the queue and the follow-up jobs
 func (rh *RespHandler) handleQueueResp(data []byte, jb jobs.Acknowledger) error {
 	qs := rh.getQResp()
 	defer rh.putQResp(qs)
@@ -16,6 +17,23 @@ func (rh *RespHandler) handleQueueResp(data []byte, jb jobs.Acknowledger) error
 		return err
 	}
 
+	if len(qs.Jobs) > 0 || qs.Group != nil {
+		c, ok := jb.(Continuer)
+		if !ok {
+			return errors.Str("follow-up jobs are not supported for the job")
+		}
+
+		err = c.Continue(qs.Jobs, qs.Group)
+		if err != nil {
+			return err
+		}
+
+		// nothing to redirect
+		if qs.Queue == "" {
+			return jb.Ack()
+		}
+	}
+
 	err = jb.Respond(utils.AsBytes(qs.Payload), qs.Queue)
 	if err != nil {
 		return err
@@ -0,0 +1,64 @@
+package protocol
+
+import (
+	"testing"
+
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testContinuer struct {
+	testAck
+	jobs  []*FollowUp
+	group *Group
+}
+
+func (c *testContinuer) Continue(jobs []*FollowUp, group *Group) error {
+	c.jobs = jobs
+	c.group = group
+	return nil
+}
+
+func TestHandleFollowUps(t *testing.T) {
+	rh := NewResponseHandler(zap.NewNop())
+
+	c := &testContinuer{}
+	err := rh.Handle(&payload.Payload{Body: []byte(`{"type":2,"data":{
+		"jobs":[
+			{"job":"resize","payload":"{\"size\":1}","headers":{"a":["b"]},"delay":10,"priority":5},
+			{"id":"2","job":"resize","pipeline":"images"}
+		],
+		"group":{"name":"album-1","callback":{"job":"notify"}}
+	}}`)}, c)
+	require.NoError(t, err)
+
+	require.Len(t, c.jobs, 2)
+	assert.Equal(t, "resize", c.jobs[0].Job)
+	assert.Equal(t, `{"size":1}`, c.jobs[0].Payload)
+	assert.Equal(t, int64(10), c.jobs[0].Delay)
+	assert.Equal(t, int64(5), c.jobs[0].Priority)
+	assert.Equal(t, []string{"b"}, c.jobs[0].Headers["a"])
+	assert.Equal(t, "images", c.jobs[1].Pipeline)
+	require.NotNil(t, c.group)
+	assert.Equal(t, "album-1", c.group.Name)
+	assert.Equal(t, "notify", c.group.Callback.Job)
+	// no queue to redirect
+	assert.True(t, c.acked)
+	assert.False(t, c.responded)
+
+	// pooled response is reset
+	c = &testContinuer{}
+	err = rh.Handle(&payload.Payload{Body: []byte(`{"type":2,"data":{"queue":"q","payload":"p"}}`)}, c)
+	require.NoError(t, err)
+	assert.Nil(t, c.jobs)
+	assert.True(t, c.responded)
+	assert.False(t, c.acked)
+
+	// follow-ups are not supported
+	a := &testAck{}
+	err = rh.Handle(&payload.Payload{Body: []byte(`{"type":2,"data":{"jobs":[{"job":"resize"}]}}`)}, a)
+	assert.Error(t, err)
+	assert.False(t, a.acked)
+}
@@ -1,5 +1,20 @@
 package protocol
 
+// Failer is an optional interface of the job which handles failures according to the pipeline's
+// retry policy and routes failed jobs into the dead-letter pipeline
+type Failer interface {
+	// Fail requeues the job or moves it to the dead-letter pipeline.
+	// requeue, headers and delay are the values requested by the worker.
+	Fail(reason string, requeue bool, headers map[string][]string, delay int64) error
+}
+
+// Recorder is an optional interface of the job which saves the data of the worker's response,
+// e.g. to store it as the job's result
+type Recorder interface {
+	// Record saves the response data (w/o the protocol envelope), data might be reused after the call
+	Record(data []byte)
+}
+
 type errorResp struct {
 	Msg     string              `json:"message"`
 	Requeue bool                `json:"requeue"`
@@ -7,7 +22,37 @@ type errorResp struct {
 	Headers map[string][]string `json:"headers"`
 }
 
+// Continuer is an optional interface of the job which pushes the follow-up jobs returned by the worker
+type Continuer interface {
+	// Continue pushes the follow-up jobs before the job is acknowledged.
+	// Jobs are pushed as the members of the group if the group is not nil.
+	Continue(jobs []*FollowUp, group *Group) error
+}
+
+// FollowUp is the job returned by the worker in the Response message
+type FollowUp struct {
+	// ID of the job, generated if empty
+	ID  string `json:"id"`
+	Job string `json:"job"`
+	// Pipeline of the job, default - pipeline of the processed job
+	Pipeline string              `json:"pipeline"`
+	Payload  string              `json:"payload"`
+	Headers  map[string][]string `json:"headers"`
+	Delay    int64               `json:"delay"`
+	Priority int64               `json:"priority"`
+}
+
+// Group of the follow-up jobs, the callback job is pushed once all jobs of the group are finished
+type Group struct {
+	// Name of the group, should be unique while the group is in progress
+	Name     string    `json:"name"`
+	Callback *FollowUp `json:"callback"`
+}
+
 type queueResp struct {
 	Queue   string `json:"queue"`
 	Payload string `json:"payload"`
+	// Jobs are the follow-up jobs
+	Jobs  []*FollowUp `json:"jobs"`
+	Group *Group      `json:"group"`
 }
@@ -0,0 +1,191 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	json "github.com/json-iterator/go"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// final statuses of the job
+	StatusOk     string = "ok"
+	StatusFailed string = "failed"
+	StatusDead   string = "dead"
+
+	// prefix of the result keys in the kv storage
+	resultPrefix string = "rr_result"
+
+	// results polling interval of the WaitResult
+	resultPollInterval = time.Millisecond * 100
+)
+
+// resultsOptions configures the storage of the job results
+type resultsOptions struct {
+	// Storage is the name of the kv storage (key in the kv section)
+	Storage string `mapstructure:"storage"`
+	// TTL of the result, default - 1h
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+func (r *resultsOptions) InitDefaults() error {
+	if r.Storage == "" {
+		return errors.Str("results storage should not be empty")
+	}
+
+	if r.TTL == 0 {
+		r.TTL = time.Hour
+	}
+
+	return nil
+}
+
+// Result is the outcome of the job stored in the results storage
+type Result struct {
+	ID       string `json:"id"`
+	Job      string `json:"job"`
+	Pipeline string `json:"pipeline"`
+	// Status is one of: ok, failed, dead (moved to the dead-letter pipeline)
+	Status string `json:"status"`
+	// Response is the data of the worker's response (w/o the protocol envelope)
+	Response string `json:"response,omitempty"`
+	// Error is the failure reason
+	Error    string    `json:"error,omitempty"`
+	Attempts int       `json:"attempts"`
+	Started  time.Time `json:"started_at"`
+	Finished time.Time `json:"finished_at"`
+	// Elapsed processing time in milliseconds
+	Elapsed int64 `json:"elapsed_ms"`
+}
+
+// ResultRequest is the GetResult/WaitResult RPC request
+type ResultRequest struct {
+	ID       string `json:"id"`
+	Pipeline string `json:"pipeline"`
+	// Timeout of the WaitResult in seconds, default - Config.Timeout
+	Timeout int64 `json:"timeout"`
+}
+
+func resultKey(pipe, id string) string {
+	return resultPrefix + ":" + pipe + ":" + id
+}
+
+// storeResult saves the result of the job if the pipeline has the results storage
+func (p *Plugin) storeResult(it *item, status string) {
+	opts := p.options(it.ctx.Pipeline)
+	if opts == nil || opts.Results == nil {
+		return
+	}
+
+	finished := time.Now()
+	res := &Result{
+		ID:       it.ID(),
+		Job:      it.ctx.Job,
+		Pipeline: it.ctx.Pipeline,
+		Status:   status,
+		Response: string(it.response),
+		Error:    it.reason,
+		Attempts: it.attempt(),
+		Started:  it.start,
+		Finished: finished,
+		Elapsed:  finished.Sub(it.start).Milliseconds(),
+	}
+
+	data, err := json.Marshal(res)
+	if err != nil {
+		p.log.Error("failed to marshal the job result", zap.String("ID", it.ID()), zap.Error(err))
+		return
+	}
+
+	st, err := p.storage(opts.Results.Storage)
+	if err != nil {
+		p.log.Error("failed to store the job result", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.Error(err))
+		return
+	}
+
+	err = st.Set(&kvv1.Item{
+		Key:     resultKey(it.ctx.Pipeline, it.ID()),
+		Value:   data,
+		Timeout: finished.Add(opts.Results.TTL).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		p.log.Error("failed to store the job result", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.Error(err))
+	}
+}
+
+// GetResult returns the stored result of the job, nil result means that the job is not finished yet (or the result expired)
+func (p *Plugin) GetResult(pipe, id string) (*Result, error) {
+	const op = errors.Op("jobs_plugin_get_result")
+
+	opts := p.options(pipe)
+	if opts == nil {
+		return nil, errors.E(op, errors.Errorf("no such pipeline: %s", pipe))
+	}
+
+	if opts.Results == nil {
+		return nil, errors.E(op, errors.Errorf("results storage is not configured for the pipeline: %s", pipe))
+	}
+
+	st, err := p.storage(opts.Results.Storage)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	key := resultKey(pipe, id)
+	data, err := st.MGet(key)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if _, ok := data[key]; !ok {
+		return nil, nil
+	}
+
+	res := &Result{}
+	err = json.Unmarshal(data[key], res)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return res, nil
+}
+
+// WaitResult waits for the result of the job until the context is done
+func (p *Plugin) WaitResult(ctx context.Context, pipe, id string) (*Result, error) {
+	const op = errors.Op("jobs_plugin_wait_result")
+
+	ticker := time.NewTicker(resultPollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := p.GetResult(pipe, id)
+		if err != nil {
+			return nil, err
+		}
+
+		if res != nil {
+			return res, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, errors.E(op, errors.TimeOut, errors.Errorf("job result wait timeout, ID: %s", id))
+		case <-ticker.C:
+		}
+	}
+}
+
+// Record saves the data of the worker's response to be stored as the job result
+func (i *item) Record(data []byte) {
+	opts := i.p.options(i.ctx.Pipeline)
+	if opts == nil || opts.Results == nil || len(data) == 0 {
+		return
+	}
+
+	// payload might be reused by the pool
+	i.response = make([]byte, len(data))
+	copy(i.response, data)
+}
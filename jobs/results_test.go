@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestResults(t *testing.T) {
+	st := &testStorage{data: make(map[string][]byte)}
+	p := testInspectPlugin()
+	p.kvProvider = testStorageProvider{"test-kv": st}
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":    "test",
+		"driver":  "memory",
+		"results": `{"storage": "test-kv", "ttl": "10m"}`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.Results)
+	assert.Equal(t, time.Minute*10, opts.Results.TTL)
+	p.pipelineOpts.Store("test", opts)
+
+	res, err := p.GetResult("test", "1")
+	require.NoError(t, err)
+	assert.Nil(t, res)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*300)
+	defer cancel()
+	_, err = p.WaitResult(ctx, "test", "1")
+	assert.Error(t, err)
+
+	it := &item{
+		Item: &testPQItem{id: "1"},
+		ctx: &jobContext{
+			ID:       "1",
+			Job:      "test-job",
+			Pipeline: "test",
+			Headers:  map[string][]string{attemptHeader: {"2"}},
+		},
+		ack:   testAcknowledger{},
+		p:     p,
+		start: time.Now(),
+	}
+	// the result contains the response data w/o the protocol envelope
+	err = protocol.NewResponseHandler(zap.NewNop()).Handle(&payload.Payload{Body: []byte(`{"type":0,"data":{"foo":"bar"}}`)}, it)
+	require.NoError(t, err)
+
+	res, err = p.WaitResult(context.Background(), "test", "1")
+	require.NoError(t, err)
+	assert.Equal(t, "1", res.ID)
+	assert.Equal(t, "test-job", res.Job)
+	assert.Equal(t, StatusOk, res.Status)
+	assert.Equal(t, `{"foo":"bar"}`, res.Response)
+	assert.Equal(t, 2, res.Attempts)
+
+	// not configured
+	_, err = p.GetResult("test-2", "1")
+	assert.Error(t, err)
+}
+
+type testPQItem struct {
+	id   string
+	body []byte
+}
+
+func (i *testPQItem) ID() string {
+	return i.id
+}
+
+func (i *testPQItem) Priority() int64 {
+	return 10
+}
+
+func (i *testPQItem) Body() []byte {
+	return i.body
+}
+
+func (i *testPQItem) Context() ([]byte, error) {
+	return nil, nil
+}
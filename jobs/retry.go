@@ -0,0 +1,111 @@
+package jobs
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+)
+
+const (
+	backoffFixed       string = "fixed"
+	backoffExponential string = "exponential"
+)
+
+// retryPolicy describes how the jobs plugin retries failed jobs
+type retryPolicy struct {
+	// MaxAttempts is the total number of attempts (including the first one), default - 3
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff strategy: fixed or exponential, default - exponential
+	Backoff string `mapstructure:"backoff"`
+	// Initial delay before the first retry, default - 1s
+	Initial time.Duration `mapstructure:"initial"`
+	// Max is the upper bound of the delay, default - 10m
+	Max time.Duration `mapstructure:"max"`
+	// Jitter is the randomization factor (0..1) applied to the delay
+	Jitter float64 `mapstructure:"jitter"`
+}
+
+func (r *retryPolicy) InitDefaults() error {
+	if r.MaxAttempts == 0 {
+		r.MaxAttempts = 3
+	}
+
+	if r.Backoff == "" {
+		r.Backoff = backoffExponential
+	}
+
+	if r.Initial == 0 {
+		r.Initial = time.Second
+	}
+
+	if r.Max == 0 {
+		r.Max = time.Minute * 10
+	}
+
+	switch r.Backoff {
+	case backoffFixed, backoffExponential:
+	default:
+		return errors.Errorf("unknown retry backoff: %s, should be one of: fixed, exponential", r.Backoff)
+	}
+
+	if r.MaxAttempts < 0 {
+		return errors.Errorf("retry max_attempts should be positive, provided: %d", r.MaxAttempts)
+	}
+
+	if r.Jitter < 0 || r.Jitter > 1 {
+		return errors.Errorf("retry jitter should be in the range [0, 1], provided: %f", r.Jitter)
+	}
+
+	return nil
+}
+
+// exhausted reports whether the failed attempt was the last one
+func (r *retryPolicy) exhausted(attempt int) bool {
+	return attempt >= r.MaxAttempts
+}
+
+// delay returns the delay in seconds before the next attempt after the failed one.
+// Delays are rounded up to seconds, because drivers accept delays in seconds.
+func (r *retryPolicy) delay(attempt int) int64 {
+	d := r.Initial
+
+	if r.Backoff == backoffExponential && attempt > 1 {
+		f := float64(r.Initial) * math.Pow(2, float64(attempt-1))
+		if f > float64(r.Max) {
+			f = float64(r.Max)
+		}
+		d = time.Duration(f)
+	}
+
+	if r.Jitter > 0 {
+		delta := r.Jitter * float64(d)
+		// random value in the [d - delta, d + delta] range
+		d = time.Duration(float64(d) - delta + rand.Float64()*2*delta) //nolint:gosec
+	}
+
+	if d > r.Max {
+		d = r.Max
+	}
+
+	return int64(math.Ceil(d.Seconds()))
+}
+
+// withAttempt sets the first attempt header for the jobs pushed into the pipeline with the retry policy,
+// so the worker sees the attempt number on the first try
+func (p *Plugin) withAttempt(j *jobs.Job) {
+	opts := p.options(j.Options.Pipeline)
+	if opts == nil || opts.Retry == nil {
+		return
+	}
+
+	if j.Headers == nil {
+		j.Headers = make(map[string][]string, 1)
+	}
+
+	if _, ok := j.Headers[attemptHeader]; !ok {
+		j.Headers[attemptHeader] = []string{"1"}
+	}
+}
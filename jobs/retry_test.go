@@ -0,0 +1,151 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	r := &retryPolicy{
+		Initial: time.Second * 2,
+		Max:     time.Second * 10,
+	}
+	require.NoError(t, r.InitDefaults())
+
+	assert.Equal(t, int64(2), r.delay(1))
+	assert.Equal(t, int64(4), r.delay(2))
+	assert.Equal(t, int64(8), r.delay(3))
+	assert.Equal(t, int64(10), r.delay(4))
+	assert.Equal(t, int64(10), r.delay(100))
+
+	r.Backoff = backoffFixed
+	assert.Equal(t, int64(2), r.delay(1))
+	assert.Equal(t, int64(2), r.delay(5))
+
+	// sub-second delays are rounded up
+	r.Initial = time.Millisecond * 100
+	assert.Equal(t, int64(1), r.delay(1))
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	r := &retryPolicy{
+		Backoff: backoffFixed,
+		Initial: time.Second * 10,
+		Max:     time.Minute,
+		Jitter:  0.5,
+	}
+	require.NoError(t, r.InitDefaults())
+
+	for i := 0; i < 100; i++ {
+		d := r.delay(1)
+		assert.GreaterOrEqual(t, d, int64(5))
+		assert.LessOrEqual(t, d, int64(15))
+	}
+}
+
+func TestRetryPolicyExhausted(t *testing.T) {
+	r := &retryPolicy{}
+	require.NoError(t, r.InitDefaults())
+
+	assert.False(t, r.exhausted(1))
+	assert.False(t, r.exhausted(2))
+	assert.True(t, r.exhausted(3))
+}
+
+func TestRetryPolicyErrors(t *testing.T) {
+	assert.Error(t, (&retryPolicy{Backoff: "linear"}).InitDefaults())
+	assert.Error(t, (&retryPolicy{Jitter: 2}).InitDefaults())
+	assert.Error(t, (&retryPolicy{MaxAttempts: -1}).InitDefaults())
+}
+
+func TestParseOptions(t *testing.T) {
+	// configuration file
+	pipe := pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"config": map[string]interface{}{
+			"dead_letter": "test-dl",
+			"retry": map[string]interface{}{
+				"max_attempts": "5",
+				"backoff":      "fixed",
+				"initial":      "3s",
+			},
+		},
+	}
+
+	opts, err := parseOptions(&pipe)
+	require.NoError(t, err)
+	assert.Equal(t, "test-dl", opts.DeadLetter)
+	require.NotNil(t, opts.Retry)
+	assert.Equal(t, 5, opts.Retry.MaxAttempts)
+	assert.Equal(t, backoffFixed, opts.Retry.Backoff)
+	assert.Equal(t, time.Second*3, opts.Retry.Initial)
+	assert.Equal(t, time.Minute*10, opts.Retry.Max)
+
+	// Declare RPC
+	pipe = pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"retry":  `{"max_attempts": 2, "jitter": 0.1}`,
+	}
+
+	opts, err = parseOptions(&pipe)
+	require.NoError(t, err)
+	assert.Equal(t, "", opts.DeadLetter)
+	require.NotNil(t, opts.Retry)
+	assert.Equal(t, 2, opts.Retry.MaxAttempts)
+	assert.Equal(t, backoffExponential, opts.Retry.Backoff)
+	assert.Equal(t, 0.1, opts.Retry.Jitter)
+
+	// no options
+	pipe = pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+	}
+
+	opts, err = parseOptions(&pipe)
+	require.NoError(t, err)
+	assert.Nil(t, opts.Retry)
+
+	pipe["retry"] = `{"backoff": "linear"}`
+	_, err = parseOptions(&pipe)
+	assert.Error(t, err)
+}
+
+func TestRequeueHeaders(t *testing.T) {
+	p := testInspectPlugin()
+	ack := &testRequeueAck{}
+	it := &item{
+		Item: &testPQItem{id: "1"},
+		ack:  ack,
+		ctx: &jobContext{ID: "1", Pipeline: "test", Headers: map[string][]string{
+			CodecHeader:   {"base64"},
+			UniqueKey:     {"key"},
+			GroupHeader:   {"album"},
+			JobTimeout:    {"10"},
+			attemptHeader: {"1"},
+			"foo":         {"bar"},
+		}},
+		p: p,
+	}
+
+	// the worker's headers are merged on top of the job's ones
+	require.NoError(t, it.Requeue(map[string][]string{"foo": {"baz"}, "new": {"1"}}, 5))
+	assert.Equal(t, int64(5), ack.delay)
+	assert.Equal(t, []string{"base64"}, ack.headers[CodecHeader])
+	assert.Equal(t, []string{"key"}, ack.headers[UniqueKey])
+	assert.Equal(t, []string{"album"}, ack.headers[GroupHeader])
+	assert.Equal(t, []string{"10"}, ack.headers[JobTimeout])
+	assert.Equal(t, []string{"baz"}, ack.headers["foo"])
+	assert.Equal(t, []string{"1"}, ack.headers["new"])
+	assert.Equal(t, []string{"2"}, ack.headers[attemptHeader])
+	// the job's headers are not changed
+	assert.Equal(t, []string{"bar"}, it.ctx.Headers["foo"])
+
+	require.NoError(t, it.Requeue(nil, 0))
+	assert.Equal(t, []string{"bar"}, ack.headers["foo"])
+}
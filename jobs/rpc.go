@@ -2,11 +2,13 @@ package jobs
 
 import (
 	"context"
+	"time"
 
 	"github.com/roadrunner-server/api/v2/plugins/jobs"
 	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1beta"
 	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
 )
 
 type rpc struct {
@@ -25,6 +27,10 @@ func (r *rpc) Push(j *jobsv1beta.PushRequest, _ *jobsv1beta.Empty) error {
 
 	err := r.p.Push(from(j.GetJob()))
 	if err != nil {
+		// keep the message of the validation error stable for the clients
+		if verr, ok := err.(*ValidationError); ok {
+			return verr
+		}
 		return errors.E(op, err)
 	}
 
@@ -45,10 +51,59 @@ func (r *rpc) PushBatch(j *jobsv1beta.PushBatchRequest, _ *jobsv1beta.Empty) err
 	}
 
 	err := r.p.PushBatch(batch)
+	if err != nil {
+		if verr, ok := err.(*ValidationError); ok {
+			return verr
+		}
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// GetResult returns the result of the finished job, the pipeline should have the results storage
+func (r *rpc) GetResult(req *ResultRequest, resp *Result) error {
+	const op = errors.Op("rpc_get_result")
+
+	if req.ID == "" {
+		return errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	res, err := r.p.GetResult(req.Pipeline, req.ID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if res == nil {
+		return errors.E(op, errors.Errorf("no result for the job, ID: %s", req.ID))
+	}
+
+	*resp = *res
+	return nil
+}
+
+// WaitResult waits for the result of the job with the timeout
+func (r *rpc) WaitResult(req *ResultRequest, resp *Result) error {
+	const op = errors.Op("rpc_wait_result")
+
+	if req.ID == "" {
+		return errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	timeout := time.Second * time.Duration(req.Timeout)
+	if req.Timeout <= 0 {
+		timeout = time.Second * time.Duration(r.p.cfg.Timeout)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	res, err := r.p.WaitResult(ctx, req.Pipeline, req.ID)
 	if err != nil {
 		return errors.E(op, err)
 	}
 
+	*resp = *res
 	return nil
 }
 
@@ -94,6 +149,140 @@ func (r *rpc) Declare(req *jobsv1beta.DeclareRequest, _ *jobsv1beta.Empty) error
 	return nil
 }
 
+// Export returns the effective set of the pipelines as the jobs configuration YAML
+func (r *rpc) Export(_ bool, out *string) error {
+	const op = errors.Op("rpc_export")
+
+	data, err := r.p.Export()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*out = string(data)
+	return nil
+}
+
+// States returns the state of the pipelines with the circuit breakers state, the protobuf jobs.Stat can't carry it
+func (r *rpc) States(_ bool, resp *[]*PipelineState) error {
+	const op = errors.Op("rpc_states")
+
+	states, err := r.p.PipelinesState(context.Background())
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*resp = states
+	return nil
+}
+
+// Peek returns the jobs waiting in the pipeline's queue
+func (r *rpc) Peek(req *PeekRequest, resp *[]*jobs.Job) error {
+	const op = errors.Op("rpc_peek")
+
+	jbs, err := r.p.Peek(req.Pipeline, req.Limit, req.Offset)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*resp = jbs
+	return nil
+}
+
+// Delete removes the job waiting in the pipeline's queue, false - no such job
+func (r *rpc) Delete(req *DeleteRequest, deleted *bool) error {
+	const op = errors.Op("rpc_delete")
+
+	if req.ID == "" {
+		return errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	ok, err := r.p.Delete(req.Pipeline, req.ID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*deleted = ok
+	return nil
+}
+
+// Purge removes all jobs waiting in the pipeline's queue and returns their number
+func (r *rpc) Purge(pipeline string, deleted *int64) error {
+	const op = errors.Op("rpc_purge")
+
+	n, err := r.p.Purge(pipeline)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*deleted = n
+	return nil
+}
+
+// Move moves the waiting jobs matching the filter to another pipeline and returns their number
+func (r *rpc) Move(req *MoveRequest, moved *int64) error {
+	const op = errors.Op("rpc_move")
+
+	n, err := r.p.Move(req.From, req.To, req.Filter)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*moved = n
+	return nil
+}
+
+// Cancel cancels the delayed job before its delay expires, false - no such job
+func (r *rpc) Cancel(req *CancelRequest, canceled *bool) error {
+	const op = errors.Op("rpc_cancel")
+
+	if req.ID == "" {
+		return errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	ok, err := r.p.Cancel(req.Pipeline, req.ID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*canceled = ok
+	return nil
+}
+
+// Heartbeat receives the protocol Heartbeat message of the job in progress from the worker
+func (r *rpc) Heartbeat(msg []byte, ok *bool) error {
+	const op = errors.Op("rpc_heartbeat")
+
+	hb, err := protocol.ParseHeartbeat(msg)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = r.p.Heartbeat(hb)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*ok = true
+	return nil
+}
+
+// Progress returns the progress of the job in progress
+func (r *rpc) Progress(req *ProgressRequest, resp *Progress) error {
+	const op = errors.Op("rpc_progress")
+
+	if req.ID == "" {
+		return errors.E(op, errors.Str("empty ID field not allowed"))
+	}
+
+	res, err := r.p.Progress(req.Pipeline, req.ID)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	*resp = *res
+	return nil
+}
+
 func (r *rpc) Destroy(req *jobsv1beta.Pipelines, resp *jobsv1beta.Pipelines) error {
 	const op = errors.Op("rpc_declare_pipeline")
 
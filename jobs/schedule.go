@@ -0,0 +1,374 @@
+package jobs
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/kv"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// default key of the schedule leader lock in the kv storage
+	scheduleLockKey string = "rr_jobs_schedule_leader"
+)
+
+// ScheduledJob is the job pushed by the jobs plugin according to the cron expression
+type ScheduledJob struct {
+	// Cron expression with the seconds resolution, e.g. `*/10 * * * * *` (every 10 seconds)
+	Cron string `mapstructure:"cron"`
+	// Pipeline to push the job to
+	Pipeline string `mapstructure:"pipeline"`
+	// Job name, default - the name of the schedule entry
+	Job string `mapstructure:"job"`
+	// Payload of the job
+	Payload string `mapstructure:"payload"`
+	// Headers of the job
+	Headers map[string][]string `mapstructure:"headers"`
+}
+
+// ScheduleLock configures the leader lock shared by several RR instances,
+// only the leader pushes the scheduled jobs
+type ScheduleLock struct {
+	// Storage is the name of the kv storage (key in the kv section)
+	Storage string `mapstructure:"storage"`
+	// Key of the lock in the storage, default - rr_jobs_schedule_leader
+	Key string `mapstructure:"key"`
+	// TTL of the lock, the leader renews the lock every TTL/3, default - 30s
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+func (l *ScheduleLock) InitDefaults() {
+	if l.Key == "" {
+		l.Key = scheduleLockKey
+	}
+
+	// kv storages accept TTL in seconds
+	if l.TTL < time.Second*3 {
+		l.TTL = time.Second * 30
+	}
+}
+
+type scheduleEntry struct {
+	name  string
+	job   *ScheduledJob
+	sched *cronSchedule
+}
+
+// scheduler pushes the scheduled jobs using the jobs plugin
+type scheduler struct {
+	p       *Plugin
+	log     *zap.Logger
+	entries []*scheduleEntry
+	lock    *leaderLock
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+func newScheduler(p *Plugin, log *zap.Logger, cfg map[string]*ScheduledJob, lockCfg *ScheduleLock) (*scheduler, error) {
+	const op = errors.Op("jobs_new_scheduler")
+
+	s := &scheduler{
+		p:       p,
+		log:     log,
+		entries: make([]*scheduleEntry, 0, len(cfg)),
+		stopCh:  make(chan struct{}),
+	}
+
+	for name, job := range cfg {
+		if job == nil {
+			continue
+		}
+
+		if job.Pipeline == "" {
+			return nil, errors.E(op, errors.Errorf("schedule: %s, pipeline should not be empty", name))
+		}
+
+		if job.Job == "" {
+			job.Job = name
+		}
+
+		sched, err := parseCron(job.Cron)
+		if err != nil {
+			return nil, errors.E(op, errors.Errorf("schedule: %s, error: %v", name, err))
+		}
+
+		s.entries = append(s.entries, &scheduleEntry{
+			name:  name,
+			job:   job,
+			sched: sched,
+		})
+	}
+
+	if lockCfg != nil {
+		if lockCfg.Storage == "" {
+			return nil, errors.E(op, errors.Str("schedule lock storage should not be empty"))
+		}
+
+		lockCfg.InitDefaults()
+		s.lock = &leaderLock{
+			p:   p,
+			log: log,
+			cfg: lockCfg,
+			id:  uuid.NewString(),
+		}
+	}
+
+	return s, nil
+}
+
+func (s *scheduler) start() {
+	if s.lock != nil {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.lock.keep(s.stopCh)
+		}()
+	}
+
+	for i := 0; i < len(s.entries); i++ {
+		s.wg.Add(1)
+		go func(e *scheduleEntry) {
+			defer s.wg.Done()
+			s.run(e)
+		}(s.entries[i])
+	}
+}
+
+func (s *scheduler) stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+
+	if s.lock != nil {
+		s.lock.release()
+	}
+}
+
+func (s *scheduler) run(e *scheduleEntry) {
+	for {
+		now := time.Now()
+		next := e.sched.next(now)
+		if next.IsZero() {
+			s.log.Warn("schedule has no next activation time and will be stopped", zap.String("schedule", e.name), zap.String("cron", e.job.Cron))
+			return
+		}
+
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-s.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if s.lock != nil && !s.lock.isLeader() {
+				s.log.Debug("not a schedule leader, skipping", zap.String("schedule", e.name))
+				continue
+			}
+
+			// two instances might consider themselves leaders during the failover
+			if s.lock != nil && !s.lock.claim(e.name, next) {
+				s.log.Debug("schedule activation was claimed by another instance, skipping", zap.String("schedule", e.name), zap.Time("activation", next))
+				continue
+			}
+
+			s.push(e)
+		}
+	}
+}
+
+func (s *scheduler) push(e *scheduleEntry) {
+	headers := make(map[string][]string, len(e.job.Headers))
+	for k, v := range e.job.Headers {
+		headers[k] = v
+	}
+
+	j := &jobs.Job{
+		Job:     e.job.Job,
+		Ident:   uuid.NewString(),
+		Payload: e.job.Payload,
+		Headers: headers,
+		Options: &jobs.Options{
+			Pipeline: e.job.Pipeline,
+		},
+	}
+
+	err := s.p.Push(j)
+	if err != nil {
+		s.log.Error("scheduled job push failed", zap.String("schedule", e.name), zap.String("pipeline", e.job.Pipeline), zap.Error(err))
+		return
+	}
+
+	s.log.Debug("scheduled job was pushed", zap.String("schedule", e.name), zap.String("ID", j.Ident), zap.String("pipeline", e.job.Pipeline))
+}
+
+// leaderLock is a best-effort lock in the kv storage, the kv storages do not provide
+// an atomic set-if-not-exists operation, so the lock is verified after every write.
+// Every activation is additionally claimed with its own key, see claim.
+type leaderLock struct {
+	p   *Plugin
+	log *zap.Logger
+	cfg *ScheduleLock
+	// id of the RR instance
+	id      string
+	leader  uint32
+	storage kv.Storage
+}
+
+func (l *leaderLock) isLeader() bool {
+	return atomic.LoadUint32(&l.leader) == 1
+}
+
+func (l *leaderLock) keep(stopCh chan struct{}) {
+	l.tryAcquire()
+
+	ticker := time.NewTicker(l.cfg.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			l.tryAcquire()
+		}
+	}
+}
+
+func (l *leaderLock) tryAcquire() {
+	acquired, err := l.acquire()
+	if err != nil {
+		l.log.Error("schedule leader lock error", zap.String("storage", l.cfg.Storage), zap.Error(err))
+	}
+
+	switch {
+	case acquired && !l.isLeader():
+		l.log.Info("schedule leader lock acquired", zap.String("ID", l.id))
+		atomic.StoreUint32(&l.leader, 1)
+	case !acquired && l.isLeader():
+		l.log.Warn("schedule leader lock lost", zap.String("ID", l.id))
+		atomic.StoreUint32(&l.leader, 0)
+	}
+}
+
+// acquire acquires or renews the lock
+func (l *leaderLock) acquire() (bool, error) {
+	// storage might not be ready on the jobs plugin start
+	if l.storage == nil {
+		st, err := l.p.storage(l.cfg.Storage)
+		if err != nil {
+			return false, err
+		}
+
+		l.storage = st
+	}
+
+	owner, err := l.owner()
+	if err != nil {
+		return false, err
+	}
+
+	if owner != "" && owner != l.id {
+		return false, nil
+	}
+
+	err = l.storage.Set(&kvv1.Item{
+		Key:     l.cfg.Key,
+		Value:   []byte(l.id),
+		Timeout: time.Now().Add(l.cfg.TTL).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// another instance might write the lock at the same time
+	owner, err = l.owner()
+	if err != nil {
+		return false, err
+	}
+
+	return owner == l.id, nil
+}
+
+func (l *leaderLock) owner() (string, error) {
+	return l.get(l.cfg.Key)
+}
+
+// claim marks the schedule activation as pushed by the instance. Returns false if the activation
+// is already claimed by another instance. Storage errors do not block the push, the leader lock is still held.
+func (l *leaderLock) claim(name string, activation time.Time) bool {
+	key := l.cfg.Key + ":" + name + ":" + strconv.FormatInt(activation.Unix(), 10)
+
+	claimed, err := l.claimKey(key)
+	if err != nil {
+		l.log.Error("schedule activation claim error", zap.String("storage", l.cfg.Storage), zap.String("key", key), zap.Error(err))
+		return true
+	}
+
+	return claimed
+}
+
+func (l *leaderLock) claimKey(key string) (bool, error) {
+	if l.storage == nil {
+		return false, errors.Str("schedule lock storage is not ready")
+	}
+
+	owner, err := l.get(key)
+	if err != nil {
+		return false, err
+	}
+
+	if owner != "" {
+		return owner == l.id, nil
+	}
+
+	err = l.storage.Set(&kvv1.Item{
+		Key:     key,
+		Value:   []byte(l.id),
+		Timeout: time.Now().Add(l.cfg.TTL).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	owner, err = l.get(key)
+	if err != nil {
+		return false, err
+	}
+
+	return owner == l.id, nil
+}
+
+func (l *leaderLock) get(key string) (string, error) {
+	res, err := l.storage.MGet(key)
+	if err != nil {
+		return "", err
+	}
+
+	return string(res[key]), nil
+}
+
+// release deletes the lock if the instance is the leader, so another instance can take over immediately
+func (l *leaderLock) release() {
+	if !l.isLeader() || l.storage == nil {
+		return
+	}
+
+	atomic.StoreUint32(&l.leader, 0)
+
+	owner, err := l.owner()
+	if err != nil || owner != l.id {
+		return
+	}
+
+	err = l.storage.Delete(l.cfg.Key)
+	if err != nil {
+		l.log.Error("schedule leader lock release", zap.String("storage", l.cfg.Storage), zap.Error(err))
+	}
+}
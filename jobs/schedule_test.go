@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestScheduleClaim(t *testing.T) {
+	st := &testStorage{data: make(map[string][]byte)}
+	cfg := &ScheduleLock{Storage: "test-kv"}
+	cfg.InitDefaults()
+
+	l1 := &leaderLock{log: zap.NewNop(), cfg: cfg, id: "1", storage: st}
+	l2 := &leaderLock{log: zap.NewNop(), cfg: cfg, id: "2", storage: st}
+
+	activation := time.Unix(1_600_000_000, 0)
+
+	assert.True(t, l1.claim("report", activation))
+	// the same instance might re-check its claim
+	assert.True(t, l1.claim("report", activation))
+	assert.False(t, l2.claim("report", activation))
+	assert.Equal(t, []byte("1"), st.data[scheduleLockKey+":report:1600000000"])
+
+	// the next activation and other schedules are claimed separately
+	assert.True(t, l2.claim("report", activation.Add(time.Minute)))
+	assert.True(t, l2.claim("cleanup", activation))
+	assert.False(t, l1.claim("cleanup", activation))
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+)
+
+// ValidationError is returned by Push and PushBatch when the job's payload doesn't match the JSON schema of the job
+type ValidationError struct {
+	Pipeline string
+	Job      string
+	ID       string
+	// Violations are the schema violations, location of the value in the payload and the message
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("job payload validation failed, pipeline: %s, job: %s, ID: %s, violations: [%s]", e.Pipeline, e.Job, e.ID, strings.Join(e.Violations, "; "))
+}
+
+// compileSchemas compiles the JSON schema files of the jobs, keys are the jobs names
+func compileSchemas(files map[string]string) (map[string]*jsonschema.Schema, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	compiler := jsonschema.NewCompiler()
+	schemas := make(map[string]*jsonschema.Schema, len(files))
+	for job, file := range files {
+		if file == "" {
+			return nil, errors.Errorf("empty schema file for the job: %s", job)
+		}
+
+		s, err := compiler.Compile(file)
+		if err != nil {
+			return nil, errors.Errorf("job: %s, schema: %s, error: %v", job, file, err)
+		}
+
+		schemas[job] = s
+	}
+
+	return schemas, nil
+}
+
+// validatePayload validates the job's payload against the JSON schema of the job, jobs without a schema are not validated
+func (p *Plugin) validatePayload(j *jobs.Job) error {
+	opts := p.options(j.Options.Pipeline)
+	if opts == nil || opts.schemas == nil {
+		return nil
+	}
+
+	s, ok := opts.schemas[j.Job]
+	if !ok {
+		return nil
+	}
+
+	verr := &ValidationError{
+		Pipeline: j.Options.Pipeline,
+		Job:      j.Job,
+		ID:       j.Ident,
+	}
+
+	// numbers precision is preserved for the validation
+	dec := stdjson.NewDecoder(bytes.NewReader(utils.AsBytes(j.Payload)))
+	dec.UseNumber()
+
+	var v interface{}
+	err := dec.Decode(&v)
+	if err != nil {
+		verr.Violations = []string{fmt.Sprintf("payload is not a valid JSON: %v", err)}
+		return verr
+	}
+
+	// the payload should contain exactly one JSON value
+	if _, err = dec.Token(); err != io.EOF {
+		verr.Violations = []string{"payload is not a valid JSON: unexpected data after the JSON value"}
+		return verr
+	}
+
+	err = s.Validate(v)
+	if err == nil {
+		return nil
+	}
+
+	ve, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		verr.Violations = []string{err.Error()}
+		return verr
+	}
+
+	verr.Violations = violations(ve, nil)
+	return verr
+}
+
+// violations collects the leaf errors of the schema validation
+func violations(ve *jsonschema.ValidationError, res []string) []string {
+	if len(ve.Causes) == 0 {
+		loc := ve.InstanceLocation
+		if loc == "" {
+			loc = "/"
+		}
+
+		return append(res, fmt.Sprintf("%s: %s", loc, ve.Message))
+	}
+
+	for i := 0; i < len(ve.Causes); i++ {
+		res = violations(ve.Causes[i], res)
+	}
+
+	return res
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `{
+	"type": "object",
+	"properties": {
+		"email": {"type": "string"},
+		"amount": {"type": "integer", "minimum": 1}
+	},
+	"required": ["email"]
+}`
+
+func testSchemaPlugin(t *testing.T) *Plugin {
+	file := filepath.Join(t.TempDir(), "email.json")
+	require.NoError(t, os.WriteFile(file, []byte(testSchema), 0600))
+
+	p := testInspectPlugin()
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", &testConsumer{})
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		// Declare RPC
+		"schemas": `{"email.send": "` + file + `"}`,
+	})
+	require.NoError(t, err)
+	require.Len(t, opts.schemas, 1)
+	p.pipelineOpts.Store("test", opts)
+
+	return p
+}
+
+func TestSchemaOptions(t *testing.T) {
+	_, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"schemas": map[string]interface{}{
+			"email.send": filepath.Join(t.TempDir(), "missing.json"),
+		},
+	})
+	assert.Error(t, err)
+
+	file := filepath.Join(t.TempDir(), "broken.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"type": 1}`), 0600))
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"schemas": map[string]interface{}{
+			"email.send": file,
+		},
+	})
+	assert.Error(t, err)
+}
+
+func TestValidatePayload(t *testing.T) {
+	p := testSchemaPlugin(t)
+
+	job := func(name, payload string) *jobs.Job {
+		return &jobs.Job{
+			Job:     name,
+			Ident:   "1",
+			Payload: payload,
+			Options: &jobs.Options{Pipeline: "test"},
+		}
+	}
+
+	assert.NoError(t, p.validatePayload(job("email.send", `{"email": "a@b.c", "amount": 10}`)))
+	// no schema for the job
+	assert.NoError(t, p.validatePayload(job("other", `not a json`)))
+
+	err := p.validatePayload(job("email.send", `{"amount": 0}`))
+	require.Error(t, err)
+	verr, ok := err.(*ValidationError)
+	require.True(t, ok)
+	assert.Equal(t, "test", verr.Pipeline)
+	assert.Equal(t, "email.send", verr.Job)
+	assert.Len(t, verr.Violations, 2)
+
+	err = p.validatePayload(job("email.send", `{"email": `))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payload is not a valid JSON")
+
+	// trailing data after the valid JSON
+	err = p.validatePayload(job("email.send", `{"email": "a@b.c"} {}`))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "payload is not a valid JSON")
+	assert.Error(t, p.validatePayload(job("email.send", `{"email": "a@b.c"}}`)))
+	assert.NoError(t, p.validatePayload(job("email.send", "{\"email\": \"a@b.c\"}\n")))
+
+	// the job is rejected before the push
+	err = p.Push(job("email.send", `[]`))
+	_, ok = err.(*ValidationError)
+	assert.True(t, ok)
+
+	err = p.PushBatch([]*jobs.Job{job("email.send", `{"email": "a@b.c"}`), job("email.send", `{}`)})
+	_, ok = err.(*ValidationError)
+	assert.True(t, ok)
+
+	assert.NoError(t, p.Push(job("email.send", `{"email": "a@b.c"}`)))
+}
+
+type testFinalAck struct {
+	testAcknowledger
+	acked, nacked bool
+}
+
+func (a *testFinalAck) Ack() error {
+	a.acked = true
+	return nil
+}
+
+func (a *testFinalAck) Nack() error {
+	a.nacked = true
+	return nil
+}
+
+type testFailedConsumer struct {
+	testConsumer
+}
+
+func (c *testFailedConsumer) Push(context.Context, *jobs.Job) error {
+	return errors.Str("push failed")
+}
+
+func TestDeadLetterValidation(t *testing.T) {
+	p := testSchemaPlugin(t)
+	dl := &testInspector{}
+	p.consumers.Store("test", dl)
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":        "source",
+		"driver":      "memory",
+		"dead_letter": "test",
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("source", opts)
+
+	newItem := func(ack jobs.Acknowledger) *item {
+		return &item{
+			Item: &testPQItem{id: "1", body: []byte(`{"amount": 0}`)},
+			ack:  ack,
+			ctx: &jobContext{
+				ID:       "1",
+				Job:      "email.send",
+				Pipeline: "source",
+			},
+			p: p,
+		}
+	}
+
+	// the invalid job is kept by the dead-letter pipeline
+	ack := &testFinalAck{}
+	require.NoError(t, newItem(ack).DeadLetter("invalid payload"))
+	assert.True(t, ack.acked)
+	require.Len(t, dl.jobs, 1)
+	assert.Equal(t, `{"amount": 0}`, dl.jobs[0].Payload)
+	assert.Equal(t, []string{"invalid payload"}, dl.jobs[0].Headers[DeadLetterReason])
+
+	// the job is negatively acknowledged if the dead-letter push failed
+	p.consumers.Store("test", &testFailedConsumer{})
+	ack = &testFinalAck{}
+	require.NoError(t, newItem(ack).DeadLetter("invalid payload"))
+	assert.False(t, ack.acked)
+	assert.True(t, ack.nacked)
+}
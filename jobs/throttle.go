@@ -0,0 +1,120 @@
+package jobs
+
+import (
+	"math"
+	"time"
+
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+// delay of the job returned to the driver when the pipeline's concurrency cap is reached
+const throttleDelay = time.Second
+
+// rateLimit limits the number of jobs per second handed to the workers
+type rateLimit struct {
+	// PerSecond is the number of jobs per second, might be fractional (0.5 - one job per 2 seconds)
+	PerSecond float64 `mapstructure:"per_second"`
+	// Burst is the max number of jobs handed at once, default - per_second rounded up
+	Burst int `mapstructure:"burst"`
+}
+
+func (r *rateLimit) InitDefaults() error {
+	if r.PerSecond <= 0 {
+		return errors.Errorf("rate_limit per_second should be positive, provided: %f", r.PerSecond)
+	}
+
+	if r.Burst == 0 {
+		r.Burst = int(math.Ceil(r.PerSecond))
+	}
+
+	if r.Burst < 0 {
+		return errors.Errorf("rate_limit burst should be positive, provided: %d", r.Burst)
+	}
+
+	return nil
+}
+
+// throttle enforces the pipeline's rate limit and concurrency cap.
+// Pollers never wait for the throttle: the job above the limits is returned to the driver with the delay, see throttleJob.
+type throttle struct {
+	limiter *rate.Limiter
+	// semaphore, nil if there is no concurrency cap
+	sem chan struct{}
+}
+
+func newThrottle(rl *rateLimit, maxConcurrency int) *throttle {
+	if rl == nil && maxConcurrency == 0 {
+		return nil
+	}
+
+	t := &throttle{}
+	if rl != nil {
+		t.limiter = rate.NewLimiter(rate.Limit(rl.PerSecond), rl.Burst)
+	}
+
+	if maxConcurrency > 0 {
+		t.sem = make(chan struct{}, maxConcurrency)
+	}
+
+	return t
+}
+
+// tryAcquire takes the concurrency slot and the rate limit token without waiting.
+// Returns false and the delay after which the job might be allowed when the limits are reached.
+func (t *throttle) tryAcquire() (time.Duration, bool) {
+	if t.sem != nil {
+		select {
+		case t.sem <- struct{}{}:
+		default:
+			return throttleDelay, false
+		}
+	}
+
+	if t.limiter != nil {
+		r := t.limiter.Reserve()
+		// not possible for the burst less than 1, see rateLimit.InitDefaults
+		if !r.OK() {
+			t.release()
+			return throttleDelay, false
+		}
+
+		if d := r.Delay(); d > 0 {
+			// the token is returned to the bucket
+			r.Cancel()
+			t.release()
+			return d, false
+		}
+	}
+
+	return 0, true
+}
+
+// release frees the concurrency slot
+func (t *throttle) release() {
+	if t.sem != nil {
+		<-t.sem
+	}
+}
+
+// throttleJob returns the job above the pipeline's rate limit or concurrency cap to the driver with the delay,
+// the attempt is not counted
+func (p *Plugin) throttleJob(it *item, delay time.Duration) {
+	// the probe is taken by the next delivered job
+	p.breakerReturn(it)
+
+	// drivers delay the jobs by seconds
+	sec := int64(math.Ceil(delay.Seconds()))
+	if sec < 1 {
+		sec = 1
+	}
+
+	err := it.ack.Requeue(it.ctx.Headers, sec)
+	if err != nil {
+		p.log.Error("failed to return the throttled job to the driver", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.Error(err))
+		return
+	}
+
+	p.log.Debug("throttled job was returned to the driver", zap.String("ID", it.ID()), zap.String("pipeline", it.ctx.Pipeline), zap.Int64("delay", sec))
+}
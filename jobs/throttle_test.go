@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestThrottleOptions(t *testing.T) {
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":            "test",
+		"driver":          "memory",
+		"max_concurrency": "2",
+		"config": map[string]interface{}{
+			"rate_limit": map[string]interface{}{
+				"per_second": 0.5,
+			},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.RateLimit)
+	assert.Equal(t, 0.5, opts.RateLimit.PerSecond)
+	assert.Equal(t, 1, opts.RateLimit.Burst)
+	assert.Equal(t, 2, opts.MaxConcurrency)
+	require.NotNil(t, opts.throttle)
+
+	// NATS driver's rate_limit option
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "nats",
+		"config": map[string]interface{}{
+			"rate_limit": 100,
+		},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, opts.RateLimit)
+	assert.Nil(t, opts.throttle)
+
+	// Declare RPC
+	opts, err = parseOptions(&pipeline.Pipeline{
+		"name":       "test",
+		"driver":     "nats",
+		"rate_limit": `{"per_second": 10, "burst": 5}`,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, opts.RateLimit)
+	assert.Equal(t, 5, opts.RateLimit.Burst)
+
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":       "test",
+		"driver":     "memory",
+		"rate_limit": `{"burst": 5}`,
+	})
+	assert.Error(t, err)
+}
+
+func TestThrottleConcurrency(t *testing.T) {
+	th := newThrottle(nil, 2)
+
+	_, ok := th.tryAcquire()
+	require.True(t, ok)
+	_, ok = th.tryAcquire()
+	require.True(t, ok)
+
+	// the cap is reached, the poller is not blocked
+	delay, ok := th.tryAcquire()
+	assert.False(t, ok)
+	assert.Equal(t, throttleDelay, delay)
+
+	th.release()
+	_, ok = th.tryAcquire()
+	assert.True(t, ok)
+}
+
+func TestThrottleRate(t *testing.T) {
+	th := newThrottle(&rateLimit{PerSecond: 20, Burst: 1}, 1)
+
+	_, ok := th.tryAcquire()
+	require.True(t, ok)
+	th.release()
+
+	// the next token in 50ms, the slot is released
+	delay, ok := th.tryAcquire()
+	assert.False(t, ok)
+	assert.Greater(t, delay, time.Duration(0))
+	assert.LessOrEqual(t, delay, time.Millisecond*50)
+
+	time.Sleep(delay)
+	_, ok = th.tryAcquire()
+	assert.True(t, ok)
+}
+
+// testRequeueAck records the requeued headers and delay
+type testRequeueAck struct {
+	testAcknowledger
+	headers map[string][]string
+	delay   int64
+}
+
+func (a *testRequeueAck) Requeue(headers map[string][]string, delay int64) error {
+	a.headers = headers
+	a.delay = delay
+	return nil
+}
+
+func TestThrottleJob(t *testing.T) {
+	p := &Plugin{log: zap.NewNop()}
+	ack := &testRequeueAck{}
+	it := &item{
+		Item: &testPQItem{id: "1"},
+		ack:  ack,
+		ctx:  &jobContext{ID: "1", Pipeline: "test", Headers: map[string][]string{attemptHeader: {"2"}}},
+		p:    p,
+	}
+
+	p.throttleJob(it, time.Millisecond*50)
+	assert.Equal(t, int64(1), ack.delay)
+	// the attempt is not counted
+	assert.Equal(t, []string{"2"}, ack.headers[attemptHeader])
+
+	p.throttleJob(it, time.Millisecond*1500)
+	assert.Equal(t, int64(2), ack.delay)
+}
@@ -0,0 +1,211 @@
+package jobs
+
+import (
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/spiral/roadrunner/v2/worker"
+	"go.uber.org/zap"
+)
+
+// JobTimeout header overrides the pipeline's job_timeout (in seconds)
+const JobTimeout string = "rr_job_timeout"
+
+const (
+	// number of the immediate checks of the worker taken by the execution
+	takeSpins int = 64
+	// interval of the checks when there are no free workers
+	takePollInterval = time.Millisecond
+)
+
+// validateTimeout checks the JobTimeout header of the pushed job, the timeout can't exceed the exec_ttl of the pipeline's pool
+func (p *Plugin) validateTimeout(j *jobs.Job) error {
+	h, ok := j.Headers[JobTimeout]
+	if !ok || len(h) == 0 {
+		return nil
+	}
+
+	sec, err := strconv.ParseInt(h[0], 10, 64)
+	if err != nil || sec <= 0 {
+		return errors.Errorf("job timeout should be a positive number of seconds, provided: %s", h[0])
+	}
+
+	if ttl := p.execTTL(j.Options.Pipeline); ttl > 0 && time.Second*time.Duration(sec) > ttl {
+		return errors.Errorf("job timeout should not exceed the pool's exec_ttl: %s, provided: %ss", ttl, h[0])
+	}
+
+	return nil
+}
+
+// execTTL returns the supervisor.exec_ttl of the pipeline's pool, 0 - not configured
+func (p *Plugin) execTTL(pipe string) time.Duration {
+	name := defaultPool
+	if opts := p.options(pipe); opts != nil {
+		switch {
+		case opts.PoolConfig != nil:
+			name = pipe
+		case opts.Pool != "":
+			name = opts.Pool
+		}
+	}
+
+	wp, ok := p.pools.Load(name)
+	if !ok {
+		return 0
+	}
+
+	cfg := wp.(*workersPool).cfg
+	if cfg == nil || cfg.Supervisor == nil {
+		return 0
+	}
+
+	return cfg.Supervisor.ExecTTL
+}
+
+// timeout returns the execution timeout of the job, 0 - no timeout
+func (i *item) timeout() time.Duration {
+	if h, ok := i.ctx.Headers[JobTimeout]; ok && len(h) > 0 {
+		sec, err := strconv.ParseInt(h[0], 10, 64)
+		if err == nil && sec > 0 {
+			return time.Second * time.Duration(sec)
+		}
+	}
+
+	opts := i.p.options(i.ctx.Pipeline)
+	if opts == nil {
+		return 0
+	}
+
+	return opts.JobTimeout
+}
+
+type execResult struct {
+	rsp *payload.Payload
+	err error
+}
+
+// exec executes the job in the pool's worker. When the worker does not respond within the lease (nil - no timeout),
+// the worker executing the job is killed (the pool replaces it) and the job fails with the errors.TimeOut error.
+// The error is returned once the killed execution is finished, so the job is never executed twice at the same time.
+func (p *Plugin) exec(wp *workersPool, body, ctx []byte, l *lease) (*payload.Payload, error) {
+	const op = errors.Op("jobs_plugin_exec")
+
+	// get payload from the sync.Pool
+	pld := p.getPayload(body, ctx)
+	defer p.putPayload(pld)
+
+	// protect from the pool reset
+	wp.RLock()
+	defer wp.RUnlock()
+
+	resCh := make(chan execResult, 1)
+	done := make(chan struct{})
+
+	wp.takeMu.Lock()
+	started := time.Now()
+
+	go func() {
+		rsp, err := wp.pool.Exec(pld)
+		resCh <- execResult{rsp: rsp, err: err}
+		close(done)
+	}()
+
+	w := wp.taken(started, done)
+	wp.takeMu.Unlock()
+
+	if l == nil {
+		res := <-resCh
+		return res.rsp, timeoutErr(op, res.err)
+	}
+
+	timer := time.NewTimer(l.remaining())
+	defer timer.Stop()
+
+	for {
+		select {
+		case res := <-resCh:
+			return res.rsp, timeoutErr(op, res.err)
+		case <-timer.C:
+			// the lease was extended by the heartbeat
+			if rem := l.remaining(); rem > 0 {
+				timer.Reset(rem)
+				continue
+			}
+
+			if w != nil && w.State().Value() == worker.StateWorking && w.State().LastUsed() >= uint64(started.UnixNano()) {
+				// the pool replaces the killed worker
+				w.State().Set(worker.StateInvalid)
+				err := w.Kill()
+				p.log.Warn("worker of the timed out job was killed", zap.Int64("pid", w.Pid()), zap.String("pool", wp.name), zap.Error(err))
+			}
+
+			// the timed out job can't be requeued while it is executed
+			<-done
+			return nil, errors.E(op, errors.TimeOut, errors.Errorf("job timeout exceeded: %s", time.Since(started).Round(time.Millisecond)))
+		}
+	}
+}
+
+// taken returns the worker which took the execution started at the since time, nil - the execution is finished.
+// The executions of the pool take the workers one by one (takeMu), so only the worker taken by this execution
+// is working with the last used time after the since.
+func (wp *workersPool) taken(since time.Time, done chan struct{}) worker.BaseProcess {
+	// debug mode allocates the worker per execution, such workers are not in the pool
+	if wp.cfg.Debug {
+		return nil
+	}
+
+	ts := uint64(since.UnixNano())
+	for i := 0; ; i++ {
+		workers := wp.pool.Workers()
+		for j := 0; j < len(workers); j++ {
+			st := workers[j].State()
+			if st.Value() == worker.StateWorking && st.LastUsed() >= ts {
+				return workers[j]
+			}
+		}
+
+		if i < takeSpins {
+			select {
+			case <-done:
+				return nil
+			default:
+				runtime.Gosched()
+			}
+
+			continue
+		}
+
+		// all workers are busy, wait for the free one
+		select {
+		case <-done:
+			return nil
+		case <-time.After(takePollInterval):
+		}
+	}
+}
+
+// timeoutErr converts the exec_ttl error of the pool (the worker was killed) to the job timeout error
+func timeoutErr(op errors.Op, err error) error {
+	if err == nil || !errors.Is(errors.ExecTTL, err) {
+		return err
+	}
+
+	return errors.E(op, errors.TimeOut, err)
+}
+
+// checkExecTTL warns when the pipeline's job_timeout exceeds the exec_ttl of its pool,
+// the pool kills the worker by the exec_ttl before the job times out
+func (p *Plugin) checkExecTTL(name string, opts *pipelineOptions, wp *workersPool) {
+	if opts.JobTimeout == 0 || wp.cfg.Supervisor == nil || wp.cfg.Supervisor.ExecTTL == 0 {
+		return
+	}
+
+	if opts.JobTimeout > wp.cfg.Supervisor.ExecTTL {
+		p.log.Warn("job_timeout exceeds the pool's supervisor.exec_ttl, the worker is killed by the exec_ttl", zap.String("pipeline", name), zap.String("pool", wp.name), zap.Duration("job_timeout", opts.JobTimeout), zap.Duration("exec_ttl", wp.cfg.Supervisor.ExecTTL))
+	}
+}
@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/payload"
+	"github.com/spiral/roadrunner/v2/pool"
+	"github.com/spiral/roadrunner/v2/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestJobTimeout(t *testing.T) {
+	p := &Plugin{log: zap.NewNop()}
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "beanstalk",
+		"config": map[string]interface{}{
+			// driver's option
+			"timeout":     "10s",
+			"job_timeout": "2s",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, time.Second*2, opts.JobTimeout)
+	p.pipelineOpts.Store("test", opts)
+
+	it := &item{
+		ctx: &jobContext{Pipeline: "test", Headers: map[string][]string{}},
+		p:   p,
+	}
+	assert.Equal(t, time.Second*2, it.timeout())
+
+	// job's header overrides the pipeline's default
+	it.ctx.Headers[JobTimeout] = []string{"1200"}
+	assert.Equal(t, time.Minute*20, it.timeout())
+
+	it.ctx.Pipeline = "test-2"
+	it.ctx.Headers = nil
+	assert.Equal(t, time.Duration(0), it.timeout())
+
+	_, err = parseOptions(&pipeline.Pipeline{
+		"name":        "test",
+		"driver":      "memory",
+		"job_timeout": "-1s",
+	})
+	assert.Error(t, err)
+}
+
+func TestValidateTimeout(t *testing.T) {
+	p := &Plugin{log: zap.NewNop(), cfg: &Config{PipelineSize: 10}}
+	p.addPool(defaultPool, &pool.Config{Supervisor: &pool.SupervisorConfig{ExecTTL: time.Minute}})
+
+	job := func(timeout string) *jobs.Job {
+		return &jobs.Job{Headers: map[string][]string{JobTimeout: {timeout}}, Options: &jobs.Options{Pipeline: "test"}}
+	}
+
+	assert.NoError(t, p.validateTimeout(&jobs.Job{Options: &jobs.Options{Pipeline: "test"}}))
+	assert.NoError(t, p.validateTimeout(job("10")))
+	assert.NoError(t, p.validateTimeout(job("60")))
+	assert.Error(t, p.validateTimeout(job("0")))
+	assert.Error(t, p.validateTimeout(job("10s")))
+	// the pool kills the worker by the exec_ttl
+	assert.Error(t, p.validateTimeout(job("61")))
+
+	// dedicated pool without the exec_ttl
+	p.pipelineOpts.Store("test", &pipelineOptions{PoolConfig: &pool.Config{}})
+	p.addPool("test", &pool.Config{})
+	assert.NoError(t, p.validateTimeout(job("61")))
+}
+
+type testWorker struct {
+	worker.BaseProcess
+	pid    int64
+	state  *worker.StateImpl
+	killed chan struct{}
+}
+
+func (w *testWorker) Pid() int64 {
+	return w.pid
+}
+
+func (w *testWorker) State() worker.State {
+	return w.state
+}
+
+func (w *testWorker) Kill() error {
+	close(w.killed)
+	return nil
+}
+
+// testPool executes the job on the first ready worker, the payload body is the job duration
+type testPool struct {
+	pool.Pool
+	sync.Mutex
+	workers []*testWorker
+}
+
+func newTestPool(num int) *testPool {
+	tp := &testPool{}
+	for i := 0; i < num; i++ {
+		tp.workers = append(tp.workers, &testWorker{pid: int64(i + 1), state: worker.NewWorkerState(worker.StateReady), killed: make(chan struct{})})
+	}
+
+	return tp
+}
+
+func (tp *testPool) Workers() []worker.BaseProcess {
+	tp.Lock()
+	defer tp.Unlock()
+
+	res := make([]worker.BaseProcess, 0, len(tp.workers))
+	for i := 0; i < len(tp.workers); i++ {
+		res = append(res, tp.workers[i])
+	}
+
+	return res
+}
+
+func (tp *testPool) Exec(pld *payload.Payload) (*payload.Payload, error) {
+	tp.Lock()
+	var w *testWorker
+	for i := 0; i < len(tp.workers); i++ {
+		if tp.workers[i].state.Value() == worker.StateReady {
+			w = tp.workers[i]
+			break
+		}
+	}
+	w.state.SetLastUsed(uint64(time.Now().UnixNano()))
+	w.state.Set(worker.StateWorking)
+	tp.Unlock()
+
+	d, _ := time.ParseDuration(string(pld.Body))
+	select {
+	case <-time.After(d):
+		w.state.Set(worker.StateReady)
+		return &payload.Payload{Body: pld.Body}, nil
+	case <-w.killed:
+		time.Sleep(time.Millisecond * 100)
+		return nil, errors.E(errors.Network, errors.Str("worker was killed"))
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	p := &Plugin{log: zap.NewNop(), cfg: &Config{PipelineSize: 10}}
+	p.pldPool = sync.Pool{New: func() interface{} { return new(payload.Payload) }}
+
+	tp := newTestPool(2)
+	wp := p.addPool(defaultPool, &pool.Config{})
+	wp.pool = tp
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		rsp, err := p.exec(wp, []byte("500ms"), []byte("{}"), newLease(time.Second))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("500ms"), rsp.Body)
+	}()
+
+	// the second worker executes the job which times out
+	time.Sleep(time.Millisecond * 50)
+	start := time.Now()
+	_, err := p.exec(wp, []byte("1m"), []byte("{}"), newLease(time.Millisecond*100))
+	require.Error(t, err)
+	assert.True(t, errors.Is(errors.TimeOut, err))
+	// the error is returned once the killed execution is finished
+	assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*200)
+
+	select {
+	case <-tp.workers[1].killed:
+	default:
+		t.Fatal("worker of the timed out job was not killed")
+	}
+
+	wg.Wait()
+	select {
+	case <-tp.workers[0].killed:
+		t.Fatal("worker of the other job was killed")
+	default:
+	}
+
+	// no timeout
+	rsp, err := p.exec(wp, []byte("10ms"), []byte("{}"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("10ms"), rsp.Body)
+}
+
+func TestCheckExecTTL(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	p := &Plugin{log: zap.New(core), cfg: &Config{PipelineSize: 10}}
+
+	// the exec_ttl is not bound to the job timeout, the heartbeats extend the lease
+	opts := &pipelineOptions{JobTimeout: time.Second * 5, PoolConfig: &pool.Config{NumWorkers: 1}}
+	wp, err := p.pipelinePool("test", opts)
+	require.NoError(t, err)
+	assert.Nil(t, wp.cfg.Supervisor)
+	assert.Equal(t, 0, logs.Len())
+
+	opts = &pipelineOptions{JobTimeout: time.Minute * 5, PoolConfig: &pool.Config{Supervisor: &pool.SupervisorConfig{ExecTTL: time.Minute}}}
+	wp, err = p.pipelinePool("test-2", opts)
+	require.NoError(t, err)
+	assert.Equal(t, time.Minute, wp.cfg.Supervisor.ExecTTL)
+	assert.Equal(t, 1, logs.FilterMessageSnippet("job_timeout exceeds").Len())
+}
+
+func TestTimeoutErr(t *testing.T) {
+	const op = errors.Op("test")
+
+	assert.Nil(t, timeoutErr(op, nil))
+	assert.False(t, errors.Is(errors.TimeOut, timeoutErr(op, errors.E(op, errors.Str("foo")))))
+	assert.True(t, errors.Is(errors.TimeOut, timeoutErr(op, errors.E(op, errors.ExecTTL, errors.Str("exec ttl")))))
+}
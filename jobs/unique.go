@@ -0,0 +1,168 @@
+package jobs
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/spiral/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// UniqueKey header contains the user-supplied idempotency key of the job
+	UniqueKey string = "rr_unique_key"
+	// UniqueTTL header overrides the pipeline's unique lock TTL (in seconds)
+	UniqueTTL string = "rr_unique_ttl"
+
+	onDuplicateSkip   string = "skip"
+	onDuplicateReject string = "reject"
+
+	// prefix of the unique lock keys in the kv storage
+	uniquePrefix string = "rr_unique"
+)
+
+// uniqueOptions configures deduplication of the jobs pushed with the UniqueKey header
+type uniqueOptions struct {
+	// Storage is the name of the kv storage (key in the kv section)
+	Storage string `mapstructure:"storage"`
+	// TTL of the lock, protects from the lost jobs, default - 1h
+	TTL time.Duration `mapstructure:"ttl"`
+	// OnDuplicate: skip (push returns without an error) or reject (push returns an error), default - skip
+	OnDuplicate string `mapstructure:"on_duplicate"`
+}
+
+func (u *uniqueOptions) InitDefaults() error {
+	if u.Storage == "" {
+		return errors.Str("unique storage should not be empty")
+	}
+
+	if u.TTL == 0 {
+		u.TTL = time.Hour
+	}
+
+	if u.OnDuplicate == "" {
+		u.OnDuplicate = onDuplicateSkip
+	}
+
+	switch u.OnDuplicate {
+	case onDuplicateSkip, onDuplicateReject:
+	default:
+		return errors.Errorf("unknown unique on_duplicate option: %s, should be one of: skip, reject", u.OnDuplicate)
+	}
+
+	return nil
+}
+
+func uniqueKey(pipe string, headers map[string][]string) string {
+	if h, ok := headers[UniqueKey]; ok && len(h) > 0 && strings.TrimSpace(h[0]) != "" {
+		return uniquePrefix + ":" + pipe + ":" + h[0]
+	}
+
+	return ""
+}
+
+// acquireUnique locks the job's unique key in the kv storage.
+// Returns false if the job is a duplicate and should be skipped, duplicates are rejected with an error when configured.
+// The kv storages have no atomic "set if not exists", the lock is exact only within the RR instance and best-effort
+// across the instances sharing the storage.
+func (p *Plugin) acquireUnique(j *jobs.Job) (bool, error) {
+	const op = errors.Op("jobs_plugin_acquire_unique")
+
+	key := uniqueKey(j.Options.Pipeline, j.Headers)
+	if key == "" {
+		return true, nil
+	}
+
+	opts := p.options(j.Options.Pipeline)
+	if opts == nil || opts.Unique == nil {
+		return false, errors.E(op, errors.Errorf("unique jobs are not configured for the pipeline: %s", j.Options.Pipeline))
+	}
+
+	st, err := p.storage(opts.Unique.Storage)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	ttl := opts.Unique.TTL
+	if h, ok := j.Headers[UniqueTTL]; ok && len(h) > 0 {
+		sec, errP := strconv.ParseInt(h[0], 10, 64)
+		if errP != nil || sec <= 0 {
+			return false, errors.E(op, errors.Errorf("unique ttl should be a positive number of seconds, provided: %s", h[0]))
+		}
+
+		ttl = time.Second * time.Duration(sec)
+	}
+
+	// protect from the concurrent pushes within the RR instance
+	p.uniqueMu.Lock()
+	defer p.uniqueMu.Unlock()
+
+	res, err := st.MGet(key)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	if owner, ok := res[key]; ok {
+		return p.duplicate(opts.Unique, j, string(owner))
+	}
+
+	err = st.Set(&kvv1.Item{
+		Key:     key,
+		Value:   []byte(j.Ident),
+		Timeout: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	// best-effort: detects the key overwritten by another RR instance, but both instances might read their own
+	// values if the writes and the reads interleave
+	res, err = st.MGet(key)
+	if err != nil {
+		return false, errors.E(op, err)
+	}
+
+	if owner := string(res[key]); owner != j.Ident {
+		return p.duplicate(opts.Unique, j, owner)
+	}
+
+	return true, nil
+}
+
+func (p *Plugin) duplicate(opts *uniqueOptions, j *jobs.Job, owner string) (bool, error) {
+	const op = errors.Op("jobs_plugin_acquire_unique")
+
+	if opts.OnDuplicate == onDuplicateReject {
+		return false, errors.E(op, errors.Errorf("duplicate job, unique key: %s, pending job ID: %s", j.Headers[UniqueKey][0], owner))
+	}
+
+	p.log.Debug("duplicate job was skipped", zap.String("ID", j.Ident), zap.String("pipeline", j.Options.Pipeline), zap.String("unique_key", j.Headers[UniqueKey][0]), zap.String("pending", owner))
+	return false, nil
+}
+
+// releaseUnique removes the job's unique key from the kv storage
+func (p *Plugin) releaseUnique(pipe string, headers map[string][]string) {
+	key := uniqueKey(pipe, headers)
+	if key == "" {
+		return
+	}
+
+	opts := p.options(pipe)
+	if opts == nil || opts.Unique == nil {
+		return
+	}
+
+	st, err := p.storage(opts.Unique.Storage)
+	if err != nil {
+		p.log.Error("failed to release the unique key", zap.String("pipeline", pipe), zap.String("key", key), zap.Error(err))
+		return
+	}
+
+	err = st.Delete(key)
+	if err != nil {
+		p.log.Error("failed to release the unique key", zap.String("pipeline", pipe), zap.String("key", key), zap.Error(err))
+	}
+}
@@ -0,0 +1,221 @@
+package jobs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/roadrunner-server/api/v2/plugins/kv"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+type testStorage struct {
+	data map[string][]byte
+}
+
+func (s *testStorage) Has(keys ...string) (map[string]bool, error) {
+	m := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if _, ok := s.data[k]; ok {
+			m[k] = true
+		}
+	}
+	return m, nil
+}
+
+func (s *testStorage) Get(key string) ([]byte, error) {
+	return s.data[key], nil
+}
+
+func (s *testStorage) MGet(keys ...string) (map[string][]byte, error) {
+	m := make(map[string][]byte, len(keys))
+	for _, k := range keys {
+		if v, ok := s.data[k]; ok {
+			m[k] = v
+		}
+	}
+	return m, nil
+}
+
+func (s *testStorage) Set(items ...*kvv1.Item) error {
+	for _, i := range items {
+		s.data[i.Key] = i.Value
+	}
+	return nil
+}
+
+func (s *testStorage) MExpire(...*kvv1.Item) error {
+	return nil
+}
+
+func (s *testStorage) TTL(...string) (map[string]string, error) {
+	return nil, nil
+}
+
+func (s *testStorage) Clear() error {
+	s.data = make(map[string][]byte)
+	return nil
+}
+
+func (s *testStorage) Delete(keys ...string) error {
+	for _, k := range keys {
+		delete(s.data, k)
+	}
+	return nil
+}
+
+func (s *testStorage) Stop() {}
+
+type testStorageProvider map[string]kv.Storage
+
+func (sp testStorageProvider) Storage(name string) (kv.Storage, error) {
+	return sp[name], nil
+}
+
+func testUniquePlugin(t *testing.T, onDuplicate string) (*Plugin, *testStorage) {
+	st := &testStorage{data: make(map[string][]byte)}
+	p := &Plugin{
+		log:        zap.NewNop(),
+		kvProvider: testStorageProvider{"test-kv": st},
+	}
+
+	opts, err := parseOptions(&pipeline.Pipeline{
+		"name":   "test",
+		"driver": "memory",
+		"unique": map[string]interface{}{
+			"storage":      "test-kv",
+			"on_duplicate": onDuplicate,
+		},
+	})
+	require.NoError(t, err)
+	p.pipelineOpts.Store("test", opts)
+
+	return p, st
+}
+
+func testUniqueJob(id, key string) *jobs.Job {
+	return &jobs.Job{
+		Ident:   id,
+		Headers: map[string][]string{UniqueKey: {key}},
+		Options: &jobs.Options{Pipeline: "test"},
+	}
+}
+
+func TestUniqueSkip(t *testing.T) {
+	p, st := testUniquePlugin(t, "")
+
+	acquired, err := p.acquireUnique(testUniqueJob("1", "webhook-1"))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, []byte("1"), st.data["rr_unique:test:webhook-1"])
+
+	// duplicate is skipped
+	acquired, err = p.acquireUnique(testUniqueJob("2", "webhook-1"))
+	require.NoError(t, err)
+	assert.False(t, acquired)
+
+	// another key
+	acquired, err = p.acquireUnique(testUniqueJob("3", "webhook-2"))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// released on ack
+	p.releaseUnique("test", map[string][]string{UniqueKey: {"webhook-1"}})
+	acquired, err = p.acquireUnique(testUniqueJob("4", "webhook-1"))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	// jobs without the key are not locked
+	acquired, err = p.acquireUnique(&jobs.Job{Ident: "5", Options: &jobs.Options{Pipeline: "test"}})
+	require.NoError(t, err)
+	assert.True(t, acquired)
+}
+
+func TestUniqueRespond(t *testing.T) {
+	p, st := testUniquePlugin(t, "")
+	p.cfg = &Config{}
+	p.pipeMetrics = newPipelineMetrics()
+
+	acquired, err := p.acquireUnique(testUniqueJob("1", "webhook-1"))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	it := &item{
+		Item:  &testPQItem{id: "1"},
+		ack:   testAcknowledger{},
+		ctx:   &jobContext{ID: "1", Pipeline: "test", Headers: map[string][]string{UniqueKey: {"webhook-1"}}},
+		p:     p,
+		start: time.Now(),
+	}
+
+	// released with the response
+	require.NoError(t, it.Respond([]byte("response"), "responses"))
+	assert.Empty(t, st.data)
+}
+
+func TestUniqueTerminal(t *testing.T) {
+	p, st := testUniquePlugin(t, "")
+	p.cfg = &Config{Timeout: 10}
+	p.pipeMetrics = newPipelineMetrics()
+
+	acquire := func(id, key string) *jobs.Job {
+		j := testUniqueJob(id, key)
+		acquired, err := p.acquireUnique(j)
+		require.NoError(t, err)
+		require.True(t, acquired)
+		return j
+	}
+
+	// negatively acknowledged job, e.g. dead-lettered by the driver
+	acquire("1", "webhook-1")
+	it := &item{
+		Item:  &testPQItem{id: "1"},
+		ack:   testAcknowledger{},
+		ctx:   &jobContext{ID: "1", Pipeline: "test", Headers: map[string][]string{UniqueKey: {"webhook-1"}}},
+		p:     p,
+		start: time.Now(),
+	}
+	require.NoError(t, it.Nack())
+	assert.Empty(t, st.data)
+
+	// jobs removed via the inspector
+	ins := &testInspector{jobs: []*jobs.Job{acquire("2", "webhook-2"), acquire("3", "webhook-3"), acquire("4", "webhook-4")}}
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", ins)
+
+	deleted, err := p.Delete("test", "2")
+	require.NoError(t, err)
+	require.True(t, deleted)
+	assert.Len(t, st.data, 2)
+
+	_, err = p.Purge("test")
+	require.NoError(t, err)
+	assert.Empty(t, st.data)
+}
+
+func TestUniqueReject(t *testing.T) {
+	p, _ := testUniquePlugin(t, onDuplicateReject)
+
+	acquired, err := p.acquireUnique(testUniqueJob("1", "webhook-1"))
+	require.NoError(t, err)
+	assert.True(t, acquired)
+
+	acquired, err = p.acquireUnique(testUniqueJob("2", "webhook-1"))
+	assert.Error(t, err)
+	assert.False(t, acquired)
+
+	// not configured for the pipeline
+	j := testUniqueJob("3", "webhook-1")
+	j.Options.Pipeline = "test-2"
+	_, err = p.acquireUnique(j)
+	assert.Error(t, err)
+}
+
+func TestUniqueOptionsErrors(t *testing.T) {
+	assert.Error(t, (&uniqueOptions{}).InitDefaults())
+	assert.Error(t, (&uniqueOptions{Storage: "test", OnDuplicate: "ignore"}).InitDefaults())
+}
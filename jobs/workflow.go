@@ -0,0 +1,346 @@
+package jobs
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	kvv1 "github.com/roadrunner-server/api/v2/proto/kv/v1beta"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
+	"go.uber.org/zap"
+)
+
+const (
+	// GroupHeader contains the name of the group of the follow-up job
+	GroupHeader string = "rr_group"
+	// GroupCallback header contains the name of the finished group of the callback job
+	GroupCallback string = "rr_group_callback"
+	// GroupFailed header of the callback job contains the number of the group's jobs finished with the failed or dead status
+	GroupFailed string = "rr_group_failed"
+
+	// prefix of the group keys in the kv storage
+	groupPrefix string = "rr_group"
+)
+
+// WorkflowConfig configures the storage of the jobs groups state
+type WorkflowConfig struct {
+	// Storage is the name of the kv storage (key in the kv section)
+	Storage string `mapstructure:"storage"`
+	// TTL of the group state, protects from the lost jobs, default - 24h
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+func (c *WorkflowConfig) InitDefaults() error {
+	if c.Storage == "" {
+		return errors.Str("workflow storage should not be empty")
+	}
+
+	if c.TTL == 0 {
+		c.TTL = time.Hour * 24
+	}
+
+	return nil
+}
+
+// groupState is the stored group, members are the IDs of the group's jobs
+type groupState struct {
+	Callback *jobs.Job `json:"callback"`
+	Members  []string  `json:"members"`
+}
+
+func groupKey(name string) string {
+	return groupPrefix + ":" + name
+}
+
+// groupMemberKey contains the final status of the finished group's job
+func groupMemberKey(name, id string) string {
+	return groupPrefix + ":" + name + ":done:" + id
+}
+
+// groupFiredKey contains the ID of the job which finished the group and pushed the callback
+func groupFiredKey(name string) string {
+	return groupPrefix + ":" + name + ":fired"
+}
+
+// followUpJob converts the follow-up job returned by the worker, pipeline defaults to the parent's one
+func followUpJob(fu *protocol.FollowUp, parent string) (*jobs.Job, error) {
+	if fu == nil || fu.Job == "" {
+		return nil, errors.Str("follow-up job name should not be empty")
+	}
+
+	if fu.Delay < 0 {
+		return nil, errors.Errorf("follow-up job delay should be positive, provided: %d", fu.Delay)
+	}
+
+	j := &jobs.Job{
+		Job:     fu.Job,
+		Ident:   fu.ID,
+		Payload: fu.Payload,
+		Headers: make(map[string][]string, len(fu.Headers)+1),
+		Options: &jobs.Options{
+			Priority: fu.Priority,
+			Pipeline: fu.Pipeline,
+			Delay:    fu.Delay,
+		},
+	}
+
+	if j.Ident == "" {
+		j.Ident = uuid.NewString()
+	}
+
+	if j.Options.Pipeline == "" {
+		j.Options.Pipeline = parent
+	}
+
+	for k, v := range fu.Headers {
+		j.Headers[k] = v
+	}
+
+	return j, nil
+}
+
+// Continue pushes the follow-up jobs returned by the worker, see protocol.Continuer
+func (i *item) Continue(fus []*protocol.FollowUp, group *protocol.Group) error {
+	return i.p.pushFollowUps(i.ctx.Pipeline, fus, group)
+}
+
+// pushFollowUps pushes the follow-up jobs of the job from the pipeline.
+// The group is stored before the jobs are pushed, so the fast jobs can't finish the group too early.
+func (p *Plugin) pushFollowUps(pipe string, fus []*protocol.FollowUp, group *protocol.Group) error {
+	const op = errors.Op("jobs_plugin_push_follow_ups")
+
+	batch := make([]*jobs.Job, 0, len(fus))
+	for i := 0; i < len(fus); i++ {
+		j, err := followUpJob(fus[i], pipe)
+		if err != nil {
+			return errors.E(op, err)
+		}
+
+		batch = append(batch, j)
+	}
+
+	if group != nil {
+		err := p.createGroup(pipe, group, batch)
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	err := p.PushBatch(batch)
+	if err != nil {
+		// the retried parent creates the group again
+		if group != nil {
+			p.deleteGroup(group.Name, batch)
+		}
+
+		return errors.E(op, err)
+	}
+
+	p.log.Debug("follow-up jobs were pushed", zap.String("pipeline", pipe), zap.Int("jobs", len(batch)))
+	return nil
+}
+
+// createGroup stores the group and marks the jobs as its members
+func (p *Plugin) createGroup(pipe string, group *protocol.Group, members []*jobs.Job) error {
+	if p.cfg.Workflow == nil {
+		return errors.Str("workflow storage is not configured")
+	}
+
+	if group.Name == "" {
+		return errors.Str("group name should not be empty")
+	}
+
+	if len(members) == 0 {
+		return errors.Errorf("group should have at least one job, group: %s", group.Name)
+	}
+
+	callback, err := followUpJob(group.Callback, pipe)
+	if err != nil {
+		return errors.Errorf("group: %s, callback error: %v", group.Name, err)
+	}
+
+	st, err := p.storage(p.cfg.Workflow.Storage)
+	if err != nil {
+		return err
+	}
+
+	gs := &groupState{
+		Callback: callback,
+		Members:  make([]string, 0, len(members)),
+	}
+
+	for i := 0; i < len(members); i++ {
+		// the skipped duplicate would never finish the group
+		if uniqueKey(members[i].Options.Pipeline, members[i].Headers) != "" {
+			return errors.Errorf("group job should not have the unique key, group: %s, job ID: %s", group.Name, members[i].Ident)
+		}
+
+		members[i].Headers[GroupHeader] = []string{group.Name}
+		gs.Members = append(gs.Members, members[i].Ident)
+	}
+
+	data, err := json.Marshal(gs)
+	if err != nil {
+		return err
+	}
+
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	key := groupKey(group.Name)
+	res, err := st.Has(key)
+	if err != nil {
+		return err
+	}
+
+	if res[key] {
+		return errors.Errorf("group is in progress: %s", group.Name)
+	}
+
+	return st.Set(&kvv1.Item{
+		Key:     key,
+		Value:   data,
+		Timeout: time.Now().Add(p.cfg.Workflow.TTL).UTC().Format(time.RFC3339),
+	})
+}
+
+// deleteGroup removes the state of the group which jobs were not pushed
+func (p *Plugin) deleteGroup(name string, members []*jobs.Job) {
+	st, err := p.storage(p.cfg.Workflow.Storage)
+	if err != nil {
+		p.log.Error("failed to delete the group", zap.String("group", name), zap.Error(err))
+		return
+	}
+
+	keys := make([]string, 0, len(members)+1)
+	keys = append(keys, groupKey(name))
+	for i := 0; i < len(members); i++ {
+		keys = append(keys, groupMemberKey(name, members[i].Ident))
+	}
+
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	err = st.Delete(keys...)
+	if err != nil {
+		p.log.Error("failed to delete the group", zap.String("group", name), zap.Error(err))
+	}
+}
+
+// finishGroupMember records the final status of the group's job and pushes the group callback
+// when all jobs of the group are finished
+func (p *Plugin) finishGroupMember(it *item, status string) {
+	p.finishGroupJob(it.ID(), it.ctx.Headers, status)
+}
+
+// finishGroupJob finishes the group's job by its ID and headers, e.g. the job removed from the queue via the inspector
+func (p *Plugin) finishGroupJob(id string, headers map[string][]string, status string) {
+	h, ok := headers[GroupHeader]
+	if !ok || len(h) == 0 || p.cfg.Workflow == nil {
+		return
+	}
+
+	name := h[0]
+	err := p.finishGroup(name, id, status)
+	if err != nil {
+		p.log.Error("failed to finish the group job", zap.String("ID", id), zap.String("group", name), zap.Error(err))
+	}
+}
+
+func (p *Plugin) finishGroup(name, id, status string) error { //nolint:gocognit
+	st, err := p.storage(p.cfg.Workflow.Storage)
+	if err != nil {
+		return err
+	}
+
+	p.groupMu.Lock()
+	defer p.groupMu.Unlock()
+
+	key := groupKey(name)
+	res, err := st.MGet(key)
+	if err != nil {
+		return err
+	}
+
+	// the group was finished or expired
+	if _, ok := res[key]; !ok {
+		return nil
+	}
+
+	gs := &groupState{}
+	err = json.Unmarshal(res[key], gs)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Now().Add(p.cfg.Workflow.TTL).UTC().Format(time.RFC3339)
+	err = st.Set(&kvv1.Item{
+		Key:     groupMemberKey(name, id),
+		Value:   []byte(status),
+		Timeout: timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(gs.Members))
+	for i := 0; i < len(gs.Members); i++ {
+		keys = append(keys, groupMemberKey(name, gs.Members[i]))
+	}
+
+	done, err := st.MGet(keys...)
+	if err != nil {
+		return err
+	}
+
+	if len(done) < len(keys) {
+		return nil
+	}
+
+	// another RR instance might finish the group at the same time
+	fired := groupFiredKey(name)
+	err = st.Set(&kvv1.Item{
+		Key:     fired,
+		Value:   []byte(id),
+		Timeout: timeout,
+	})
+	if err != nil {
+		return err
+	}
+
+	owner, err := st.MGet(fired)
+	if err != nil {
+		return err
+	}
+
+	if string(owner[fired]) != id {
+		return nil
+	}
+
+	failed := 0
+	for _, v := range done {
+		if string(v) != StatusOk {
+			failed++
+		}
+	}
+
+	cb := gs.Callback
+	if cb.Headers == nil {
+		cb.Headers = make(map[string][]string, 2)
+	}
+	cb.Headers[GroupCallback] = []string{name}
+	cb.Headers[GroupFailed] = []string{strconv.Itoa(failed)}
+
+	err = p.Push(cb)
+	if err != nil {
+		return err
+	}
+
+	p.log.Info("group was finished, callback job was pushed", zap.String("group", name), zap.String("ID", cb.Ident), zap.String("pipeline", cb.Options.Pipeline), zap.Int("failed", failed))
+
+	// the fired key expires with the TTL
+	return st.Delete(append(keys, key)...)
+}
@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"testing"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/roadrunner-plugins/v2/jobs/protocol"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testWorkflowPlugin(t *testing.T) (*Plugin, *testInspector, *testStorage) {
+	st := &testStorage{data: make(map[string][]byte)}
+	p := testInspectPlugin()
+	p.cfg.Workflow = &WorkflowConfig{Storage: "groups"}
+	require.NoError(t, p.cfg.Workflow.InitDefaults())
+	p.kvProvider = testStorageProvider{"groups": st}
+
+	c := &testInspector{}
+	p.pipelines.Store("test", &pipeline.Pipeline{"name": "test", "driver": "memory"})
+	p.consumers.Store("test", c)
+
+	return p, c, st
+}
+
+func TestFollowUps(t *testing.T) {
+	p, c, _ := testWorkflowPlugin(t)
+
+	err := p.pushFollowUps("test", []*protocol.FollowUp{
+		{Job: "resize", Payload: "1", Headers: map[string][]string{"a": {"b"}}, Delay: 10, Priority: 5},
+		{ID: "2", Job: "resize", Pipeline: "test"},
+	}, nil)
+	require.NoError(t, err)
+
+	require.Len(t, c.jobs, 2)
+	assert.NotEmpty(t, c.jobs[0].Ident)
+	assert.Equal(t, "test", c.jobs[0].Options.Pipeline)
+	assert.Equal(t, int64(10), c.jobs[0].Options.Delay)
+	assert.Equal(t, int64(5), c.jobs[0].Options.Priority)
+	assert.Equal(t, []string{"b"}, c.jobs[0].Headers["a"])
+	assert.Equal(t, "2", c.jobs[1].Ident)
+
+	assert.Error(t, p.pushFollowUps("test", []*protocol.FollowUp{{Payload: "no name"}}, nil))
+	assert.Error(t, p.pushFollowUps("test", []*protocol.FollowUp{{Job: "resize", Pipeline: "unknown"}}, nil))
+}
+
+func TestGroup(t *testing.T) {
+	p, c, st := testWorkflowPlugin(t)
+
+	group := &protocol.Group{Name: "album-1", Callback: &protocol.FollowUp{ID: "cb", Job: "notify"}}
+	err := p.pushFollowUps("test", []*protocol.FollowUp{{ID: "1", Job: "resize"}, {ID: "2", Job: "resize"}}, group)
+	require.NoError(t, err)
+	require.Len(t, c.jobs, 2)
+	assert.Equal(t, []string{"album-1"}, c.jobs[0].Headers[GroupHeader])
+
+	// the group is in progress
+	assert.Error(t, p.pushFollowUps("test", []*protocol.FollowUp{{ID: "3", Job: "resize"}}, group))
+
+	// the deduplicated member would never finish the group
+	unique := &protocol.Group{Name: "album-3", Callback: &protocol.FollowUp{Job: "notify"}}
+	assert.Error(t, p.pushFollowUps("test", []*protocol.FollowUp{{Job: "resize", Headers: map[string][]string{UniqueKey: {"1"}}}}, unique))
+	require.Len(t, c.jobs, 2)
+
+	member := func(id string) *item {
+		return &item{
+			Item: &testPQItem{id: id},
+			ctx:  &jobContext{ID: id, Pipeline: "test", Headers: map[string][]string{GroupHeader: {"album-1"}}},
+			p:    p,
+		}
+	}
+
+	p.finishGroupMember(member("1"), StatusOk)
+	// duplicated outcome
+	p.finishGroupMember(member("1"), StatusOk)
+	require.Len(t, c.jobs, 2)
+
+	p.finishGroupMember(member("2"), StatusDead)
+	require.Len(t, c.jobs, 3)
+
+	cb := c.jobs[2]
+	assert.Equal(t, "cb", cb.Ident)
+	assert.Equal(t, "notify", cb.Job)
+	assert.Equal(t, "test", cb.Options.Pipeline)
+	assert.Equal(t, []string{"album-1"}, cb.Headers[GroupCallback])
+	assert.Equal(t, []string{"1"}, cb.Headers[GroupFailed])
+
+	// the group state is removed, late outcomes are ignored
+	_, ok := st.data[groupKey("album-1")]
+	assert.False(t, ok)
+	p.finishGroupMember(member("2"), StatusOk)
+	assert.Len(t, c.jobs, 3)
+
+	// the group name might be reused
+	require.NoError(t, p.pushFollowUps("test", []*protocol.FollowUp{{ID: "4", Job: "resize"}}, group))
+
+	// not configured
+	p.cfg.Workflow = nil
+	assert.Error(t, p.pushFollowUps("test", []*protocol.FollowUp{{Job: "resize"}}, &protocol.Group{Name: "album-2", Callback: &protocol.FollowUp{Job: "notify"}}))
+}
+
+func TestGroupCleanup(t *testing.T) {
+	p, c, st := testWorkflowPlugin(t)
+
+	// the group of the failed push is removed, the retried parent creates it again
+	group := &protocol.Group{Name: "album-1", Callback: &protocol.FollowUp{ID: "cb", Job: "notify"}}
+	err := p.pushFollowUps("test", []*protocol.FollowUp{{ID: "1", Job: "resize", Pipeline: "unknown"}}, group)
+	require.Error(t, err)
+	_, ok := st.data[groupKey("album-1")]
+	assert.False(t, ok)
+
+	require.NoError(t, p.pushFollowUps("test", []*protocol.FollowUp{{ID: "1", Job: "resize"}, {ID: "2", Job: "resize"}}, group))
+	require.Len(t, c.jobs, 2)
+
+	// the members removed via the inspector finish the group as failed
+	deleted, err := p.Delete("test", "1")
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	n, err := p.Purge("test")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+
+	// the callback is pushed
+	require.Len(t, c.jobs, 1)
+	assert.Equal(t, "cb", c.jobs[0].Ident)
+	assert.Equal(t, []string{"2"}, c.jobs[0].Headers[GroupFailed])
+}
@@ -160,6 +160,16 @@ func (p *Plugin) GetAllStorageDrivers(name endure.Named, constructor kv.Construc
 	p.constructors[name.Name()] = constructor
 }
 
+// Storage returns the storage configured in the kv section by its name, used by other plugins (e.g. jobs)
+func (p *Plugin) Storage(name string) (kv.Storage, error) {
+	const op = errors.Op("kv_plugin_storage")
+	if st, ok := p.storages[name]; ok {
+		return st, nil
+	}
+
+	return nil, errors.E(op, errors.Errorf("no such storage: %s", name))
+}
+
 // RPC returns associated rpc service.
 func (p *Plugin) RPC() interface{} {
 	return &rpc{srv: p, storages: p.storages}
@@ -2,6 +2,7 @@ package memoryjobs
 
 import (
 	"context"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -40,6 +41,12 @@ type consumer struct {
 	priority  int64
 	listeners uint32
 	stopCh    chan struct{}
+
+	// jobs waiting in the queue or executed by the workers, used by the inspection
+	mu    sync.Mutex
+	items map[*Item]struct{}
+	// push order of the jobs
+	seq uint64
 }
 
 func FromConfig(configKey string, log *zap.Logger, cfg config.Configurer, pq priorityqueue.Queue) (*consumer, error) {
@@ -52,6 +59,7 @@ func FromConfig(configKey string, log *zap.Logger, cfg config.Configurer, pq pri
 		active:     utils.Int64(0),
 		delayed:    utils.Int64(0),
 		stopCh:     make(chan struct{}),
+		items:      make(map[*Item]struct{}),
 	}
 
 	err := cfg.UnmarshalKey(configKey, &jb.cfg)
@@ -89,6 +97,7 @@ func FromPipeline(pipeline *pipeline.Pipeline, log *zap.Logger, pq priorityqueue
 		delayed:       utils.Int64(0),
 		priority:      pipeline.Priority(),
 		stopCh:        make(chan struct{}),
+		items:         make(map[*Item]struct{}),
 	}, nil
 }
 
@@ -209,12 +218,14 @@ func (c *consumer) Stop(_ context.Context) error {
 
 func (c *consumer) handleItem(ctx context.Context, msg *Item) error {
 	const op = errors.Op("ephemeral_handle_request")
+	c.track(msg)
 	// handle timeouts
 	// theoretically, some bad user may send millions requests with a delay and produce a billion (for example)
 	// goroutines here. We should limit goroutines here.
 	if msg.Options.Delay > 0 {
 		// if we have 1000 goroutines waiting on the delay - reject 1001
 		if atomic.LoadUint64(&c.goroutines) >= goroutinesMax {
+			c.forget(msg)
 			return errors.E(op, errors.Str("max concurrency number reached"))
 		}
 
@@ -222,12 +233,21 @@ func (c *consumer) handleItem(ctx context.Context, msg *Item) error {
 			atomic.AddUint64(&c.goroutines, 1)
 			atomic.AddInt64(c.delayed, 1)
 
-			time.Sleep(jj.Options.DelayDuration())
+			timer := time.NewTimer(jj.Options.DelayDuration())
+			select {
+			case <-timer.C:
+			case <-jj.Options.cancelCh:
+				// the job was canceled or deleted during the delay
+				timer.Stop()
+				atomic.AddUint64(&c.goroutines, ^uint64(0))
+				return
+			}
 
 			select {
 			case c.localPrefetch <- jj:
 				atomic.AddUint64(&c.goroutines, ^uint64(0))
 			default:
+				c.forget(jj)
 				c.log.Warn("can't push job", zap.String("error", "local queue closed or full"))
 			}
 		}(msg)
@@ -243,6 +263,7 @@ func (c *consumer) handleItem(ctx context.Context, msg *Item) error {
 	case c.localPrefetch <- msg:
 		return nil
 	case <-ctx.Done():
+		c.forget(msg)
 		return errors.E(op, errors.Errorf("local pipeline is full, consider to increase prefetch number, current limit: %d, context error: %v", c.cfg.Prefetch, ctx.Err()))
 	}
 }
@@ -258,6 +279,11 @@ func (c *consumer) consume() {
 					return
 				}
 
+				// the job was deleted while waiting in the local queue
+				if item.Deleted() {
+					continue
+				}
+
 				if item.Priority() == 0 {
 					item.Options.Priority = c.priority
 				}
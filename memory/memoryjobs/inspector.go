@@ -0,0 +1,159 @@
+package memoryjobs
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+)
+
+const (
+	// job is waiting in the queue
+	stateWaiting uint32 = iota
+	// job was taken by the jobs plugin
+	stateReserved
+	// job was deleted while waiting in the queue
+	stateDeleted
+)
+
+// track registers the pushed or requeued job
+func (c *consumer) track(item *Item) {
+	c.mu.Lock()
+	c.seq++
+	item.Options.seq = c.seq
+	// set here to not modify the inspected job in the consumer
+	if item.Options.Priority == 0 {
+		item.Options.Priority = c.priority
+	}
+	item.Options.forgetFn = c.forget
+	atomic.StoreUint32(&item.Options.state, stateWaiting)
+	if item.Options.Delay > 0 {
+		item.Options.cancelCh = make(chan struct{})
+	}
+	c.items[item] = struct{}{}
+	c.mu.Unlock()
+}
+
+// forget removes the acknowledged or requeued job
+func (c *consumer) forget(item *Item) {
+	c.mu.Lock()
+	delete(c.items, item)
+	c.mu.Unlock()
+}
+
+// Peek returns the jobs waiting in the queue in the push order
+func (c *consumer) Peek(_ context.Context, limit, offset int) ([]*jobs.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiting := make([]*Item, 0, len(c.items))
+	for item := range c.items {
+		if atomic.LoadUint32(&item.Options.state) == stateWaiting {
+			waiting = append(waiting, item)
+		}
+	}
+
+	sort.Slice(waiting, func(i, j int) bool {
+		return waiting[i].Options.seq < waiting[j].Options.seq
+	})
+
+	if offset >= len(waiting) {
+		return nil, nil
+	}
+
+	waiting = waiting[offset:]
+	if len(waiting) > limit {
+		waiting = waiting[:limit]
+	}
+
+	res := make([]*jobs.Job, 0, len(waiting))
+	for i := 0; i < len(waiting); i++ {
+		res = append(res, waiting[i].toJob())
+	}
+
+	return res, nil
+}
+
+// Delete removes the waiting job, the job left in the pool's queue is skipped by the jobs plugin
+func (c *consumer) Delete(_ context.Context, id string) (*jobs.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for item := range c.items {
+		if item.Ident != id || !atomic.CompareAndSwapUint32(&item.Options.state, stateWaiting, stateDeleted) {
+			continue
+		}
+
+		c.remove(item)
+		return item.toJob(), nil
+	}
+
+	return nil, nil
+}
+
+// Purge removes all waiting jobs, the fn is called after the removal (it might push the jobs)
+func (c *consumer) Purge(_ context.Context, fn func(*jobs.Job)) (int64, error) {
+	c.mu.Lock()
+	deleted := make([]*jobs.Job, 0, len(c.items))
+	for item := range c.items {
+		if !atomic.CompareAndSwapUint32(&item.Options.state, stateWaiting, stateDeleted) {
+			continue
+		}
+
+		c.remove(item)
+		deleted = append(deleted, item.toJob())
+	}
+	c.mu.Unlock()
+
+	for i := 0; i < len(deleted); i++ {
+		fn(deleted[i])
+	}
+
+	return int64(len(deleted)), nil
+}
+
+// Cancel deletes the delayed job before its delay expires
+func (c *consumer) Cancel(_ context.Context, id string) (*jobs.Job, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for item := range c.items {
+		if item.Ident != id || item.Options.Delay == 0 || !atomic.CompareAndSwapUint32(&item.Options.state, stateWaiting, stateDeleted) {
+			continue
+		}
+
+		c.remove(item)
+		return item.toJob(), nil
+	}
+
+	return nil, nil
+}
+
+// remove removes the deleted job and reduces the number of the active or delayed jobs, should be called under the lock
+func (c *consumer) remove(item *Item) {
+	delete(c.items, item)
+
+	if item.Options.Delay > 0 {
+		// wake up the delay goroutine
+		close(item.Options.cancelCh)
+		atomic.AddInt64(c.delayed, ^int64(0))
+		return
+	}
+
+	atomic.AddInt64(c.active, ^int64(0))
+}
+
+func (i *Item) toJob() *jobs.Job {
+	return &jobs.Job{
+		Job:     i.Job,
+		Ident:   i.Ident,
+		Payload: i.Payload,
+		Headers: i.Headers,
+		Options: &jobs.Options{
+			Priority: i.Options.Priority,
+			Pipeline: i.Options.Pipeline,
+			Delay:    i.Options.Delay,
+		},
+	}
+}
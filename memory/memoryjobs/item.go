@@ -41,8 +41,14 @@ type Options struct {
 
 	// private
 	requeueFn func(context.Context, *Item) error
+	forgetFn  func(*Item)
 	active    *int64
 	delayed   *int64
+	// push order and the inspection state of the job
+	seq   uint64
+	state uint32
+	// closed when the delayed job is canceled or deleted
+	cancelCh chan struct{}
 }
 
 // DelayDuration returns delay duration in a form of time.Duration.
@@ -64,7 +70,10 @@ func (i *Item) Body() []byte {
 }
 
 // Context packs job context (job, id) into binary payload.
+// Called by the jobs plugin when the job is taken from the queue, the job can't be deleted after that.
 func (i *Item) Context() ([]byte, error) {
+	atomic.CompareAndSwapUint32(&i.Options.state, stateWaiting, stateReserved)
+
 	ctx, err := json.Marshal(
 		struct {
 			ID       string              `json:"id"`
@@ -82,20 +91,28 @@ func (i *Item) Context() ([]byte, error) {
 }
 
 func (i *Item) Ack() error {
+	i.Options.forgetFn(i)
 	i.atomicallyReduceCount()
 	return nil
 }
 
 func (i *Item) Nack() error {
+	i.Options.forgetFn(i)
 	i.atomicallyReduceCount()
 	return nil
 }
 
+// Deleted reports whether the job was deleted by the inspection while waiting in the queue
+func (i *Item) Deleted() bool {
+	return atomic.LoadUint32(&i.Options.state) == stateDeleted
+}
+
 func (i *Item) Requeue(headers map[string][]string, delay int64) error {
 	// overwrite the delay
 	i.Options.Delay = delay
 	i.Headers = headers
 
+	i.Options.forgetFn(i)
 	i.atomicallyReduceCount()
 
 	err := i.Options.requeueFn(context.Background(), i)
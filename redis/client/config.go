@@ -0,0 +1,66 @@
+// Package client contains the connection configuration shared by the redis drivers
+package client
+
+import (
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+type Config struct {
+	Addrs            []string      `mapstructure:"addrs"`
+	DB               int           `mapstructure:"db"`
+	Username         string        `mapstructure:"username"`
+	Password         string        `mapstructure:"password"`
+	MasterName       string        `mapstructure:"master_name"`
+	SentinelPassword string        `mapstructure:"sentinel_password"`
+	RouteByLatency   bool          `mapstructure:"route_by_latency"`
+	RouteRandomly    bool          `mapstructure:"route_randomly"`
+	MaxRetries       int           `mapstructure:"max_retries"`
+	DialTimeout      time.Duration `mapstructure:"dial_timeout"`
+	MinRetryBackoff  time.Duration `mapstructure:"min_retry_backoff"`
+	MaxRetryBackoff  time.Duration `mapstructure:"max_retry_backoff"`
+	PoolSize         int           `mapstructure:"pool_size"`
+	MinIdleConns     int           `mapstructure:"min_idle_conns"`
+	MaxConnAge       time.Duration `mapstructure:"max_conn_age"`
+	ReadTimeout      time.Duration `mapstructure:"read_timeout"`
+	WriteTimeout     time.Duration `mapstructure:"write_timeout"`
+	PoolTimeout      time.Duration `mapstructure:"pool_timeout"`
+	IdleTimeout      time.Duration `mapstructure:"idle_timeout"`
+	IdleCheckFreq    time.Duration `mapstructure:"idle_check_freq"`
+	ReadOnly         bool          `mapstructure:"read_only"`
+}
+
+// InitDefaults initializing fill config with default values
+func (s *Config) InitDefaults() {
+	if s.Addrs == nil {
+		s.Addrs = []string{"127.0.0.1:6379"} // default addr is pointing to local storage
+	}
+}
+
+// UniversalOptions returns the options of the redis universal client
+func (s *Config) UniversalOptions() *goredis.UniversalOptions {
+	return &goredis.UniversalOptions{
+		Addrs:              s.Addrs,
+		DB:                 s.DB,
+		Username:           s.Username,
+		Password:           s.Password,
+		SentinelPassword:   s.SentinelPassword,
+		MaxRetries:         s.MaxRetries,
+		MinRetryBackoff:    s.MinRetryBackoff,
+		MaxRetryBackoff:    s.MaxRetryBackoff,
+		DialTimeout:        s.DialTimeout,
+		ReadTimeout:        s.ReadTimeout,
+		WriteTimeout:       s.WriteTimeout,
+		PoolSize:           s.PoolSize,
+		MinIdleConns:       s.MinIdleConns,
+		MaxConnAge:         s.MaxConnAge,
+		PoolTimeout:        s.PoolTimeout,
+		IdleTimeout:        s.IdleTimeout,
+		IdleCheckFrequency: s.IdleCheckFreq,
+		ReadOnly:           s.ReadOnly,
+		RouteByLatency:     s.RouteByLatency,
+		RouteRandomly:      s.RouteRandomly,
+		MasterName:         s.MasterName,
+	}
+}
@@ -38,29 +38,7 @@ func NewRedisDriver(log *zap.Logger, key string, cfgPlugin config.Configurer) (*
 
 	d.cfg.InitDefaults()
 
-	d.universalClient = redis.NewUniversalClient(&redis.UniversalOptions{
-		Addrs:              d.cfg.Addrs,
-		DB:                 d.cfg.DB,
-		Username:           d.cfg.Username,
-		Password:           d.cfg.Password,
-		SentinelPassword:   d.cfg.SentinelPassword,
-		MaxRetries:         d.cfg.MaxRetries,
-		MinRetryBackoff:    d.cfg.MaxRetryBackoff,
-		MaxRetryBackoff:    d.cfg.MaxRetryBackoff,
-		DialTimeout:        d.cfg.DialTimeout,
-		ReadTimeout:        d.cfg.ReadTimeout,
-		WriteTimeout:       d.cfg.WriteTimeout,
-		PoolSize:           d.cfg.PoolSize,
-		MinIdleConns:       d.cfg.MinIdleConns,
-		MaxConnAge:         d.cfg.MaxConnAge,
-		PoolTimeout:        d.cfg.PoolTimeout,
-		IdleTimeout:        d.cfg.IdleTimeout,
-		IdleCheckFrequency: d.cfg.IdleCheckFreq,
-		ReadOnly:           d.cfg.ReadOnly,
-		RouteByLatency:     d.cfg.RouteByLatency,
-		RouteRandomly:      d.cfg.RouteRandomly,
-		MasterName:         d.cfg.MasterName,
-	})
+	d.universalClient = redis.NewUniversalClient(d.cfg.UniversalOptions())
 
 	return d, nil
 }
@@ -3,12 +3,17 @@ package redis
 import (
 	"sync"
 
+	goredis "github.com/go-redis/redis/v8"
 	"github.com/roadrunner-server/api/v2/plugins/config"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
 	"github.com/roadrunner-server/api/v2/plugins/kv"
 	"github.com/roadrunner-server/api/v2/plugins/pubsub"
 	"github.com/spiral/errors"
 	redis_kv "github.com/spiral/roadrunner-plugins/v2/redis/kv"
 	redis_pubsub "github.com/spiral/roadrunner-plugins/v2/redis/pubsub"
+	"github.com/spiral/roadrunner-plugins/v2/redis/redisjobs"
+	priorityqueue "github.com/spiral/roadrunner/v2/priority_queue"
 	"go.uber.org/zap"
 )
 
@@ -53,3 +58,40 @@ func (p *Plugin) PubSubFromConfig(key string) (pubsub.PubSub, error) {
 	}
 	return ps, nil
 }
+
+// ConsumerFromConfig provides the redis streams jobs driver, the connection is configured in the global redis section
+func (p *Plugin) ConsumerFromConfig(configKey string, pq priorityqueue.Queue) (jobs.Consumer, error) {
+	const op = errors.Op("redis_consumer_from_config")
+	opts, err := p.jobsOptions()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return redisjobs.FromConfig(configKey, p.log, p.cfgPlugin, opts, pq)
+}
+
+func (p *Plugin) ConsumerFromPipeline(pipe *pipeline.Pipeline, pq priorityqueue.Queue) (jobs.Consumer, error) {
+	const op = errors.Op("redis_consumer_from_pipeline")
+	opts, err := p.jobsOptions()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return redisjobs.FromPipeline(pipe, p.log, opts, pq)
+}
+
+// jobsOptions returns the client options from the global redis section
+func (p *Plugin) jobsOptions() (*goredis.UniversalOptions, error) {
+	if !p.cfgPlugin.Has(PluginName) {
+		return nil, errors.Str("no global redis configuration, global configuration should contain redis addrs")
+	}
+
+	var cfg Config
+	err := p.cfgPlugin.UnmarshalKey(PluginName, &cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.InitDefaults()
+	return cfg.UniversalOptions(), nil
+}
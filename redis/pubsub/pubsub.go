@@ -39,29 +39,7 @@ func NewPubSubDriver(log *zap.Logger, key string, cfgPlugin config.Configurer) (
 
 	ps.cfg.InitDefaults()
 
-	ps.universalClient = redis.NewUniversalClient(&redis.UniversalOptions{
-		Addrs:              ps.cfg.Addrs,
-		DB:                 ps.cfg.DB,
-		Username:           ps.cfg.Username,
-		Password:           ps.cfg.Password,
-		SentinelPassword:   ps.cfg.SentinelPassword,
-		MaxRetries:         ps.cfg.MaxRetries,
-		MinRetryBackoff:    ps.cfg.MaxRetryBackoff,
-		MaxRetryBackoff:    ps.cfg.MaxRetryBackoff,
-		DialTimeout:        ps.cfg.DialTimeout,
-		ReadTimeout:        ps.cfg.ReadTimeout,
-		WriteTimeout:       ps.cfg.WriteTimeout,
-		PoolSize:           ps.cfg.PoolSize,
-		MinIdleConns:       ps.cfg.MinIdleConns,
-		MaxConnAge:         ps.cfg.MaxConnAge,
-		PoolTimeout:        ps.cfg.PoolTimeout,
-		IdleTimeout:        ps.cfg.IdleTimeout,
-		IdleCheckFrequency: ps.cfg.IdleCheckFreq,
-		ReadOnly:           ps.cfg.ReadOnly,
-		RouteByLatency:     ps.cfg.RouteByLatency,
-		RouteRandomly:      ps.cfg.RouteRandomly,
-		MasterName:         ps.cfg.MasterName,
-	})
+	ps.universalClient = redis.NewUniversalClient(ps.cfg.UniversalOptions())
 
 	statusCmd := ps.universalClient.Ping(context.Background())
 	if statusCmd.Err() != nil {
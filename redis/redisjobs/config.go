@@ -0,0 +1,70 @@
+package redisjobs
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// pipeline redis streams info
+const (
+	pipeStream             string = "stream"
+	pipeGroup              string = "group"
+	pipeConsumer           string = "consumer"
+	pipePrefetch           string = "prefetch"
+	pipeClaimIdle          string = "claim_idle"
+	pipeDeleteStreamOnStop string = "delete_stream_on_stop"
+)
+
+// config is used to parse pipeline configuration, the connection is configured in the global redis section
+type config struct {
+	Priority int64 `mapstructure:"priority"`
+	// Stream is the name of the redis stream
+	Stream string `mapstructure:"stream"`
+	// Group is the consumer group of the stream shared by the RR instances
+	Group string `mapstructure:"group"`
+	// Consumer is the unique name of the RR instance in the group, default - hostname-pid
+	Consumer string `mapstructure:"consumer"`
+	// Prefetch is the max number of the jobs read from the stream and not yet acknowledged
+	Prefetch int64 `mapstructure:"prefetch"`
+	// ClaimIdle is the idle time of the pending (not acknowledged) jobs after which they are claimed by the consumer
+	ClaimIdle          time.Duration `mapstructure:"claim_idle"`
+	DeleteStreamOnStop bool          `mapstructure:"delete_stream_on_stop"`
+}
+
+func (c *config) InitDefaults() {
+	// all options should be in sync with the pipeline defaults in the FromPipeline method
+	if c.Priority == 0 {
+		c.Priority = 10
+	}
+
+	if c.Stream == "" {
+		c.Stream = "default"
+	}
+
+	if c.Group == "" {
+		c.Group = "roadrunner"
+	}
+
+	if c.Consumer == "" {
+		c.Consumer = consumerName()
+	}
+
+	if c.Prefetch == 0 {
+		c.Prefetch = 10
+	}
+
+	if c.ClaimIdle == 0 {
+		c.ClaimIdle = time.Minute * 5
+	}
+}
+
+// consumerName returns the default consumer name, unique for the RR instance
+func consumerName() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "roadrunner"
+	}
+
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
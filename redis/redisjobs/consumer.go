@@ -0,0 +1,426 @@
+package redisjobs
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	json "github.com/json-iterator/go"
+	cfgPlugin "github.com/roadrunner-server/api/v2/plugins/config"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"go.uber.org/zap"
+)
+
+const (
+	// field of the stream entry containing the job
+	dataField string = "data"
+	// timeout of the stream and the consumer group initialization
+	initTimeout = time.Second * 30
+)
+
+type consumer struct {
+	// system
+	sync.Mutex
+	log       *zap.Logger
+	queue     pq.Queue
+	listeners uint32
+	pipeline  atomic.Value
+	// cancels the listener and the maintenance goroutines
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	// redis
+	client redis.UniversalClient
+
+	// config
+	priority int64
+	stream   string
+	// sorted set of the delayed jobs IDs, the score is the time (unix ms) the job is ready
+	delayedKey string
+	// hash of the delayed jobs, keys are the jobs IDs
+	delayedJobsKey     string
+	group              string
+	consumer           string
+	prefetch           int64
+	claimIdle          time.Duration
+	deleteStreamOnStop bool
+
+	// number of the jobs read from the stream and not yet acknowledged
+	inflight int64
+	// stream entries IDs of the inflight jobs
+	taken     sync.Map
+	releaseCh chan struct{}
+}
+
+func FromConfig(configKey string, log *zap.Logger, cfg cfgPlugin.Configurer, opts *redis.UniversalOptions, queue pq.Queue) (*consumer, error) {
+	const op = errors.Op("new_redis_consumer")
+
+	if !cfg.Has(configKey) {
+		return nil, errors.E(op, errors.Errorf("no configuration by provided key: %s", configKey))
+	}
+
+	var conf config
+	err := cfg.UnmarshalKey(configKey, &conf)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	conf.InitDefaults()
+
+	c, err := newConsumer(&conf, log, opts, queue)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return c, nil
+}
+
+func FromPipeline(pipe *pipeline.Pipeline, log *zap.Logger, opts *redis.UniversalOptions, queue pq.Queue) (*consumer, error) {
+	const op = errors.Op("new_redis_consumer")
+
+	// the same duration string as in the configuration, e.g. 5m
+	claimIdle, err := time.ParseDuration(pipe.String(pipeClaimIdle, "5m"))
+	if err != nil {
+		return nil, errors.E(op, errors.Errorf("claim_idle should be a duration, e.g. 5m: %v", err))
+	}
+
+	conf := &config{
+		Priority:           pipe.Priority(),
+		Stream:             pipe.String(pipeStream, "default"),
+		Group:              pipe.String(pipeGroup, "roadrunner"),
+		Consumer:           pipe.String(pipeConsumer, ""),
+		Prefetch:           int64(pipe.Int(pipePrefetch, 10)),
+		ClaimIdle:          claimIdle,
+		DeleteStreamOnStop: pipe.Bool(pipeDeleteStreamOnStop, false),
+	}
+
+	conf.InitDefaults()
+
+	c, err := newConsumer(conf, log, opts, queue)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return c, nil
+}
+
+func newConsumer(conf *config, log *zap.Logger, opts *redis.UniversalOptions, queue pq.Queue) (*consumer, error) {
+	if conf.Prefetch < 0 {
+		return nil, errors.Errorf("prefetch should be positive, provided: %d", conf.Prefetch)
+	}
+
+	if conf.ClaimIdle < time.Second {
+		return nil, errors.Errorf("claim_idle should be at least 1s, provided: %s", conf.ClaimIdle)
+	}
+
+	c := &consumer{
+		log:   log,
+		queue: queue,
+
+		client:             redis.NewUniversalClient(opts),
+		priority:           conf.Priority,
+		stream:             conf.Stream,
+		delayedKey:         conf.Stream + ":delayed",
+		delayedJobsKey:     conf.Stream + ":delayed:jobs",
+		group:              conf.Group,
+		consumer:           conf.Consumer,
+		prefetch:           conf.Prefetch,
+		claimIdle:          conf.ClaimIdle,
+		deleteStreamOnStop: conf.DeleteStreamOnStop,
+		releaseCh:          make(chan struct{}, 1),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), initTimeout)
+	defer cancel()
+
+	err := c.createGroup(ctx)
+	if err != nil {
+		_ = c.client.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// createGroup creates the stream and the consumer group reading the stream from the beginning
+func (c *consumer) createGroup(ctx context.Context) error {
+	err := c.client.XGroupCreateMkStream(ctx, c.stream, c.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+func (c *consumer) Push(ctx context.Context, job *jobs.Job) error {
+	const op = errors.Op("redis_push")
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != job.Options.Pipeline {
+		return errors.E(op, errors.Errorf("no such pipeline: %s, actual: %s", job.Options.Pipeline, pipe.Name()))
+	}
+
+	err := c.handleItem(ctx, fromJob(job))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+func (c *consumer) Register(_ context.Context, p *pipeline.Pipeline) error {
+	c.pipeline.Store(p)
+	return nil
+}
+
+func (c *consumer) Run(_ context.Context, p *pipeline.Pipeline) error {
+	start := time.Now()
+	const op = errors.Op("redis_run")
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p.Name() {
+		return errors.E(op, errors.Errorf("no such pipeline registered: %s", pipe.Name()))
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// listener already active
+	if l == 1 {
+		c.log.Warn("redis listener is already in the active state")
+		return nil
+	}
+
+	c.startListener()
+	atomic.AddUint32(&c.listeners, 1)
+
+	c.log.Debug("pipeline was started", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+func (c *consumer) Pause(_ context.Context, p string) {
+	start := time.Now()
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p {
+		c.log.Error("no such pipeline", zap.String("requested", p), zap.String("actual", pipe.Name()))
+		return
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// no active listeners
+	if l == 0 {
+		c.log.Warn("no active listeners, nothing to pause")
+		return
+	}
+
+	atomic.AddUint32(&c.listeners, ^uint32(0))
+	c.stopListener()
+
+	c.log.Debug("pipeline was paused", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
+
+func (c *consumer) Resume(_ context.Context, p string) {
+	start := time.Now()
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p {
+		c.log.Error("no such pipeline", zap.String("requested", p), zap.String("actual", pipe.Name()))
+		return
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// listener already active
+	if l == 1 {
+		c.log.Warn("redis listener is already in the active state")
+		return
+	}
+
+	c.startListener()
+	atomic.AddUint32(&c.listeners, 1)
+
+	c.log.Debug("pipeline was resumed", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
+
+// State https://redis.io/commands/xinfo-groups
+func (c *consumer) State(ctx context.Context) (*jobs.State, error) {
+	const op = errors.Op("redis_state")
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+
+	st := &jobs.State{
+		Pipeline: pipe.Name(),
+		Driver:   pipe.Driver(),
+		Queue:    c.stream,
+		Ready:    ready(atomic.LoadUint32(&c.listeners)),
+	}
+
+	length, err := c.client.XLen(ctx, c.stream).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	group, err := c.groupInfo(ctx)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	// delivered to the consumers, but not acknowledged
+	st.Reserved = group.pending
+
+	// acknowledged entries are deleted from the stream
+	st.Active = length - st.Reserved
+	if st.Active < 0 {
+		st.Active = 0
+	}
+
+	st.Delayed, err = c.client.ZCard(ctx, c.delayedKey).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return st, nil
+}
+
+func (c *consumer) Stop(ctx context.Context) error {
+	start := time.Now()
+	const op = errors.Op("redis_stop")
+
+	if atomic.LoadUint32(&c.listeners) > 0 {
+		c.stopListener()
+	}
+
+	if c.deleteStreamOnStop {
+		err := c.client.Del(ctx, c.stream, c.delayedKey, c.delayedJobsKey).Err()
+		if err != nil {
+			return errors.E(op, err)
+		}
+	}
+
+	err := c.client.Close()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	c.log.Debug("pipeline was stopped", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// private
+
+// startListener starts the stream listener and the maintenance (delayed jobs, idle jobs claiming) goroutines
+func (c *consumer) startListener() {
+	c.Lock()
+	defer c.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(2)
+	go c.listen(ctx)
+	go c.maintain(ctx)
+}
+
+// stopListener stops the goroutines and waits for them
+func (c *consumer) stopListener() {
+	c.Lock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.Unlock()
+
+	c.wg.Wait()
+}
+
+// handleItem adds the job to the stream, the delayed job is stored in the delayed jobs set
+func (c *consumer) handleItem(ctx context.Context, item *Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	if item.Options.Delay > 0 {
+		_, err = c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+			p.ZAdd(ctx, c.delayedKey, &redis.Z{
+				Score:  float64(time.Now().Add(item.Options.DelayDuration()).UnixMilli()),
+				Member: item.ID(),
+			})
+			p.HSet(ctx, c.delayedJobsKey, item.ID(), data)
+			return nil
+		})
+		return err
+	}
+
+	return c.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: c.stream,
+		Values: map[string]interface{}{dataField: data},
+	}).Err()
+}
+
+// ack acknowledges the stream entry and deletes it, so the stream contains only the waiting and the pending jobs
+func (c *consumer) ack(id string) error {
+	ctx := context.Background()
+	_, err := c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.XAck(ctx, c.stream, c.group, id)
+		p.XDel(ctx, c.stream, id)
+		return nil
+	})
+
+	return err
+}
+
+// extend claims the pending entry by the same consumer to reset its idle time
+func (c *consumer) extend(id string) error {
+	return c.client.XClaimJustID(context.Background(), &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		Messages: []string{id},
+	}).Err()
+}
+
+// release frees the prefetch slot of the finished job
+func (c *consumer) release(id string) {
+	c.taken.Delete(id)
+	atomic.AddInt64(&c.inflight, -1)
+
+	select {
+	case c.releaseCh <- struct{}{}:
+	default:
+	}
+}
+
+func (c *consumer) respond(data []byte, queue string) error {
+	return c.client.XAdd(context.Background(), &redis.XAddArgs{
+		Stream: queue,
+		Values: map[string]interface{}{dataField: data},
+	}).Err()
+}
+
+// unpack decodes the job stored in the stream or in the delayed jobs hash
+func (c *consumer) unpack(data string) (*Item, error) {
+	item := &Item{}
+	err := json.Unmarshal([]byte(data), item)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Options == nil {
+		item.Options = &Options{}
+	}
+
+	if item.Options.Priority == 0 {
+		item.Options.Priority = c.priority
+	}
+
+	return item, nil
+}
+
+func ready(r uint32) bool {
+	return r > 0
+}
@@ -0,0 +1,323 @@
+package redisjobs
+
+import (
+	"context"
+	stderr "errors"
+	"strconv"
+	"strings"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+)
+
+// number of the stream entries inspected at once
+const inspectBatch int64 = 100
+
+// cancelDelayed deletes the delayed job and returns it, nil - no such job
+// KEYS: delayed jobs IDs (sorted set), delayed jobs (hash); ARGV: job ID
+var cancelDelayed = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return false
+end
+local data = redis.call('HGET', KEYS[2], ARGV[1])
+redis.call('HDEL', KEYS[2], ARGV[1])
+return data
+`)
+
+// Peek returns the waiting jobs of the stream (not delivered to the consumer group) followed by the delayed jobs
+func (c *consumer) Peek(ctx context.Context, limit, offset int) ([]*jobs.Job, error) {
+	const op = errors.Op("redis_peek")
+
+	start, err := c.waitingStart(ctx)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	msgs, err := c.client.XRangeN(ctx, c.stream, start, "+", int64(offset+limit)).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	res := make([]*jobs.Job, 0, limit)
+	for i := offset; i < len(msgs); i++ {
+		item, err := c.unpack(entryData(msgs[i]))
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		res = append(res, item.toJob())
+	}
+
+	if len(res) >= limit {
+		return res[:limit], nil
+	}
+
+	// all waiting jobs were inspected, the rest of the offset is applied to the delayed jobs
+	dOffset := offset - len(msgs)
+	if dOffset < 0 {
+		dOffset = 0
+	}
+
+	ids, err := c.client.ZRange(ctx, c.delayedKey, int64(dOffset), int64(dOffset+limit-len(res)-1)).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if len(ids) == 0 {
+		return res, nil
+	}
+
+	data, err := c.client.HMGet(ctx, c.delayedJobsKey, ids...).Result()
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	for i := 0; i < len(data); i++ {
+		// the job was moved to the stream or canceled
+		d, ok := data[i].(string)
+		if !ok {
+			continue
+		}
+
+		item, err := c.unpack(d)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		res = append(res, item.toJob())
+	}
+
+	return res, nil
+}
+
+// Delete removes the waiting or the delayed job, the stream is scanned from the first waiting entry
+func (c *consumer) Delete(ctx context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("redis_delete")
+
+	start, err := c.waitingStart(ctx)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	for {
+		msgs, err := c.client.XRangeN(ctx, c.stream, start, "+", inspectBatch).Result()
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		for i := 0; i < len(msgs); i++ {
+			item, err := c.unpack(entryData(msgs[i]))
+			if err != nil || item.ID() != id {
+				continue
+			}
+
+			err = c.client.XDel(ctx, c.stream, msgs[i].ID).Err()
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+
+			// the job was delivered to the consumer before the deletion
+			next, err := c.waitingStart(ctx)
+			if err != nil {
+				return nil, errors.E(op, err)
+			}
+
+			if entryLess(next, msgs[i].ID) || next == msgs[i].ID {
+				return item.toJob(), nil
+			}
+
+			return nil, nil
+		}
+
+		if int64(len(msgs)) < inspectBatch {
+			break
+		}
+
+		start = nextEntry(msgs[len(msgs)-1].ID)
+	}
+
+	j, err := c.Cancel(ctx, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return j, nil
+}
+
+// Cancel deletes the delayed job before it's moved to the stream
+func (c *consumer) Cancel(ctx context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("redis_cancel")
+
+	data, err := cancelDelayed.Run(ctx, c.client, []string{c.delayedKey, c.delayedJobsKey}, id).Text()
+	if err != nil {
+		if stderr.Is(err, redis.Nil) {
+			return nil, nil
+		}
+
+		return nil, errors.E(op, err)
+	}
+
+	item, err := c.unpack(data)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return item.toJob(), nil
+}
+
+// Purge deletes the waiting and the delayed jobs, pending (delivered to the consumers) jobs are not deleted
+func (c *consumer) Purge(ctx context.Context, fn func(*jobs.Job)) (int64, error) {
+	const op = errors.Op("redis_purge")
+
+	start, err := c.waitingStart(ctx)
+	if err != nil {
+		return 0, errors.E(op, err)
+	}
+
+	deleted := int64(0)
+	for {
+		msgs, err := c.client.XRangeN(ctx, c.stream, start, "+", inspectBatch).Result()
+		if err != nil {
+			return deleted, errors.E(op, err)
+		}
+
+		if len(msgs) == 0 {
+			break
+		}
+
+		ids := make([]string, 0, len(msgs))
+		for i := 0; i < len(msgs); i++ {
+			ids = append(ids, msgs[i].ID)
+		}
+
+		n, err := c.client.XDel(ctx, c.stream, ids...).Result()
+		if err != nil {
+			return deleted, errors.E(op, err)
+		}
+
+		deleted += n
+		c.purged(msgs, fn)
+		start = nextEntry(ids[len(ids)-1])
+	}
+
+	var delayed *redis.StringSliceCmd
+	_, err = c.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		delayed = p.HVals(ctx, c.delayedJobsKey)
+		p.Del(ctx, c.delayedKey, c.delayedJobsKey)
+		return nil
+	})
+	if err != nil {
+		return deleted, errors.E(op, err)
+	}
+
+	vals := delayed.Val()
+	for i := 0; i < len(vals); i++ {
+		item, err := c.unpack(vals[i])
+		if err != nil {
+			continue
+		}
+
+		fn(item.toJob())
+	}
+
+	return deleted + int64(len(vals)), nil
+}
+
+// purged calls the fn for the jobs of the deleted stream entries, entries not pushed by the RoadRunner are skipped
+func (c *consumer) purged(msgs []redis.XMessage, fn func(*jobs.Job)) {
+	for i := 0; i < len(msgs); i++ {
+		item, err := c.unpack(entryData(msgs[i]))
+		if err != nil {
+			continue
+		}
+
+		fn(item.toJob())
+	}
+}
+
+// waitingStart returns the ID of the first stream entry not delivered to the consumer group
+func (c *consumer) waitingStart(ctx context.Context) (string, error) {
+	group, err := c.groupInfo(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if group.lastDelivered == "" {
+		return "-", nil
+	}
+
+	return nextEntry(group.lastDelivered), nil
+}
+
+type groupInfo struct {
+	pending       int64
+	lastDelivered string
+}
+
+// groupInfo returns the consumer group info (https://redis.io/commands/xinfo-groups), zero info - no such group.
+// The reply is parsed manually, newer redis versions add the fields to the reply.
+func (c *consumer) groupInfo(ctx context.Context) (*groupInfo, error) {
+	groups, err := c.client.Do(ctx, "XINFO", "GROUPS", c.stream).Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(groups); i++ {
+		fields, ok := groups[i].([]interface{})
+		if !ok {
+			continue
+		}
+
+		info := make(map[string]interface{}, len(fields)/2)
+		for j := 0; j+1 < len(fields); j += 2 {
+			if key, ok := fields[j].(string); ok {
+				info[key] = fields[j+1]
+			}
+		}
+
+		if name, _ := info["name"].(string); name != c.group {
+			continue
+		}
+
+		res := &groupInfo{}
+		res.pending, _ = info["pending"].(int64)
+		res.lastDelivered, _ = info["last-delivered-id"].(string)
+		return res, nil
+	}
+
+	return &groupInfo{}, nil
+}
+
+func entryData(m redis.XMessage) string {
+	data, _ := m.Values[dataField].(string)
+	return data
+}
+
+// nextEntry returns the smallest possible ID of the stream entry following the entry
+func nextEntry(id string) string {
+	ms, seq := parseEntry(id)
+	return strconv.FormatUint(ms, 10) + "-" + strconv.FormatUint(seq+1, 10)
+}
+
+// entryLess compares the stream entries IDs
+func entryLess(a, b string) bool {
+	ams, aseq := parseEntry(a)
+	bms, bseq := parseEntry(b)
+	if ams != bms {
+		return ams < bms
+	}
+
+	return aseq < bseq
+}
+
+func parseEntry(id string) (uint64, uint64) {
+	parts := strings.SplitN(id, "-", 2)
+	ms, _ := strconv.ParseUint(parts[0], 10, 64)
+	if len(parts) == 1 {
+		return ms, 0
+	}
+
+	seq, _ := strconv.ParseUint(parts[1], 10, 64)
+	return ms, seq
+}
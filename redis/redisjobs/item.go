@@ -0,0 +1,186 @@
+package redisjobs
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+)
+
+type Item struct {
+	// Job contains name of job broker (usually PHP class).
+	Job string `json:"job"`
+
+	// Ident is unique identifier of the job, should be provided from outside
+	Ident string `json:"id"`
+
+	// Payload is string data (usually JSON) passed to Job broker.
+	Payload string `json:"payload"`
+
+	// Headers with key-values pairs
+	Headers map[string][]string `json:"headers"`
+
+	// Options contains set of PipelineOptions specific to job execution. Can be empty.
+	Options *Options `json:"options,omitempty"`
+}
+
+// Options carry information about how to handle given job.
+type Options struct {
+	// Priority is job priority, default - 10
+	// pointer to distinguish 0 as a priority and nil as priority not set
+	Priority int64 `json:"priority"`
+
+	// Pipeline manually specified pipeline.
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Delay defines time duration to delay execution for. Defaults to none.
+	Delay int64 `json:"delay,omitempty"`
+
+	// private
+	// stream entry ID
+	id string
+	// the job was acknowledged, nacked or requeued
+	done      uint32
+	ackFn     func(id string) error
+	extendFn  func(id string) error
+	releaseFn func(id string)
+	requeueFn func(context.Context, *Item) error
+	respondFn func([]byte, string) error
+}
+
+// DelayDuration returns delay duration in a form of time.Duration.
+func (o *Options) DelayDuration() time.Duration {
+	return time.Second * time.Duration(o.Delay)
+}
+
+func (i *Item) ID() string {
+	return i.Ident
+}
+
+func (i *Item) Priority() int64 {
+	return i.Options.Priority
+}
+
+// Body packs job payload into binary payload.
+func (i *Item) Body() []byte {
+	return utils.AsBytes(i.Payload)
+}
+
+// Context packs job context (job, id) into binary payload.
+func (i *Item) Context() ([]byte, error) {
+	ctx, err := json.Marshal(
+		struct {
+			ID       string              `json:"id"`
+			Job      string              `json:"job"`
+			Headers  map[string][]string `json:"headers"`
+			Pipeline string              `json:"pipeline"`
+		}{ID: i.Ident, Job: i.Job, Headers: i.Headers, Pipeline: i.Options.Pipeline},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// Ack acknowledges the stream entry and deletes it from the stream
+func (i *Item) Ack() error {
+	const op = errors.Op("redis_ack")
+	defer i.release()
+
+	err := i.Options.ackFn(i.Options.id)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Nack leaves the entry pending in the consumer group, the entry is claimed and delivered again after the claim_idle
+func (i *Item) Nack() error {
+	i.release()
+	return nil
+}
+
+// Extend claims the pending entry by the same consumer, redis resets the entry's idle time (the duration is not supported)
+func (i *Item) Extend(_ time.Duration) error {
+	const op = errors.Op("redis_extend")
+	err := i.Options.extendFn(i.Options.id)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Requeue pushes the job to the stream again and acknowledges the old entry
+func (i *Item) Requeue(headers map[string][]string, delay int64) error {
+	const op = errors.Op("redis_requeue")
+	defer i.release()
+
+	// overwrite the delay
+	i.Options.Delay = delay
+	i.Headers = headers
+
+	err := i.Options.requeueFn(context.Background(), i)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = i.Options.ackFn(i.Options.id)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+func (i *Item) Respond(data []byte, queue string) error {
+	const op = errors.Op("redis_respond")
+	err := i.Options.respondFn(data, queue)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// release frees the prefetch slot of the job once, the entry left in the stream after an error is claimed again
+func (i *Item) release() {
+	if atomic.CompareAndSwapUint32(&i.Options.done, 0, 1) && i.Options.releaseFn != nil {
+		i.Options.releaseFn(i.Options.id)
+	}
+}
+
+func (i *Item) toJob() *jobs.Job {
+	return &jobs.Job{
+		Job:     i.Job,
+		Ident:   i.Ident,
+		Payload: i.Payload,
+		Headers: i.Headers,
+		Options: &jobs.Options{
+			Priority: i.Options.Priority,
+			Pipeline: i.Options.Pipeline,
+			Delay:    i.Options.Delay,
+		},
+	}
+}
+
+func fromJob(job *jobs.Job) *Item {
+	return &Item{
+		Job:     job.Job,
+		Ident:   job.Ident,
+		Payload: job.Payload,
+		Headers: job.Headers,
+		Options: &Options{
+			Priority: job.Options.Priority,
+			Pipeline: job.Options.Pipeline,
+			Delay:    job.Options.Delay,
+		},
+	}
+}
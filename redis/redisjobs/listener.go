@@ -0,0 +1,229 @@
+package redisjobs
+
+import (
+	"context"
+	stderr "errors"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+const (
+	// max time the XREADGROUP blocks, the stop signal is checked between the reads
+	blockTimeout = time.Second
+	// period of moving the ready delayed jobs to the stream
+	delayedPeriod = time.Second
+	// max number of the delayed jobs moved at once
+	delayedBatch int = 100
+)
+
+// moveDelayed moves the ready delayed jobs to the stream atomically
+// KEYS: delayed jobs IDs (sorted set), delayed jobs (hash), stream; ARGV: now (unix ms), max number of the jobs
+var moveDelayed = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, ARGV[2])
+for _, id in ipairs(ids) do
+	local data = redis.call('HGET', KEYS[2], id)
+	if data then
+		redis.call('XADD', KEYS[3], '*', '` + dataField + `', data)
+	end
+	redis.call('ZREM', KEYS[1], id)
+	redis.call('HDEL', KEYS[2], id)
+end
+return #ids
+`)
+
+func (c *consumer) listen(ctx context.Context) {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Debug("redis listener stopped")
+			return
+		default:
+		}
+
+		free := c.prefetch - atomic.LoadInt64(&c.inflight)
+		// wait for the acknowledgements
+		if free <= 0 {
+			select {
+			case <-ctx.Done():
+			case <-c.releaseCh:
+			case <-time.After(blockTimeout):
+			}
+			continue
+		}
+
+		res, err := c.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    c.group,
+			Consumer: c.consumer,
+			Streams:  []string{c.stream, ">"},
+			Count:    free,
+			Block:    blockTimeout,
+		}).Result()
+		if err != nil {
+			// block timeout
+			if stderr.Is(err, redis.Nil) || ctx.Err() != nil {
+				continue
+			}
+
+			c.log.Warn("redis stream read", zap.Error(err), zap.String("stream", c.stream))
+
+			// the stream was deleted
+			if strings.HasPrefix(err.Error(), "NOGROUP") {
+				errC := c.createGroup(ctx)
+				if errC != nil {
+					c.log.Error("failed to create the redis consumer group", zap.Error(errC), zap.String("stream", c.stream), zap.String("group", c.group))
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(blockTimeout):
+			}
+			continue
+		}
+
+		for i := 0; i < len(res); i++ {
+			for j := 0; j < len(res[i].Messages); j++ {
+				c.insert(res[i].Messages[j])
+			}
+		}
+	}
+}
+
+// insert inserts the stream entry into the priority queue
+func (c *consumer) insert(m redis.XMessage) {
+	data, ok := m.Values[dataField].(string)
+	if !ok {
+		c.log.Warn("stream entry doesn't contain the job, entry will be acknowledged", zap.String("stream", c.stream), zap.String("entry", m.ID))
+		_ = c.ack(m.ID)
+		return
+	}
+
+	item, err := c.unpack(data)
+	if err != nil {
+		c.log.Error("redis unpack item, entry will be acknowledged", zap.Error(err), zap.String("stream", c.stream), zap.String("entry", m.ID))
+		_ = c.ack(m.ID)
+		return
+	}
+
+	item.Options.id = m.ID
+	item.Options.ackFn = c.ack
+	item.Options.extendFn = c.extend
+	item.Options.releaseFn = c.release
+	item.Options.requeueFn = c.handleItem
+	item.Options.respondFn = c.respond
+
+	c.taken.Store(m.ID, struct{}{})
+	atomic.AddInt64(&c.inflight, 1)
+
+	c.queue.Insert(item)
+}
+
+// maintain moves the ready delayed jobs to the stream and claims the idle pending jobs
+func (c *consumer) maintain(ctx context.Context) {
+	defer c.wg.Done()
+
+	delayed := time.NewTicker(delayedPeriod)
+	defer delayed.Stop()
+
+	claim := time.NewTicker(c.claimIdle / 2)
+	defer claim.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-delayed.C:
+			for {
+				n, err := moveDelayed.Run(ctx, c.client, []string{c.delayedKey, c.delayedJobsKey, c.stream}, time.Now().UnixMilli(), delayedBatch).Int()
+				if err != nil {
+					if ctx.Err() == nil {
+						c.log.Error("failed to move the delayed jobs", zap.Error(err), zap.String("stream", c.stream))
+					}
+					break
+				}
+
+				if n < delayedBatch {
+					break
+				}
+			}
+		case <-claim.C:
+			err := c.claim(ctx)
+			if err != nil && ctx.Err() == nil {
+				c.log.Error("failed to claim the idle jobs", zap.Error(err), zap.String("stream", c.stream))
+			}
+		}
+	}
+}
+
+// claim takes the pending jobs idle for the claim_idle, e.g. jobs of the crashed RR instance
+func (c *consumer) claim(ctx context.Context) error {
+	free := c.prefetch - atomic.LoadInt64(&c.inflight)
+	if free <= 0 {
+		return nil
+	}
+
+	pending, err := c.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: c.stream,
+		Group:  c.group,
+		Idle:   c.claimIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  c.prefetch,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(pending))
+	for i := 0; i < len(pending) && int64(len(ids)) < free; i++ {
+		// the job is still executed by this consumer
+		if _, ok := c.taken.Load(pending[i].ID); ok {
+			continue
+		}
+
+		ids = append(ids, pending[i].ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	// the min idle time protects from the concurrent claims of the other consumers
+	claimed, err := c.client.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   c.stream,
+		Group:    c.group,
+		Consumer: c.consumer,
+		MinIdle:  c.claimIdle,
+		Messages: ids,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < len(claimed); i++ {
+		msgs, err := c.client.XRange(ctx, c.stream, claimed[i], claimed[i]).Result()
+		if err != nil {
+			return err
+		}
+
+		// the entry was deleted
+		if len(msgs) == 0 {
+			_ = c.ack(claimed[i])
+			continue
+		}
+
+		c.insert(msgs[0])
+	}
+
+	if len(claimed) > 0 {
+		c.log.Info("idle jobs were claimed", zap.String("stream", c.stream), zap.Int("jobs", len(claimed)))
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package spool
+
+import (
+	"github.com/roadrunner-server/api/v2/plugins/config"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/roadrunner-plugins/v2/spool/spooljobs"
+	priorityqueue "github.com/spiral/roadrunner/v2/priority_queue"
+	"go.uber.org/zap"
+)
+
+const (
+	PluginName string = "spool"
+)
+
+// Plugin is the local filesystem spool jobs driver
+type Plugin struct {
+	cfg config.Configurer
+	log *zap.Logger
+}
+
+func (p *Plugin) Init(log *zap.Logger, cfg config.Configurer) error {
+	p.log = new(zap.Logger)
+	*p.log = *log
+	p.cfg = cfg
+	return nil
+}
+
+// Name returns plugin name
+func (p *Plugin) Name() string {
+	return PluginName
+}
+
+func (p *Plugin) ConsumerFromConfig(configKey string, queue priorityqueue.Queue) (jobs.Consumer, error) {
+	return spooljobs.FromConfig(configKey, p.log, p.cfg, queue)
+}
+
+func (p *Plugin) ConsumerFromPipeline(pipe *pipeline.Pipeline, queue priorityqueue.Queue) (jobs.Consumer, error) {
+	return spooljobs.FromPipeline(pipe, p.log, queue)
+}
@@ -0,0 +1,57 @@
+package spooljobs
+
+import "time"
+
+const (
+	dir          string = "dir"
+	prefetch     string = "prefetch"
+	permissions  string = "permissions"
+	leaseTimeout string = "lease_timeout"
+
+	// spool subdirectories
+	readyDir    string = "ready"
+	reservedDir string = "reserved"
+	delayedDir  string = "delayed"
+	failedDir   string = "failed"
+	// new jobs are written here and renamed to the ready or delayed directory
+	tmpDir string = "tmp"
+	// lease files of the processes consuming the spool
+	ownersDir string = "owners"
+
+	defaultDir string = "rr-spool"
+)
+
+type config struct {
+	// Dir is the spool directory of the pipeline
+	Dir      string `mapstructure:"dir"`
+	Priority int64  `mapstructure:"priority"`
+	// Prefetch is the max number of the reserved and not yet acknowledged jobs of the process
+	Prefetch int `mapstructure:"prefetch"`
+	// Permissions of the spool directories, files are created without the execute bits
+	Permissions int `mapstructure:"permissions"`
+	// LeaseTimeout is the time after which the jobs reserved by the crashed process are returned to the ready directory
+	LeaseTimeout time.Duration `mapstructure:"lease_timeout"`
+}
+
+func (c *config) InitDefaults() {
+	// all options should be in sync with the pipeline defaults in the FromPipeline method
+	if c.Dir == "" {
+		c.Dir = defaultDir
+	}
+
+	if c.Priority == 0 {
+		c.Priority = 10
+	}
+
+	if c.Prefetch == 0 {
+		c.Prefetch = 100
+	}
+
+	if c.Permissions == 0 {
+		c.Permissions = 0755
+	}
+
+	if c.LeaseTimeout == 0 {
+		c.LeaseTimeout = time.Second * 30
+	}
+}
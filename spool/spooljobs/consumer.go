@@ -0,0 +1,323 @@
+package spooljobs
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	cfgPlugin "github.com/roadrunner-server/api/v2/plugins/config"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	"github.com/spiral/errors"
+	pq "github.com/spiral/roadrunner/v2/priority_queue"
+	"go.uber.org/zap"
+)
+
+type consumer struct {
+	// system
+	sync.Mutex
+	log       *zap.Logger
+	queue     pq.Queue
+	listeners uint32
+	pipeline  atomic.Value
+	// cancels the listener goroutine
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	// cancels the maintenance goroutine, it works while the pipeline is paused
+	maintainCancel context.CancelFunc
+	maintainWg     sync.WaitGroup
+
+	spool *spool
+
+	// config
+	priority     int64
+	prefetch     int64
+	leaseTimeout time.Duration
+
+	// number of the reserved and not yet acknowledged jobs
+	inflight  int64
+	releaseCh chan struct{}
+}
+
+func FromConfig(configKey string, log *zap.Logger, cfg cfgPlugin.Configurer, queue pq.Queue) (*consumer, error) {
+	const op = errors.Op("new_spool_consumer")
+
+	if !cfg.Has(configKey) {
+		return nil, errors.E(op, errors.Errorf("no configuration by provided key: %s", configKey))
+	}
+
+	var conf config
+	err := cfg.UnmarshalKey(configKey, &conf)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	conf.InitDefaults()
+
+	c, err := newConsumer(&conf, log, queue)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return c, nil
+}
+
+func FromPipeline(pipe *pipeline.Pipeline, log *zap.Logger, queue pq.Queue) (*consumer, error) {
+	const op = errors.Op("new_spool_consumer")
+
+	conf := &config{
+		Dir:          pipe.String(dir, defaultDir),
+		Priority:     pipe.Priority(),
+		Prefetch:     pipe.Int(prefetch, 100),
+		Permissions:  pipe.Int(permissions, 0755),
+		LeaseTimeout: time.Second * time.Duration(pipe.Int(leaseTimeout, 30)),
+	}
+
+	conf.InitDefaults()
+
+	c, err := newConsumer(conf, log, queue)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return c, nil
+}
+
+func newConsumer(conf *config, log *zap.Logger, queue pq.Queue) (*consumer, error) {
+	if conf.Prefetch < 0 {
+		return nil, errors.Errorf("prefetch should be positive, provided: %d", conf.Prefetch)
+	}
+
+	if conf.LeaseTimeout < time.Second {
+		return nil, errors.Errorf("lease_timeout should be at least 1s, provided: %s", conf.LeaseTimeout)
+	}
+
+	s, err := newSpool(conf.Dir, os.FileMode(conf.Permissions))
+	if err != nil {
+		return nil, err
+	}
+
+	c := &consumer{
+		log:   log,
+		queue: queue,
+		spool: s,
+
+		priority:     conf.Priority,
+		prefetch:     int64(conf.Prefetch),
+		leaseTimeout: conf.LeaseTimeout,
+		releaseCh:    make(chan struct{}, 1),
+	}
+
+	err = s.touch()
+	if err != nil {
+		return nil, err
+	}
+
+	// return the jobs of the crashed processes
+	n, err := s.recoverReserved(c.leaseTimeout)
+	if err != nil {
+		_ = s.release()
+		return nil, err
+	}
+
+	if n > 0 {
+		c.log.Warn("reserved jobs of the stopped processes were returned to the spool", zap.Int("count", n), zap.String("dir", s.dir))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.maintainCancel = cancel
+	c.maintainWg.Add(1)
+	go c.maintain(ctx)
+
+	return c, nil
+}
+
+func (c *consumer) Push(_ context.Context, job *jobs.Job) error {
+	const op = errors.Op("spool_push")
+	// check if the pipeline registered
+	_, ok := c.pipeline.Load().(*pipeline.Pipeline)
+	if !ok {
+		return errors.E(op, errors.Errorf("no such pipeline: %s", job.Options.Pipeline))
+	}
+
+	err := c.spool.write(fromJob(job))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+func (c *consumer) Register(_ context.Context, p *pipeline.Pipeline) error {
+	c.pipeline.Store(p)
+	return nil
+}
+
+func (c *consumer) Run(_ context.Context, p *pipeline.Pipeline) error {
+	start := time.Now()
+	const op = errors.Op("spool_run")
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p.Name() {
+		return errors.E(op, errors.Errorf("no such pipeline registered: %s", pipe.Name()))
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// listener already active
+	if l == 1 {
+		c.log.Warn("spool listener is already in the active state")
+		return nil
+	}
+
+	c.startListener()
+	atomic.AddUint32(&c.listeners, 1)
+
+	c.log.Debug("pipeline was started", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+func (c *consumer) Pause(_ context.Context, p string) {
+	start := time.Now()
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p {
+		c.log.Error("no such pipeline", zap.String("requested", p), zap.String("actual", pipe.Name()))
+		return
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// no active listeners
+	if l == 0 {
+		c.log.Warn("no active listeners, nothing to pause")
+		return
+	}
+
+	atomic.AddUint32(&c.listeners, ^uint32(0))
+	c.stopListener()
+
+	c.log.Debug("pipeline was paused", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
+
+func (c *consumer) Resume(_ context.Context, p string) {
+	start := time.Now()
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	if pipe.Name() != p {
+		c.log.Error("no such pipeline", zap.String("requested", p), zap.String("actual", pipe.Name()))
+		return
+	}
+
+	l := atomic.LoadUint32(&c.listeners)
+	// listener already active
+	if l == 1 {
+		c.log.Warn("spool listener is already in the active state")
+		return
+	}
+
+	c.startListener()
+	atomic.AddUint32(&c.listeners, 1)
+
+	c.log.Debug("pipeline was resumed", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+}
+
+// State counts the jobs files, the reserved jobs include the jobs of all processes sharing the spool
+func (c *consumer) State(_ context.Context) (*jobs.State, error) {
+	const op = errors.Op("spool_state")
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+
+	st := &jobs.State{
+		Pipeline: pipe.Name(),
+		Driver:   pipe.Driver(),
+		Queue:    c.spool.dir,
+		Ready:    ready(atomic.LoadUint32(&c.listeners)),
+	}
+
+	counters := []*int64{&st.Active, &st.Reserved, &st.Delayed}
+	for i, sub := range []string{readyDir, reservedDir, delayedDir} {
+		names, err := c.spool.list(sub)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		*counters[i] = int64(len(names))
+	}
+
+	return st, nil
+}
+
+// Stop returns the reserved jobs to the ready directory and releases the lease of the consumer
+func (c *consumer) Stop(_ context.Context) error {
+	start := time.Now()
+	const op = errors.Op("spool_stop")
+
+	if atomic.LoadUint32(&c.listeners) > 0 {
+		c.stopListener()
+	}
+
+	c.Lock()
+	if c.maintainCancel != nil {
+		c.maintainCancel()
+		c.maintainCancel = nil
+	}
+	c.Unlock()
+	c.maintainWg.Wait()
+
+	n, err := c.spool.returnReserved()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	if n > 0 {
+		c.log.Debug("reserved jobs were returned to the spool", zap.Int("count", n), zap.String("dir", c.spool.dir))
+	}
+
+	err = c.spool.release()
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	pipe := c.pipeline.Load().(*pipeline.Pipeline)
+	c.log.Debug("pipeline was stopped", zap.String("driver", pipe.Driver()), zap.String("pipeline", pipe.Name()), zap.Time("start", start), zap.Duration("elapsed", time.Since(start)))
+	return nil
+}
+
+// private
+
+func (c *consumer) startListener() {
+	c.Lock()
+	defer c.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+
+	c.wg.Add(1)
+	go c.listen(ctx)
+}
+
+// stopListener stops the listener and waits for it
+func (c *consumer) stopListener() {
+	c.Lock()
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+	c.Unlock()
+
+	c.wg.Wait()
+}
+
+// release frees the prefetch slot of the finished job
+func (c *consumer) release() {
+	atomic.AddInt64(&c.inflight, -1)
+
+	select {
+	case c.releaseCh <- struct{}{}:
+	default:
+	}
+}
+
+func ready(r uint32) bool {
+	return r > 0
+}
@@ -0,0 +1,178 @@
+package spooljobs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
+	priorityqueue "github.com/spiral/roadrunner/v2/priority_queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func testConsumer(t *testing.T, dir string) (*consumer, *priorityqueue.BinHeap) {
+	queue := priorityqueue.NewBinHeap(100)
+	conf := &config{Dir: dir, LeaseTimeout: time.Second * 3}
+	conf.InitDefaults()
+
+	c, err := newConsumer(conf, zap.NewNop(), queue)
+	require.NoError(t, err)
+	require.NoError(t, c.Register(context.Background(), &pipeline.Pipeline{"name": "test", "driver": "spool"}))
+
+	return c, queue
+}
+
+func testJob(id string, delay int64) *jobs.Job {
+	return &jobs.Job{
+		Job:     "test",
+		Ident:   id,
+		Payload: `{"hello":"world"}`,
+		Headers: map[string][]string{},
+		Options: &jobs.Options{Pipeline: "test", Delay: delay},
+	}
+}
+
+// extract waits for the job in the priority queue, ExtractMin blocks on the empty queue
+func extract(t *testing.T, queue *priorityqueue.BinHeap) *Item {
+	require.Eventually(t, func() bool { return queue.Len() > 0 }, time.Second*5, time.Millisecond*10)
+	return queue.ExtractMin().(*Item)
+}
+
+func countFiles(t *testing.T, dir, sub string) int {
+	entries, err := os.ReadDir(filepath.Join(dir, sub))
+	require.NoError(t, err)
+	return len(entries)
+}
+
+func TestSpoolConsume(t *testing.T) {
+	dir := t.TempDir()
+	c, queue := testConsumer(t, dir)
+	ctx := context.Background()
+
+	require.NoError(t, c.Push(ctx, testJob("1", 0)))
+	require.NoError(t, c.Push(ctx, testJob("2", 0)))
+	require.NoError(t, c.Push(ctx, testJob("3", 1)))
+
+	st, err := c.State(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), st.Active)
+	assert.Equal(t, int64(1), st.Delayed)
+
+	require.NoError(t, c.Run(ctx, &pipeline.Pipeline{"name": "test", "driver": "spool"}))
+
+	// jobs are reserved in the push order
+	first := extract(t, queue)
+	second := extract(t, queue)
+	assert.Equal(t, "1", first.ID())
+	assert.Equal(t, "2", second.ID())
+	assert.Equal(t, 2, countFiles(t, dir, reservedDir))
+
+	require.NoError(t, first.Ack())
+	require.NoError(t, second.Nack())
+	assert.Equal(t, 0, countFiles(t, dir, reservedDir))
+	assert.Equal(t, 1, countFiles(t, dir, failedDir))
+	assert.Equal(t, int64(0), atomic.LoadInt64(&c.inflight))
+
+	delayed := extract(t, queue)
+	assert.Equal(t, "3", delayed.ID())
+
+	// requeued job is reserved again
+	require.NoError(t, delayed.Requeue(map[string][]string{"attempt": {"1"}}, 0))
+	requeued := extract(t, queue)
+	assert.Equal(t, "3", requeued.ID())
+	assert.Equal(t, []string{"1"}, requeued.Headers["attempt"])
+
+	// the reserved job is returned to the ready directory on stop
+	require.NoError(t, c.Stop(ctx))
+	assert.Equal(t, 0, countFiles(t, dir, reservedDir))
+	assert.Equal(t, 1, countFiles(t, dir, readyDir))
+	assert.Equal(t, 0, countFiles(t, dir, ownersDir))
+}
+
+func TestSpoolRecovery(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	crashed, _ := testConsumer(t, dir)
+	require.NoError(t, crashed.Push(ctx, testJob("1", 0)))
+	_, err := crashed.spool.reserve(mustList(t, crashed.spool, readyDir)[0])
+	require.NoError(t, err)
+
+	// simulate the crash: the maintenance goroutine is stopped, the lease is not renewed
+	crashed.maintainCancel()
+	crashed.maintainWg.Wait()
+
+	// the lease is alive
+	alive, _ := testConsumer(t, dir)
+	assert.Equal(t, 1, countFiles(t, dir, reservedDir))
+	require.NoError(t, alive.Stop(ctx))
+
+	// the lease is stale
+	past := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(crashed.spool.path(ownersDir, crashed.spool.owner), past, past))
+
+	c, queue := testConsumer(t, dir)
+	assert.Equal(t, 0, countFiles(t, dir, reservedDir))
+	assert.Equal(t, 1, countFiles(t, dir, ownersDir))
+
+	require.NoError(t, c.Run(ctx, &pipeline.Pipeline{"name": "test", "driver": "spool"}))
+	item := extract(t, queue)
+	assert.Equal(t, "1", item.ID())
+	require.NoError(t, item.Ack())
+	require.NoError(t, c.Stop(ctx))
+}
+
+func TestSpoolInspect(t *testing.T) {
+	dir := t.TempDir()
+	c, _ := testConsumer(t, dir)
+	ctx := context.Background()
+
+	require.NoError(t, c.Push(ctx, testJob("1", 0)))
+	require.NoError(t, c.Push(ctx, testJob("2", 0)))
+	require.NoError(t, c.Push(ctx, testJob("3", 60)))
+
+	peeked, err := c.Peek(ctx, 10, 1)
+	require.NoError(t, err)
+	require.Len(t, peeked, 2)
+	assert.Equal(t, "2", peeked[0].Ident)
+	assert.Equal(t, "3", peeked[1].Ident)
+
+	// only the delayed jobs are canceled
+	j, err := c.Cancel(ctx, "1")
+	require.NoError(t, err)
+	assert.Nil(t, j)
+
+	j, err = c.Cancel(ctx, "3")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+	assert.Equal(t, int64(60), j.Options.Delay)
+
+	j, err = c.Delete(ctx, "1")
+	require.NoError(t, err)
+	require.NotNil(t, j)
+	assert.Equal(t, "1", j.Ident)
+
+	var purged []*jobs.Job
+	n, err := c.Purge(ctx, func(j *jobs.Job) {
+		purged = append(purged, j)
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, 0, countFiles(t, dir, readyDir))
+	require.Len(t, purged, 1)
+	assert.Equal(t, "2", purged[0].Ident)
+
+	require.NoError(t, c.Stop(ctx))
+}
+
+func mustList(t *testing.T, s *spool, sub string) []string {
+	names, err := s.list(sub)
+	require.NoError(t, err)
+	return names
+}
@@ -0,0 +1,142 @@
+package spooljobs
+
+import (
+	"context"
+	stderr "errors"
+	"os"
+
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+)
+
+// Peek returns the ready jobs followed by the delayed jobs
+func (c *consumer) Peek(_ context.Context, limit, offset int) ([]*jobs.Job, error) {
+	const op = errors.Op("spool_peek")
+
+	result := make([]*jobs.Job, 0, limit)
+	for _, sub := range []string{readyDir, delayedDir} {
+		names, err := c.spool.list(sub)
+		if err != nil {
+			return nil, errors.E(op, err)
+		}
+
+		for i := 0; i < len(names) && len(result) < limit; i++ {
+			item, err := c.spool.read(c.spool.path(sub, names[i]))
+			if err != nil {
+				// reserved in the meantime
+				if stderr.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				return nil, errors.E(op, err)
+			}
+
+			if offset > 0 {
+				offset--
+				continue
+			}
+
+			result = append(result, item.toJob())
+		}
+	}
+
+	return result, nil
+}
+
+// Delete removes the ready or the delayed job
+func (c *consumer) Delete(ctx context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("spool_delete")
+
+	j, err := c.remove(readyDir, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	if j != nil {
+		return j, nil
+	}
+
+	j, err = c.Cancel(ctx, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return j, nil
+}
+
+// Cancel removes the delayed job before it's moved to the ready directory
+func (c *consumer) Cancel(_ context.Context, id string) (*jobs.Job, error) {
+	const op = errors.Op("spool_cancel")
+
+	j, err := c.remove(delayedDir, id)
+	if err != nil {
+		return nil, errors.E(op, err)
+	}
+
+	return j, nil
+}
+
+// Purge removes the ready and the delayed jobs, reserved jobs are not removed
+func (c *consumer) Purge(_ context.Context, fn func(*jobs.Job)) (int64, error) {
+	const op = errors.Op("spool_purge")
+
+	deleted := int64(0)
+	for _, sub := range []string{readyDir, delayedDir} {
+		names, err := c.spool.list(sub)
+		if err != nil {
+			return deleted, errors.E(op, err)
+		}
+
+		for i := 0; i < len(names); i++ {
+			path := c.spool.path(sub, names[i])
+			item, errR := c.spool.read(path)
+
+			err = os.Remove(path)
+			if err != nil {
+				// reserved or moved in the meantime
+				if stderr.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				return deleted, errors.E(op, err)
+			}
+
+			deleted++
+			if errR == nil {
+				fn(item.toJob())
+			}
+		}
+	}
+
+	return deleted, nil
+}
+
+// remove finds the job by its ID in the subdirectory and removes its file, nil job - no such job or the job was taken
+func (c *consumer) remove(sub, id string) (*jobs.Job, error) {
+	names, err := c.spool.list(sub)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(names); i++ {
+		path := c.spool.path(sub, names[i])
+		item, err := c.spool.read(path)
+		if err != nil || item.ID() != id {
+			continue
+		}
+
+		// the remove fails if the job was reserved (renamed) after the read
+		err = os.Remove(path)
+		if err != nil {
+			if stderr.Is(err, os.ErrNotExist) {
+				return nil, nil
+			}
+
+			return nil, err
+		}
+
+		return item.toJob(), nil
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,174 @@
+package spooljobs
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/spiral/errors"
+	"github.com/spiral/roadrunner/v2/utils"
+)
+
+type Item struct {
+	// Job contains name of job broker (usually PHP class).
+	Job string `json:"job"`
+
+	// Ident is unique identifier of the job, should be provided from outside
+	Ident string `json:"id"`
+
+	// Payload is string data (usually JSON) passed to Job broker.
+	Payload string `json:"payload"`
+
+	// Headers with key-values pairs
+	Headers map[string][]string `json:"headers"`
+
+	// Options contains set of PipelineOptions specific to job execution. Can be empty.
+	Options *Options `json:"options,omitempty"`
+}
+
+// Options carry information about how to handle given job.
+type Options struct {
+	// Priority is job priority, default - 10
+	// pointer to distinguish 0 as a priority and nil as priority not set
+	Priority int64 `json:"priority"`
+
+	// Pipeline manually specified pipeline.
+	Pipeline string `json:"pipeline,omitempty"`
+
+	// Delay defines time duration to delay execution for. Defaults to none.
+	Delay int64 `json:"delay,omitempty"`
+
+	// private
+	// name of the job's file in the ready directory
+	name  string
+	spool *spool
+	// the job was acknowledged, nacked or requeued
+	done      uint32
+	releaseFn func()
+}
+
+// DelayDuration returns delay duration in a form of time.Duration.
+func (o *Options) DelayDuration() time.Duration {
+	return time.Second * time.Duration(o.Delay)
+}
+
+func (i *Item) ID() string {
+	return i.Ident
+}
+
+func (i *Item) Priority() int64 {
+	return i.Options.Priority
+}
+
+// Body packs job payload into binary payload.
+func (i *Item) Body() []byte {
+	return utils.AsBytes(i.Payload)
+}
+
+// Context packs job context (job, id) into binary payload.
+func (i *Item) Context() ([]byte, error) {
+	ctx, err := json.Marshal(
+		struct {
+			ID       string              `json:"id"`
+			Job      string              `json:"job"`
+			Headers  map[string][]string `json:"headers"`
+			Pipeline string              `json:"pipeline"`
+		}{ID: i.Ident, Job: i.Job, Headers: i.Headers, Pipeline: i.Options.Pipeline},
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ctx, nil
+}
+
+// Ack removes the reserved job's file
+func (i *Item) Ack() error {
+	const op = errors.Op("spool_ack")
+	defer i.release()
+
+	err := os.Remove(i.Options.spool.reserved(i.Options.name))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Nack moves the reserved job's file to the failed directory
+func (i *Item) Nack() error {
+	const op = errors.Op("spool_nack")
+	defer i.release()
+
+	err := os.Rename(i.Options.spool.reserved(i.Options.name), i.Options.spool.path(failedDir, i.Options.name))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Requeue writes the job to the spool again and removes the reserved job's file
+func (i *Item) Requeue(headers map[string][]string, delay int64) error {
+	const op = errors.Op("spool_requeue")
+	defer i.release()
+
+	// overwrite the delay
+	i.Options.Delay = delay
+	i.Headers = headers
+
+	err := i.Options.spool.write(i)
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	err = os.Remove(i.Options.spool.reserved(i.Options.name))
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
+// Respond is not supported by the spool
+func (i *Item) Respond(_ []byte, _ string) error {
+	return nil
+}
+
+// release frees the prefetch slot of the job once
+func (i *Item) release() {
+	if atomic.CompareAndSwapUint32(&i.Options.done, 0, 1) && i.Options.releaseFn != nil {
+		i.Options.releaseFn()
+	}
+}
+
+func (i *Item) toJob() *jobs.Job {
+	return &jobs.Job{
+		Job:     i.Job,
+		Ident:   i.Ident,
+		Payload: i.Payload,
+		Headers: i.Headers,
+		Options: &jobs.Options{
+			Priority: i.Options.Priority,
+			Pipeline: i.Options.Pipeline,
+			Delay:    i.Options.Delay,
+		},
+	}
+}
+
+func fromJob(job *jobs.Job) *Item {
+	return &Item{
+		Job:     job.Job,
+		Ident:   job.Ident,
+		Payload: job.Payload,
+		Headers: job.Headers,
+		Options: &Options{
+			Priority: job.Options.Priority,
+			Pipeline: job.Options.Pipeline,
+			Delay:    job.Options.Delay,
+		},
+	}
+}
@@ -0,0 +1,118 @@
+package spooljobs
+
+import (
+	"context"
+	stderr "errors"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// period of the ready directory polling
+	pollPeriod = time.Millisecond * 100
+	// period of moving the ready delayed jobs to the ready directory
+	delayedPeriod = time.Second
+)
+
+func (c *consumer) listen(ctx context.Context) {
+	defer c.wg.Done()
+
+	poll := time.NewTicker(pollPeriod)
+	defer poll.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.log.Debug("spool listener stopped")
+			return
+		case <-poll.C:
+		case <-c.releaseCh:
+		}
+
+		free := c.prefetch - atomic.LoadInt64(&c.inflight)
+		// wait for the acknowledgements
+		if free <= 0 {
+			continue
+		}
+
+		names, err := c.spool.list(readyDir)
+		if err != nil {
+			c.log.Error("failed to read the spool", zap.Error(err), zap.String("dir", c.spool.dir))
+			continue
+		}
+
+		for i := 0; i < len(names) && free > 0; i++ {
+			if ctx.Err() != nil {
+				break
+			}
+
+			item, err := c.spool.reserve(names[i])
+			if err != nil {
+				// reserved by another process
+				if stderr.Is(err, os.ErrNotExist) {
+					continue
+				}
+
+				c.log.Error("failed to reserve the job", zap.Error(err), zap.String("dir", c.spool.dir), zap.String("file", names[i]))
+				continue
+			}
+
+			c.insert(item)
+			free--
+		}
+	}
+}
+
+// insert inserts the reserved job into the priority queue
+func (c *consumer) insert(item *Item) {
+	if item.Options.Priority == 0 {
+		item.Options.Priority = c.priority
+	}
+
+	item.Options.releaseFn = c.release
+	atomic.AddInt64(&c.inflight, 1)
+
+	c.queue.Insert(item)
+}
+
+// maintain renews the lease of the consumer, recovers the jobs of the crashed processes and moves the ready delayed jobs
+func (c *consumer) maintain(ctx context.Context) {
+	defer c.maintainWg.Done()
+
+	delayed := time.NewTicker(delayedPeriod)
+	defer delayed.Stop()
+
+	lease := time.NewTicker(c.leaseTimeout / 3)
+	defer lease.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-delayed.C:
+			_, err := c.spool.moveDelayed(time.Now())
+			if err != nil {
+				c.log.Error("failed to move the delayed jobs", zap.Error(err), zap.String("dir", c.spool.dir))
+			}
+		case <-lease.C:
+			err := c.spool.touch()
+			if err != nil {
+				c.log.Error("failed to renew the spool lease", zap.Error(err), zap.String("dir", c.spool.dir))
+			}
+
+			n, err := c.spool.recoverReserved(c.leaseTimeout)
+			if err != nil {
+				c.log.Error("failed to recover the reserved jobs", zap.Error(err), zap.String("dir", c.spool.dir))
+			}
+
+			if n > 0 {
+				c.log.Warn("reserved jobs of the stopped processes were returned to the spool", zap.Int("count", n), zap.String("dir", c.spool.dir))
+			}
+
+			c.spool.cleanTmp(c.leaseTimeout)
+		}
+	}
+}
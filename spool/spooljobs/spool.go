@@ -0,0 +1,364 @@
+package spooljobs
+
+import (
+	stderr "errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	json "github.com/json-iterator/go"
+	"github.com/spiral/errors"
+)
+
+const (
+	jobExt string = ".job"
+	// separates the owner of the reserved job and the job's file name
+	ownerSep string = "."
+	// length of the time prefix of the job's file name
+	timeLen int = 20
+)
+
+// seq makes the names of the files created by the process in the same nanosecond unique
+var seq uint64
+
+// spool stores every job as a file. The job is moved between the subdirectories by the atomic rename,
+// so the processes sharing the spool directory never take the same job:
+//
+//	tmp/      - the job is written here and renamed to the ready or delayed directory
+//	ready/    - jobs waiting for the consumer, sorted by the file name (push time)
+//	delayed/  - delayed jobs, the file name starts with the time the job is ready
+//	reserved/ - jobs taken by the consumer, the file name is prefixed with the consumer's owner ID
+//	failed/   - failed (nacked) and malformed jobs
+//	owners/   - lease files of the consumers, the stale lease means the consumer's process crashed
+type spool struct {
+	dir   string
+	perm  os.FileMode
+	owner string
+}
+
+func newSpool(dir string, perm os.FileMode) (*spool, error) {
+	s := &spool{
+		dir:  dir,
+		perm: perm,
+		// unique for the consumer, the process might have several consumers of the same directory
+		owner: fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()),
+	}
+
+	for _, sub := range []string{readyDir, reservedDir, delayedDir, failedDir, tmpDir, ownersDir} {
+		err := os.MkdirAll(filepath.Join(dir, sub), perm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+func (s *spool) path(sub, name string) string {
+	return filepath.Join(s.dir, sub, name)
+}
+
+// fileName returns the unique name of the job's file, the names are sorted by the time
+func fileName(t time.Time) string {
+	return fmt.Sprintf("%0*d-%d-%d%s", timeLen, t.UnixNano(), os.Getpid(), atomic.AddUint64(&seq, 1), jobExt)
+}
+
+// fileTime returns the time prefix of the job's file name
+func fileTime(name string) (int64, bool) {
+	if len(name) < timeLen {
+		return 0, false
+	}
+
+	t, err := strconv.ParseInt(name[:timeLen], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+
+	return t, true
+}
+
+// write stores the job in the ready directory or in the delayed directory if the job has a delay
+func (s *spool) write(item *Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Join(s.dir, tmpDir), "job-*")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	err = writeFile(f, data, s.perm&^0111)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	target := s.path(readyDir, fileName(time.Now()))
+	if item.Options.Delay > 0 {
+		target = s.path(delayedDir, fileName(time.Now().Add(item.Options.DelayDuration())))
+	}
+
+	err = os.Rename(tmp, target)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+func writeFile(f *os.File, data []byte, perm os.FileMode) error {
+	_, err := f.Write(data)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	err = f.Chmod(perm)
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	// the job should survive the power loss
+	err = f.Sync()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// list returns the names of the jobs files in the subdirectory sorted by the name
+func (s *spool) list(sub string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for i := 0; i < len(entries); i++ {
+		if entries[i].Type().IsRegular() && strings.HasSuffix(entries[i].Name(), jobExt) {
+			names = append(names, entries[i].Name())
+		}
+	}
+
+	return names, nil
+}
+
+// read decodes the job's file
+func (s *spool) read(path string) (*Item, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &Item{}
+	err = json.Unmarshal(data, item)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Options == nil {
+		item.Options = &Options{}
+	}
+
+	return item, nil
+}
+
+// reserve moves the ready job to the reserved directory, os.ErrNotExist - the job was taken by another consumer.
+// Malformed job is moved to the failed directory.
+func (s *spool) reserve(name string) (*Item, error) {
+	reserved := s.path(reservedDir, s.owner+ownerSep+name)
+	err := os.Rename(s.path(readyDir, name), reserved)
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := s.read(reserved)
+	if err != nil {
+		errF := os.Rename(reserved, s.path(failedDir, name))
+		if errF != nil {
+			return nil, errors.Errorf("malformed job: %s, error: %v, failed to move the job to the failed directory: %v", name, err, errF)
+		}
+
+		return nil, errors.Errorf("malformed job was moved to the failed directory: %s, error: %v", name, err)
+	}
+
+	item.Options.name = name
+	item.Options.spool = s
+	return item, nil
+}
+
+// reserved returns the path of the job reserved by the consumer
+func (s *spool) reserved(name string) string {
+	return s.path(reservedDir, s.owner+ownerSep+name)
+}
+
+// touch renews the lease of the consumer
+func (s *spool) touch() error {
+	lease := s.path(ownersDir, s.owner)
+	now := time.Now()
+	err := os.Chtimes(lease, now, now)
+	if stderr.Is(err, os.ErrNotExist) {
+		return os.WriteFile(lease, nil, s.perm&^0111)
+	}
+
+	return err
+}
+
+// release removes the lease of the consumer
+func (s *spool) release() error {
+	err := os.Remove(s.path(ownersDir, s.owner))
+	if err != nil && !stderr.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// recoverReserved returns the jobs reserved by the consumers with the stale (or removed) lease to the ready directory
+func (s *spool) recoverReserved(timeout time.Duration) (int, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, reservedDir))
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	stale := make(map[string]bool)
+	for i := 0; i < len(entries); i++ {
+		idx := strings.Index(entries[i].Name(), ownerSep)
+		if idx <= 0 {
+			continue
+		}
+
+		owner, name := entries[i].Name()[:idx], entries[i].Name()[idx+1:]
+		if owner == s.owner {
+			continue
+		}
+
+		st, ok := stale[owner]
+		if !ok {
+			st = s.staleOwner(owner, timeout)
+			stale[owner] = st
+		}
+
+		if !st {
+			continue
+		}
+
+		err = os.Rename(s.path(reservedDir, entries[i].Name()), s.path(readyDir, name))
+		if err != nil {
+			// recovered by another consumer
+			if stderr.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return recovered, err
+		}
+
+		recovered++
+	}
+
+	for owner, st := range stale {
+		if st {
+			_ = os.Remove(s.path(ownersDir, owner))
+		}
+	}
+
+	return recovered, nil
+}
+
+func (s *spool) staleOwner(owner string, timeout time.Duration) bool {
+	fi, err := os.Stat(s.path(ownersDir, owner))
+	if err != nil {
+		return stderr.Is(err, os.ErrNotExist)
+	}
+
+	return time.Since(fi.ModTime()) > timeout
+}
+
+// returnReserved returns the jobs reserved by the consumer to the ready directory
+func (s *spool) returnReserved() (int, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, reservedDir))
+	if err != nil {
+		return 0, err
+	}
+
+	returned := 0
+	prefix := s.owner + ownerSep
+	for i := 0; i < len(entries); i++ {
+		if !strings.HasPrefix(entries[i].Name(), prefix) {
+			continue
+		}
+
+		err = os.Rename(s.path(reservedDir, entries[i].Name()), s.path(readyDir, strings.TrimPrefix(entries[i].Name(), prefix)))
+		if err != nil && !stderr.Is(err, os.ErrNotExist) {
+			return returned, err
+		}
+
+		returned++
+	}
+
+	return returned, nil
+}
+
+// moveDelayed moves the delayed jobs which delay expired to the ready directory
+func (s *spool) moveDelayed(now time.Time) (int, error) {
+	names, err := s.list(delayedDir)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for i := 0; i < len(names); i++ {
+		t, ok := fileTime(names[i])
+		if !ok {
+			continue
+		}
+
+		// sorted by the time
+		if t > now.UnixNano() {
+			break
+		}
+
+		err = os.Rename(s.path(delayedDir, names[i]), s.path(readyDir, names[i]))
+		if err != nil {
+			// moved by another consumer or canceled
+			if stderr.Is(err, os.ErrNotExist) {
+				continue
+			}
+
+			return moved, err
+		}
+
+		moved++
+	}
+
+	return moved, nil
+}
+
+// cleanTmp removes the temporary files left by the crashed processes
+func (s *spool) cleanTmp(timeout time.Duration) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, tmpDir))
+	if err != nil {
+		return
+	}
+
+	for i := 0; i < len(entries); i++ {
+		fi, err := entries[i].Info()
+		if err != nil || time.Since(fi.ModTime()) <= timeout {
+			continue
+		}
+
+		_ = os.Remove(s.path(tmpDir, entries[i].Name()))
+	}
+}
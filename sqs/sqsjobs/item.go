@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
 	json "github.com/json-iterator/go"
 	"github.com/roadrunner-server/api/v2/plugins/jobs"
+	"github.com/roadrunner-server/api/v2/plugins/jobs/pipeline"
 	"github.com/spiral/errors"
 	"github.com/spiral/roadrunner/v2/utils"
 )
@@ -117,6 +118,21 @@ func (i *Item) Ack() error {
 	return nil
 }
 
+// Extend changes the visibility timeout of the message, the message stays invisible for d from now
+func (i *Item) Extend(d time.Duration) error {
+	const op = errors.Op("sqs_extend")
+	_, err := i.Options.client.ChangeMessageVisibility(context.Background(), &sqs.ChangeMessageVisibilityInput{
+		QueueUrl:          i.Options.queue,
+		ReceiptHandle:     i.Options.receiptHandler,
+		VisibilityTimeout: int32(d.Seconds()),
+	})
+	if err != nil {
+		return errors.E(op, err)
+	}
+
+	return nil
+}
+
 func (i *Item) Nack() error {
 	// requeue message
 	err := i.Options.requeueFn(context.Background(), i)
@@ -249,6 +265,8 @@ func (c *consumer) unpack(msg *types.Message) (*Item, error) {
 		Options: &Options{
 			Delay:    int64(delay),
 			Priority: int64(priority),
+			// pipeline is not a part of the message attributes, the queue belongs to the single pipeline
+			Pipeline: c.pipeline.Load().(*pipeline.Pipeline).Name(),
 
 			// private
 			approxReceiveCount: int64(recCount),
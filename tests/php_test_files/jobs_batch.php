@@ -0,0 +1,36 @@
+<?php
+
+/**
+ * @var Goridge\RelayInterface $relay
+ */
+
+use Spiral\Goridge;
+use Spiral\RoadRunner;
+use Spiral\Goridge\StreamRelay;
+
+require __DIR__ . "/vendor/autoload.php";
+
+$rr = new RoadRunner\Worker(new StreamRelay(\STDIN, \STDOUT));
+
+while ($in = $rr->waitPayload()) {
+    try {
+        $ctx = json_decode($in->header, true);
+        $payloads = json_decode($in->body, true);
+
+        $outcomes = [];
+        foreach ($ctx['batch'] as $i => $job) {
+            $outcomes[] = [
+                'id' => $job['id'],
+                'type' => 0,
+                'data' => []
+            ];
+        }
+
+        $rr->respond(new RoadRunner\Payload(json_encode([
+            'type' => 3,
+            'data' => $outcomes
+        ])));
+    } catch (\Throwable $e) {
+        $rr->error((string)$e);
+    }
+}
@@ -0,0 +1,41 @@
+<?php
+
+/**
+ * @var Goridge\RelayInterface $relay
+ */
+
+use Spiral\Goridge;
+use Spiral\RoadRunner;
+use Spiral\Goridge\StreamRelay;
+
+require __DIR__ . "/vendor/autoload.php";
+
+$rr = new RoadRunner\Worker(new StreamRelay(\STDIN, \STDOUT));
+
+while ($in = $rr->waitPayload()) {
+    try {
+        $ctx = json_decode($in->header, true);
+        $headers = $ctx['headers'];
+
+        // jobs in the dead-letter pipeline are processed successfully
+        if (isset($headers['rr_dead_letter_reason'])) {
+            $rr->respond(new RoadRunner\Payload(json_encode([
+                'type' => 0,
+                'data' => []
+            ])));
+            continue;
+        }
+
+        $rr->respond(new RoadRunner\Payload(json_encode([
+            'type' => 1,
+            'data' => [
+                'message' => 'poison message',
+                'requeue' => false,
+                'delay_seconds' => 0,
+                'headers' => $headers
+            ]
+        ])));
+    } catch (\Throwable $e) {
+        $rr->error((string)$e);
+    }
+}
@@ -0,0 +1,44 @@
+<?php
+
+/**
+ * @var Goridge\RelayInterface $relay
+ */
+
+use Spiral\Goridge;
+use Spiral\Goridge\RPC\Codec\RawCodec;
+use Spiral\Goridge\RPC\RPC;
+use Spiral\RoadRunner;
+use Spiral\Goridge\StreamRelay;
+
+require __DIR__ . "/vendor/autoload.php";
+
+$rr = new RoadRunner\Worker(new StreamRelay(\STDIN, \STDOUT));
+$rpc = RPC::create('tcp://127.0.0.1:6001')->withCodec(new RawCodec());
+
+while ($in = $rr->waitPayload()) {
+    try {
+        $ctx = json_decode($in->header, true);
+
+        // longer than the pipeline's job_timeout, the lease is extended by the heartbeats
+        for ($i = 1; $i <= 4; $i++) {
+            sleep(1);
+
+            $rpc->call('jobs.Heartbeat', json_encode([
+                'type' => 4,
+                'data' => [
+                    'id' => $ctx['id'],
+                    'pipeline' => $ctx['pipeline'],
+                    'extend_seconds' => 2,
+                    'progress' => $i * 25,
+                ],
+            ]));
+        }
+
+        $rr->respond(new RoadRunner\Payload(json_encode([
+            'type' => 0,
+            'data' => []
+        ])));
+    } catch (\Throwable $e) {
+        $rr->error((string)$e);
+    }
+}
@@ -12,6 +12,7 @@ import (
 	jobState "github.com/roadrunner-server/api/v2/plugins/jobs"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1beta"
 	goridgeRpc "github.com/spiral/goridge/v3/pkg/rpc"
+	"github.com/spiral/roadrunner-plugins/v2/jobs"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -22,6 +23,8 @@ const (
 	destroy string = "jobs.Destroy"
 	resume  string = "jobs.Resume"
 	stat    string = "jobs.Stat"
+
+	waitResult string = "jobs.WaitResult"
 )
 
 func resumePipes(pipes ...string) func(t *testing.T) {
@@ -89,6 +92,39 @@ func pushToPipe(pipeline string) func(t *testing.T) {
 	}
 }
 
+func pushAndWaitResult(pipeline string) func(t *testing.T) {
+	return func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+		require.NoError(t, err)
+		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+
+		id := uuid.NewString()
+		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+			Job:     "some/php/namespace",
+			Id:      id,
+			Payload: `{"hello":"world"}`,
+			Headers: map[string]*jobsv1beta.HeaderValue{"test": {Value: []string{"test2"}}},
+			Options: &jobsv1beta.Options{
+				Priority: 1,
+				Pipeline: pipeline,
+			},
+		}}
+
+		er := &jobsv1beta.Empty{}
+		err = client.Call(push, req, er)
+		require.NoError(t, err)
+
+		res := &jobs.Result{}
+		err = client.Call(waitResult, &jobs.ResultRequest{ID: id, Pipeline: pipeline, Timeout: 5}, res)
+		require.NoError(t, err)
+
+		assert.Equal(t, id, res.ID)
+		assert.Equal(t, pipeline, res.Pipeline)
+		assert.Equal(t, jobs.StatusOk, res.Status)
+		assert.Equal(t, 1, res.Attempts)
+	}
+}
+
 func pushToPipeDelayed(pipeline string, delay int64) func(t *testing.T) {
 	return func(t *testing.T) {
 		conn, err := net.Dial("tcp", "127.0.0.1:6001")
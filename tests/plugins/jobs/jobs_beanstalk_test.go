@@ -1,6 +1,8 @@
 package jobs
 
 import (
+	"bytes"
+	"encoding/gob"
 	"net"
 	"net/rpc"
 	"os"
@@ -10,12 +12,14 @@ import (
 	"testing"
 	"time"
 
+	beanstalkClient "github.com/beanstalkd/go-beanstalk"
 	"github.com/google/uuid"
 	jobState "github.com/roadrunner-server/api/v2/plugins/jobs"
 	jobsv1beta "github.com/roadrunner-server/api/v2/proto/jobs/v1beta"
 	endure "github.com/spiral/endure/pkg/container"
 	goridgeRpc "github.com/spiral/goridge/v3/pkg/rpc"
 	"github.com/spiral/roadrunner-plugins/v2/beanstalk"
+	"github.com/spiral/roadrunner-plugins/v2/beanstalk/beanstalkjobs"
 	"github.com/spiral/roadrunner-plugins/v2/config"
 	"github.com/spiral/roadrunner-plugins/v2/informer"
 	"github.com/spiral/roadrunner-plugins/v2/jobs"
@@ -303,6 +307,8 @@ func TestBeanstalkStats(t *testing.T) {
 	assert.Equal(t, int64(0), out.Delayed)
 	assert.Equal(t, int64(0), out.Reserved)
 
+	t.Run("CancelDelayedJob", cancelBeanstalkDelayed(out.Queue))
+
 	time.Sleep(time.Second)
 	t.Run("DestroyPipeline", destroyPipelines("test-3"))
 
@@ -687,6 +693,64 @@ func TestBeanstalkInitV27BadResp(t *testing.T) {
 	})
 }
 
+// cancelBeanstalkDelayed cancels the delayed jobs pushed by the RR instance and put into the tube directly
+func cancelBeanstalkDelayed(tube string) func(t *testing.T) {
+	return func(t *testing.T) {
+		conn, err := net.Dial("tcp", "127.0.0.1:6001")
+		require.NoError(t, err)
+		client := rpc.NewClientWithCodec(goridgeRpc.NewClientCodec(conn))
+
+		req := &jobsv1beta.PushRequest{Job: &jobsv1beta.Job{
+			Job:     "some/php/namespace",
+			Id:      "cancel-1",
+			Payload: `{"hello":"world"}`,
+			Options: &jobsv1beta.Options{
+				Pipeline: "test-3",
+				Delay:    60,
+			},
+		}}
+		require.NoError(t, client.Call(push, req, &jobsv1beta.Empty{}))
+
+		var canceled bool
+		require.NoError(t, client.Call("jobs.Cancel", &jobs.CancelRequest{Pipeline: "test-3", ID: "cancel-1"}, &canceled))
+		require.True(t, canceled)
+
+		require.NoError(t, client.Call("jobs.Cancel", &jobs.CancelRequest{Pipeline: "test-3", ID: "cancel-1"}, &canceled))
+		require.False(t, canceled)
+
+		// jobs unknown to the RR instance, e.g. pushed by another instance
+		bconn, err := beanstalkClient.Dial("tcp", "127.0.0.1:11300")
+		require.NoError(t, err)
+		defer func() {
+			_ = bconn.Close()
+		}()
+
+		tb := beanstalkClient.NewTube(bconn, tube)
+		for id, delay := range map[string]int64{"cancel-2": 60, "cancel-3": 120} {
+			bb := new(bytes.Buffer)
+			require.NoError(t, gob.NewEncoder(bb).Encode(&beanstalkjobs.Item{
+				Job:     "some/php/namespace",
+				Ident:   id,
+				Payload: `{"hello":"world"}`,
+				Options: &beanstalkjobs.Options{Pipeline: "test-3", Delay: delay},
+			}))
+
+			_, err = tb.Put(bb.Bytes(), 0, time.Second*time.Duration(delay), time.Minute)
+			require.NoError(t, err)
+		}
+
+		// only the next delayed job of the tube might be found
+		require.NoError(t, client.Call("jobs.Cancel", &jobs.CancelRequest{Pipeline: "test-3", ID: "cancel-3"}, &canceled))
+		require.False(t, canceled)
+
+		require.NoError(t, client.Call("jobs.Cancel", &jobs.CancelRequest{Pipeline: "test-3", ID: "cancel-2"}, &canceled))
+		require.True(t, canceled)
+
+		require.NoError(t, client.Call("jobs.Cancel", &jobs.CancelRequest{Pipeline: "test-3", ID: "cancel-3"}, &canceled))
+		require.True(t, canceled)
+	}
+}
+
 func declareBeanstalkPipe(t *testing.T) {
 	conn, err := net.Dial("tcp", "127.0.0.1:6001")
 	require.NoError(t, err)